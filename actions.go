@@ -0,0 +1,278 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Actions of a schedule on an existing message, a new message is sent when
+// the action is empty.
+const (
+	actionEdit   = "edit"
+	actionDelete = "delete"
+	actionReact  = "react"
+)
+
+// maxReactions is the number of reactions a schedule can add, Discord allows
+// 20 different reactions per message.
+const maxReactions = 20
+
+// applyAction applies the action of the schedule to its message.
+func applyAction(s *discordgo.Session, sch *Schedule) error {
+	switch sch.Action {
+	case actionEdit:
+		_, err := s.ChannelMessageEdit(sch.ChannelID, sch.MessageID, sch.Content)
+		if err != nil {
+			return errors.New("Error editing message: " + err.Error())
+		}
+	case actionDelete:
+		err := s.ChannelMessageDelete(sch.ChannelID, sch.MessageID)
+		if err != nil {
+			return errors.New("Error deleting message: " + err.Error())
+		}
+	case actionReact:
+		// the reactions are added in order, a failing one stops the others
+		for _, emoji := range sch.Reactions {
+			err := s.MessageReactionAdd(sch.ChannelID, sch.MessageID, emoji)
+			if err != nil {
+				return errors.New("Error adding reaction " + emoji + ": " + err.Error())
+			}
+		}
+	default:
+		return errors.New("unknown action " + sch.Action)
+	}
+	return nil
+}
+
+// actionTarget returns the message given by link or ID, which must be in the
+// guild of the interaction. A plain ID is looked for in the current channel.
+func actionTarget(s *discordgo.Session, i *discordgo.InteractionCreate, link string) (*discordgo.Message, *discordgo.Channel, error) {
+	channel, err := s.Channel(i.ChannelID)
+	if err != nil {
+		return nil, nil, errors.New("Error getting channel: " + err.Error())
+	}
+	message, channel, err := linkedMessage(s, channel, link)
+	if err != nil {
+		return nil, nil, err
+	}
+	if channel.GuildID != i.GuildID {
+		return nil, nil, errors.New("the message is not in this server")
+	}
+	return message, channel, nil
+}
+
+func handleEdit(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config := store.GuildConfig(i.GuildID)
+
+	link := ""
+	content := ""
+	sendTime := ""
+	date := ""
+	for _, option := range options {
+		if option.Name == "link" {
+			link = option.StringValue()
+		} else if option.Name == "message" {
+			content = option.StringValue()
+		} else if option.Name == "time" {
+			sendTime = option.StringValue()
+		} else if option.Name == "date" {
+			date = option.StringValue()
+		}
+	}
+
+	message, channel, err := actionTarget(s, i, link)
+	if err != nil {
+		respondError(s, i, "Error scheduling edit", err)
+		return
+	}
+	// the bot can only edit its own messages, which belong to nobody in
+	// particular so only the moderators can change them
+	if message.Author == nil || message.Author.ID != s.State.User.ID {
+		respondError(s, i, "Error scheduling edit", errors.New("only the messages sent by the bot can be edited"))
+		return
+	}
+	if !hasPermission(i, discordgo.PermissionManageMessages) {
+		respondError(s, i, "Error scheduling edit", errors.New("only the moderators can edit the messages of the bot"))
+		return
+	}
+	if utf8.RuneCountInString(content) > maxMessageLength {
+		respondError(s, i, "Error scheduling edit", errors.New("the message is longer than 2000 characters"))
+		return
+	}
+
+	if date == "" {
+		date = time.Now().In(config.location()).Format("02/01/2006")
+	}
+	fixedTime, err := parseSendTime(date, sendTime, config)
+	if err != nil {
+		logger.Error("Error scheduling edit: ", "error", err)
+		respondError(s, i, "Error scheduling edit", err)
+		return
+	}
+	sch := &Schedule{
+		GuildID:     channel.GuildID,
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		AuthorID:    interactionUser(i).ID,
+		Content:     content,
+		Thread:      channel.IsThread(),
+		Action:      actionEdit,
+		MessageID:   message.ID,
+		SendAt:      fixedTime,
+	}
+	startSchedule(s, sch)
+	logger.Info("Edit scheduled", "message", message.ID, "channel", channel.Name, "date", date, "sendTime", sendTime)
+	respondEmbed(s, i, scheduledEmbed("Edit scheduled", sch))
+}
+
+func handleDelete(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config := store.GuildConfig(i.GuildID)
+
+	link := ""
+	sendTime := ""
+	date := ""
+	for _, option := range options {
+		if option.Name == "link" {
+			link = option.StringValue()
+		} else if option.Name == "time" {
+			sendTime = option.StringValue()
+		} else if option.Name == "date" {
+			date = option.StringValue()
+		}
+	}
+
+	message, channel, err := actionTarget(s, i, link)
+	if err != nil {
+		respondError(s, i, "Error scheduling deletion", err)
+		return
+	}
+	// the members can delete their own messages, the moderators any message
+	user := interactionUser(i)
+	if (message.Author == nil || message.Author.ID != user.ID) && !hasPermission(i, discordgo.PermissionManageMessages) {
+		respondError(s, i, "Error scheduling deletion", errors.New("only its author or a moderator can delete a message"))
+		return
+	}
+
+	if date == "" {
+		date = time.Now().In(config.location()).Format("02/01/2006")
+	}
+	fixedTime, err := parseSendTime(date, sendTime, config)
+	if err != nil {
+		logger.Error("Error scheduling deletion: ", "error", err)
+		respondError(s, i, "Error scheduling deletion", err)
+		return
+	}
+	sch := &Schedule{
+		GuildID:     channel.GuildID,
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		AuthorID:    user.ID,
+		// the content of the message is kept for the agenda and the audit
+		Content:   message.Content,
+		Thread:    channel.IsThread(),
+		Action:    actionDelete,
+		MessageID: message.ID,
+		SendAt:    fixedTime,
+	}
+	startSchedule(s, sch)
+	logger.Info("Deletion scheduled", "message", message.ID, "channel", channel.Name, "date", date, "sendTime", sendTime)
+	respondEmbed(s, i, scheduledEmbed("Deletion scheduled", sch))
+}
+
+// parseReactions returns the emojis separated by spaces in text, in the
+// format of the reactions API: unicode emojis as is, and name:id for the
+// custom emojis written <:name:id> or <a:name:id>.
+func parseReactions(text string) ([]string, error) {
+	emojis := []string{}
+	for _, field := range strings.Fields(text) {
+		if strings.HasPrefix(field, "<") {
+			custom := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(field, "<"), "a"), ">")
+			name, id, found := strings.Cut(strings.TrimPrefix(custom, ":"), ":")
+			if !found || name == "" || !isSnowflake(id) {
+				return nil, errors.New("invalid emoji " + field)
+			}
+			field = name + ":" + id
+		}
+		emojis = append(emojis, field)
+	}
+	if len(emojis) == 0 {
+		return nil, errors.New("no emoji to react with")
+	}
+	if len(emojis) > maxReactions {
+		return nil, errors.New("a message can have at most 20 different reactions")
+	}
+	return emojis, nil
+}
+
+func handleReact(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config := store.GuildConfig(i.GuildID)
+
+	link := ""
+	emojis := ""
+	sendTime := ""
+	date := ""
+	for _, option := range options {
+		if option.Name == "link" {
+			link = option.StringValue()
+		} else if option.Name == "emojis" {
+			emojis = option.StringValue()
+		} else if option.Name == "time" {
+			sendTime = option.StringValue()
+		} else if option.Name == "date" {
+			date = option.StringValue()
+		}
+	}
+
+	message, channel, err := actionTarget(s, i, link)
+	if err != nil {
+		respondError(s, i, "Error scheduling reactions", err)
+		return
+	}
+	reactions, err := parseReactions(emojis)
+	if err != nil {
+		respondError(s, i, "Error scheduling reactions", err)
+		return
+	}
+
+	if date == "" {
+		date = time.Now().In(config.location()).Format("02/01/2006")
+	}
+	fixedTime, err := parseSendTime(date, sendTime, config)
+	if err != nil {
+		logger.Error("Error scheduling reactions: ", "error", err)
+		respondError(s, i, "Error scheduling reactions", err)
+		return
+	}
+	sch := &Schedule{
+		GuildID:     channel.GuildID,
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		AuthorID:    interactionUser(i).ID,
+		Thread:      channel.IsThread(),
+		Action:      actionReact,
+		MessageID:   message.ID,
+		Reactions:   reactions,
+		SendAt:      fixedTime,
+	}
+	startSchedule(s, sch)
+	logger.Info("Reactions scheduled", "message", message.ID, "channel", channel.Name, "reactions", len(reactions), "date", date, "sendTime", sendTime)
+	respondEmbed(s, i, scheduledEmbed("Reactions scheduled", sch))
+}
@@ -0,0 +1,134 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// agendaDays is how far ahead the agenda looks.
+const agendaDays = 7
+
+func handleAgenda(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" {
+		respond(s, i, "The agenda is only available in a server")
+		return
+	}
+	location := store.GuildConfig(i.GuildID).location()
+	respondEmbed(s, i, agendaEmbed(schedules.shared(i.GuildID, interactionUser(i).ID), time.Now().In(location)))
+}
+
+// agendaEmbed groups the schedules by day, from the day of now and for the
+// next agendaDays days, in the time zone of now. The recurring schedules are
+// listed at each of their occurrences.
+func agendaEmbed(list []*Schedule, now time.Time) *discordgo.MessageEmbed {
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 0, agendaDays)
+
+	type occurrence struct {
+		sendAt time.Time
+		sch    *Schedule
+	}
+	occurrences := []occurrence{}
+	for _, sch := range list {
+		for _, sendAt := range sch.occurrences(end) {
+			occurrences = append(occurrences, occurrence{sendAt, sch})
+		}
+	}
+	slices.SortStableFunc(occurrences, func(a, b occurrence) int {
+		return a.sendAt.Compare(b.sendAt)
+	})
+
+	embed := &discordgo.MessageEmbed{
+		Title: "Agenda for the next " + strconv.Itoa(agendaDays) + " days",
+	}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		lines := []string{}
+		for _, o := range occurrences {
+			if o.sendAt.Before(day) || !o.sendAt.Before(next) {
+				continue
+			}
+			lines = append(lines, "`"+o.sendAt.Format("15:04")+"` "+o.sch.target()+" "+truncate(oneLine(o.sch.preview()), 60)+" (`"+o.sch.ID+"`)")
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  day.Format("Monday 02/01"),
+			Value: truncate(strings.Join(lines, "\n"), 1024),
+		})
+	}
+	if len(embed.Fields) == 0 {
+		embed.Description = "Nothing scheduled"
+	}
+	return embed
+}
+
+// oneLine replaces the line breaks of text so it fits in a list entry.
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// handleMine lists the schedules of the member in every server, as they may
+// manage the announcements of several servers from the same account.
+func handleMine(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := interactionUser(i)
+	respondEphemeralEmbed(s, i, mineEmbed(s, schedules.author(user.ID)))
+}
+
+// mineEmbed groups the schedules by server, in the order of their first send
+// time.
+func mineEmbed(s *discordgo.Session, list []*Schedule) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: "Your scheduled messages",
+	}
+	guildIDs := []string{}
+	lines := map[string][]string{}
+	for _, sch := range list {
+		if _, found := lines[sch.GuildID]; !found {
+			guildIDs = append(guildIDs, sch.GuildID)
+		}
+		sendAt := strconv.FormatInt(sch.SendAt.Unix(), 10)
+		lines[sch.GuildID] = append(lines[sch.GuildID], "<t:"+sendAt+":f> "+sch.target()+" "+truncate(oneLine(sch.preview()), 60)+" (`"+sch.ID+"`)")
+	}
+	for _, guildID := range guildIDs {
+		// an embed has at most 25 fields
+		if len(embed.Fields) == 25 {
+			embed.Footer = &discordgo.MessageEmbedFooter{Text: "Only the first 25 servers are shown"}
+			break
+		}
+		name := "Direct messages"
+		if guildID != "" {
+			name = "Server " + guildID
+			if guild, err := s.State.Guild(guildID); err == nil {
+				name = guild.Name
+			}
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  name,
+			Value: truncate(strings.Join(lines[guildID], "\n"), 1024),
+		})
+	}
+	if len(embed.Fields) == 0 {
+		embed.Description = "Nothing scheduled"
+	}
+	return embed
+}
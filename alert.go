@@ -0,0 +1,216 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alerter reports an operational problem of the bot to its operators.
+type Alerter interface {
+	Alert(subject string, details string) error
+}
+
+// alertThrottle is the minimum delay between two alerts with the same subject.
+const alertThrottle = 15 * time.Minute
+
+var (
+	alerters   []Alerter
+	alertMu    sync.Mutex
+	lastAlerts = map[string]time.Time{}
+)
+
+// setupAlerters creates the alerters configured in the environment.
+func setupAlerters(s *discordgo.Session) {
+	if channelID := os.Getenv("SENDLATER_ALERT_CHANNEL"); channelID != "" {
+		alerters = append(alerters, &discordAlerter{session: s, channelID: channelID})
+	}
+	if addr := os.Getenv("SENDLATER_ALERT_SMTP"); addr != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		alerter := &smtpAlerter{
+			addr: addr,
+			from: os.Getenv("SENDLATER_ALERT_EMAIL_FROM"),
+			to:   envList("SENDLATER_ALERT_EMAIL_TO"),
+		}
+		if user := os.Getenv("SENDLATER_ALERT_SMTP_USER"); user != "" {
+			alerter.auth = smtp.PlainAuth("", user, os.Getenv("SENDLATER_ALERT_SMTP_PASSWORD"), host)
+		}
+		alerters = append(alerters, alerter)
+	}
+	if url := os.Getenv("SENDLATER_ALERT_WEBHOOK"); url != "" {
+		alerters = append(alerters, &webhookAlerter{url: url, secret: os.Getenv("SENDLATER_ALERT_WEBHOOK_SECRET")})
+	}
+	readFailureThresholds()
+	logger.Info("Alerters configured", "count", len(alerters), "failureLimit", failureLimit, "failureWindow", failureWindow)
+}
+
+// readFailureThresholds reads when the delivery failures are alerted.
+func readFailureThresholds() {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	failureLimit = envInt("SENDLATER_ALERT_FAILURES", 5)
+	window, err := parseDelay(envOr("SENDLATER_ALERT_FAILURE_WINDOW", "10m"))
+	if err != nil {
+		logger.Error("Invalid SENDLATER_ALERT_FAILURE_WINDOW, using the default", "error", err)
+		window = 10 * time.Minute
+	}
+	failureWindow = window
+}
+
+// alert reports the problem to every configured alerter, in the background.
+// Alerts with the same subject are throttled so that a lasting problem does
+// not flood the operators.
+func alert(subject string, details string) {
+	alertMu.Lock()
+	if time.Since(lastAlerts[subject]) < alertThrottle {
+		alertMu.Unlock()
+		return
+	}
+	lastAlerts[subject] = time.Now()
+	alertMu.Unlock()
+
+	logger.Warn("Alerting operators", "subject", subject, "details", details)
+	for _, alerter := range alerters {
+		go func(alerter Alerter) {
+			err := alerter.Alert(subject, details)
+			if err != nil {
+				logger.Error("Error sending alert", "error", err, "subject", subject)
+			}
+		}(alerter)
+	}
+}
+
+// discordAlerter posts the alerts in a Discord channel.
+type discordAlerter struct {
+	session   *discordgo.Session
+	channelID string
+}
+
+func (a *discordAlerter) Alert(subject string, details string) error {
+	_, err := a.session.ChannelMessageSend(a.channelID, truncate("⚠️ **"+subject+"**\n"+details, 2000))
+	return err
+}
+
+// smtpAlerter sends the alerts by email.
+type smtpAlerter struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+func (a *smtpAlerter) Alert(subject string, details string) error {
+	message := "From: " + a.from + "\r\n" +
+		"To: " + strings.Join(a.to, ", ") + "\r\n" +
+		"Subject: [sendlater] " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + details + "\r\n"
+	return smtp.SendMail(a.addr, a.auth, a.from, a.to, []byte(message))
+}
+
+// webhookAlerter posts the alerts as signed JSON payloads.
+type webhookAlerter struct {
+	url    string
+	secret string
+}
+
+func (a *webhookAlerter) Alert(subject string, details string) error {
+	body, err := json.Marshal(map[string]string{
+		"subject": subject,
+		"details": details,
+		"time":    time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return errors.New("Error encoding alert: " + err.Error())
+	}
+	return postSigned(a.url, a.secret, body)
+}
+
+// unauthorizedLimit is the number of consecutive 401 answers from Discord
+// after which the operators are alerted.
+const unauthorizedLimit = 3
+
+var (
+	unauthorizedMu    sync.Mutex
+	unauthorizedCount int
+)
+
+// watchDiscordError counts the consecutive 401 answers from Discord, a
+// revoked token makes every delivery fail until someone notices.
+func watchDiscordError(err error) {
+	unauthorizedMu.Lock()
+	defer unauthorizedMu.Unlock()
+	var restErr *discordgo.RESTError
+	if err == nil || !errors.As(err, &restErr) || restErr.Response == nil || restErr.Response.StatusCode != http.StatusUnauthorized {
+		unauthorizedCount = 0
+		return
+	}
+	unauthorizedCount++
+	if unauthorizedCount >= unauthorizedLimit {
+		alert("Discord rejects the bot token", "Discord answered 401 Unauthorized to the last "+strconv.Itoa(unauthorizedCount)+" requests: "+err.Error())
+	}
+}
+
+// deliveryFailure is a message that could not be delivered.
+type deliveryFailure struct {
+	at      time.Time
+	details string
+}
+
+var (
+	// failureLimit is the number of failed deliveries within failureWindow
+	// after which the operators are alerted.
+	failureLimit  = 5
+	failureWindow = 10 * time.Minute
+	failuresMu    sync.Mutex
+	failures      []deliveryFailure
+)
+
+// watchDeliveryFailure counts the failed deliveries, a spike usually means
+// the bot lost a permission or a channel and many messages are lost with it.
+func watchDeliveryFailure(sch *Schedule, err error) {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	now := time.Now()
+	kept := failures[:0]
+	for _, failure := range failures {
+		if now.Sub(failure.at) < failureWindow {
+			kept = append(kept, failure)
+		}
+	}
+	failures = append(kept, deliveryFailure{
+		at:      now,
+		details: "- message " + sch.ID + " in #" + sch.ChannelName + " (guild " + sch.GuildID + "): " + err.Error(),
+	})
+	if len(failures) < failureLimit {
+		return
+	}
+	details := strconv.Itoa(len(failures)) + " messages could not be delivered in the last " + formatDelay(failureWindow) + ":"
+	for _, failure := range failures[max(0, len(failures)-10):] {
+		details += "\n" + failure.details
+	}
+	alert("Deliveries are failing", details)
+}
@@ -0,0 +1,284 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// apiMaxBody is the maximum size of a request body of the API.
+const apiMaxBody = 1 << 20
+
+// apiSchedule is a schedule as created and listed by the API.
+type apiSchedule struct {
+	ID        string                    `json:"id"`
+	GuildID   string                    `json:"guild_id"`
+	ChannelID string                    `json:"channel_id"`
+	AuthorID  string                    `json:"author_id"`
+	Content   string                    `json:"content,omitempty"`
+	Embeds    []*discordgo.MessageEmbed `json:"embeds,omitempty"`
+	SendAt    time.Time                 `json:"send_at"`
+	TTS       bool                      `json:"tts,omitempty"`
+	Silent    bool                      `json:"silent,omitempty"`
+	// Files are the names of the files of the schedule, which the API can't
+	// upload.
+	Files []string `json:"files,omitempty"`
+	// AwaitingApproval is set until a moderator approves the schedule.
+	AwaitingApproval bool `json:"awaiting_approval,omitempty"`
+}
+
+func newAPISchedule(sch *Schedule) apiSchedule {
+	files := []string{}
+	for _, file := range sch.Files {
+		files = append(files, file.Name)
+	}
+	return apiSchedule{
+		ID:               sch.ID,
+		GuildID:          sch.GuildID,
+		ChannelID:        sch.ChannelID,
+		AuthorID:         sch.AuthorID,
+		Content:          sch.Content,
+		Embeds:           sch.Embeds,
+		SendAt:           sch.SendAt,
+		TTS:              sch.TTS,
+		Silent:           sch.Silent,
+		Files:            files,
+		AwaitingApproval: sch.AwaitingApproval,
+	}
+}
+
+// serveAPI serves the HTTP API on addr in the background, unless addr is
+// empty. The requests must carry one of tokens as a bearer token.
+func serveAPI(s *discordgo.Session, addr string, tokens []string) {
+	if addr == "" {
+		return
+	}
+	if len(tokens) == 0 {
+		logger.Error("The API is not served without SENDLATER_API_TOKENS", "address", addr)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /schedules", func(w http.ResponseWriter, r *http.Request) {
+		apiCreateSchedule(s, w, r)
+	})
+	mux.HandleFunc("GET /schedules", apiListSchedules)
+	mux.HandleFunc("GET /schedules/{id}", apiGetSchedule)
+	mux.HandleFunc("DELETE /schedules/{id}", func(w http.ResponseWriter, r *http.Request) {
+		apiCancelSchedule(s, w, r)
+	})
+	mux.HandleFunc("GET /guilds/{guild}/backup", apiExportSchedules)
+	mux.HandleFunc("POST /guilds/{guild}/backup", func(w http.ResponseWriter, r *http.Request) {
+		apiRestoreSchedules(s, w, r)
+	})
+	go func() {
+		logger.Info("Serving API", "address", addr)
+		err := http.ListenAndServe(addr, apiAuth(tokens, mux))
+		logger.Error("API stopped", "error", err, "address", addr)
+	}()
+}
+
+// apiAuth rejects the requests without a valid bearer token.
+func apiAuth(tokens []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if validAPIToken(r.Header.Get("Authorization"), tokens) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		logger.Warn("API request refused", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+		writeAPIError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+	})
+}
+
+// validAPIToken reports whether the authorization header is "Bearer " and
+// one of tokens.
+func validAPIToken(header string, tokens []string) bool {
+	given, found := strings.CutPrefix(header, "Bearer ")
+	if !found {
+		return false
+	}
+	for _, token := range tokens {
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// apiCreateRequest is the body of POST /schedules. Without an author, the
+// message is scheduled by the bot, outside of the quotas of the members.
+type apiCreateRequest struct {
+	ChannelID string                    `json:"channel_id"`
+	AuthorID  string                    `json:"author_id"`
+	Content   string                    `json:"content"`
+	Embeds    []*discordgo.MessageEmbed `json:"embeds"`
+	SendAt    time.Time                 `json:"send_at"`
+	TTS       bool                      `json:"tts"`
+	Silent    bool                      `json:"silent"`
+}
+
+func apiCreateSchedule(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	var req apiCreateRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBody))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&req)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errors.New("invalid body: "+err.Error()))
+		return
+	}
+	sch, status, err := createAPISchedule(s, req)
+	if err != nil {
+		writeAPIError(w, status, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusCreated, newAPISchedule(sch))
+}
+
+// createAPISchedule checks and starts the schedule of a request of the HTTP or
+// gRPC API. It returns the HTTP status of the error when it is refused.
+func createAPISchedule(s *discordgo.Session, req apiCreateRequest) (*Schedule, int, error) {
+	if req.Content == "" && len(req.Embeds) == 0 {
+		return nil, http.StatusBadRequest, errors.New("content or embeds are required")
+	}
+	if utf8.RuneCountInString(req.Content) > maxMessageLength {
+		return nil, http.StatusBadRequest, errors.New("the content is longer than 2000 characters")
+	}
+	if req.SendAt.IsZero() {
+		return nil, http.StatusBadRequest, errors.New("send_at is required, like 2025-01-31T09:00:00Z")
+	}
+
+	channel, err := s.State.Channel(req.ChannelID)
+	if err != nil {
+		channel, err = s.Channel(req.ChannelID)
+	}
+	if err != nil || channel.GuildID == "" || !guildAllowed(channel.GuildID) {
+		return nil, http.StatusNotFound, errors.New("unknown channel " + req.ChannelID)
+	}
+	err = checkChannel(channel, false)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	config := store.GuildConfig(channel.GuildID)
+	err = checkSendBounds(req.SendAt, time.Now())
+	if err == nil && config.inQuietHours(req.SendAt) {
+		err = errors.New("the time is during the quiet hours of the server")
+	}
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	authorID := s.State.User.ID
+	if req.AuthorID != "" {
+		authorID = req.AuthorID
+		err = checkCanPost(s, authorID, channel, req.Content, nil)
+		if err == nil && config.quotaLeft(channel.GuildID, authorID) == 0 {
+			err = errors.New("the author has reached the number of pending messages of the server")
+		}
+		if err != nil {
+			return nil, http.StatusForbidden, err
+		}
+	}
+
+	sch := &Schedule{
+		GuildID:     channel.GuildID,
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		AuthorID:    authorID,
+		Content:     req.Content,
+		Embeds:      req.Embeds,
+		SendAt:      req.SendAt,
+		TTS:         req.TTS,
+		Silent:      req.Silent,
+		Thread:      channel.IsThread(),
+	}
+	startSchedule(s, sch)
+	logger.Info("Message scheduled through the API", "id", sch.ID, "channel", channel.Name, "guild", channel.GuildID, "author", authorID)
+	return sch, http.StatusCreated, nil
+}
+
+// apiListSchedules lists the pending schedules of the guild_id or author_id
+// query parameter.
+func apiListSchedules(w http.ResponseWriter, r *http.Request) {
+	list, err := listAPISchedules(r.URL.Query().Get("guild_id"), r.URL.Query().Get("author_id"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	result := []apiSchedule{}
+	for _, sch := range list {
+		result = append(result, newAPISchedule(sch))
+	}
+	writeAPIJSON(w, http.StatusOK, result)
+}
+
+// listAPISchedules returns the pending schedules of the guild, without the
+// reminders of its members, or else of the author.
+func listAPISchedules(guildID string, authorID string) ([]*Schedule, error) {
+	if guildID != "" {
+		return schedules.shared(guildID, ""), nil
+	}
+	if authorID != "" {
+		return schedules.author(authorID), nil
+	}
+	return nil, errors.New("guild_id or author_id is required")
+}
+
+func apiGetSchedule(w http.ResponseWriter, r *http.Request) {
+	sch := schedules.get(r.PathValue("id"))
+	if sch == nil {
+		writeAPIError(w, http.StatusNotFound, errors.New("no pending message with ID "+r.PathValue("id")))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, newAPISchedule(sch))
+}
+
+func apiCancelSchedule(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	err := cancelAPISchedule(s, r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cancelAPISchedule cancels the pending schedule with the given ID.
+func cancelAPISchedule(s *discordgo.Session, id string) error {
+	sch := schedules.take(id)
+	if sch == nil {
+		return errors.New("no pending message with ID " + id)
+	}
+	audit(s, AuditCancelled, s.State.User.ID, sch)
+	logger.Info("Message cancelled through the API", "id", id)
+	return nil
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		logger.Error("Error writing API response", "error", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,156 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+)
+
+// Custom IDs of the review buttons, followed by the ID of the schedule.
+const (
+	approvalPrefix  = "approval_"
+	approvalApprove = approvalPrefix + "approve:"
+	approvalReject  = approvalPrefix + "reject:"
+)
+
+var errNotApproved = errors.New("it was not approved by the moderators in time")
+
+// needsApproval reports whether the schedule must be approved before being
+// sent: in approval mode, only the owners and the members allowed to manage
+// the messages of the channel don't need it.
+func needsApproval(s *discordgo.Session, sch *Schedule) bool {
+	if sch.GuildID == "" || sch.DM || isOwner(sch.AuthorID) || !store.FlagEnabled(sch.GuildID, FlagApprovalMode) {
+		return false
+	}
+	if store.GuildConfig(sch.GuildID).ApprovalChannelID == "" {
+		return false
+	}
+	// when the permissions can't be read, the moderators decide
+	permissions, err := s.UserChannelPermissions(sch.AuthorID, sch.ChannelID)
+	needed := err != nil || permissions&(discordgo.PermissionManageMessages|discordgo.PermissionAdministrator) == 0
+	logger.Debug("Approval decided", "guild", sch.GuildID, "author", sch.AuthorID, "needed", needed, "error", err)
+	return needed
+}
+
+// requestApproval posts the schedule for review in the approval channel of
+// the guild, and tells its author.
+func requestApproval(s *discordgo.Session, sch *Schedule) {
+	channelID := store.GuildConfig(sch.GuildID).ApprovalChannelID
+	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         approvalContent(sch),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Approve",
+					Style:    discordgo.SuccessButton,
+					CustomID: approvalApprove + sch.ID,
+				},
+				discordgo.Button{
+					Label:    "Reject",
+					Style:    discordgo.DangerButton,
+					CustomID: approvalReject + sch.ID,
+				},
+			}},
+		},
+	})
+	if err != nil {
+		logger.Error("Error posting approval request", "error", err, "channel", channelID, "id", sch.ID)
+		alert("Approval request failed", "Message "+sch.ID+" of guild "+sch.GuildID+" could not be posted for review: "+err.Error())
+	}
+	err = notifyAuthor(s, sch, "Your message `"+sch.ID+"` for "+sch.target()+" is waiting for the approval of the moderators.")
+	if err != nil {
+		logger.Error("Error notifying author", "error", err, "id", sch.ID, "author", sch.AuthorID)
+	}
+}
+
+// approvalContent describes the schedule to review.
+func approvalContent(sch *Schedule) string {
+	content := "Message `" + sch.ID + "` by <@" + sch.AuthorID + "> in " + sch.target() + " for <t:" + strconv.FormatInt(sch.SendAt.Unix(), 10) + ":F> is waiting for approval"
+	if preview := sch.preview(); preview != "" {
+		content += "\n>>> " + truncate(preview, 1500)
+	}
+	return content
+}
+
+// handleApprovalComponent approves or rejects the schedule of a review
+// message, then writes the decision on it in place of its buttons.
+func handleApprovalComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !hasPermission(i, discordgo.PermissionManageMessages) {
+		respondEphemeral(s, i, "Only the moderators can review messages")
+		return
+	}
+
+	customID := i.MessageComponentData().CustomID
+	moderator := interactionUser(i)
+	id := ""
+	approved := false
+	if after, found := strings.CutPrefix(customID, approvalApprove); found {
+		id = after
+		approved = true
+	} else {
+		id = strings.TrimPrefix(customID, approvalReject)
+	}
+
+	decision := ""
+	sch := schedules.get(id)
+	if sch == nil || sch.GuildID != i.GuildID || !sch.AwaitingApproval {
+		decision = "This message is not waiting for approval anymore."
+	} else if approved {
+		// the schedule is replaced by an approved copy, as a dispatcher
+		// worker may be reading it
+		approvedSch := *sch
+		approvedSch.AwaitingApproval = false
+		if schedules.replace(&approvedSch) == nil {
+			decision = "This message is not waiting for approval anymore."
+		} else {
+			watchSchedule(s, &approvedSch)
+			audit(s, AuditApproved, moderator.ID, &approvedSch)
+			decision = "Approved by <@" + moderator.ID + ">."
+			err := notifyAuthor(s, sch, "Your message `"+sch.ID+"` for "+sch.target()+" was approved by the moderators.")
+			if err != nil {
+				logger.Error("Error notifying author", "error", err, "id", sch.ID, "author", sch.AuthorID)
+			}
+		}
+	} else {
+		if !schedules.remove(sch) {
+			decision = "This message is not waiting for approval anymore."
+		} else {
+			audit(s, AuditRejected, moderator.ID, sch)
+			decision = "Rejected by <@" + moderator.ID + ">."
+			err := notifyAuthor(s, sch, "Your message `"+sch.ID+"` for "+sch.target()+" was rejected by the moderators.")
+			if err != nil {
+				logger.Error("Error notifying author", "error", err, "id", sch.ID, "author", sch.AuthorID)
+			}
+		}
+	}
+	logger.Info("Message reviewed", "id", id, "moderator", moderator.ID, "approved", approved)
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:         truncate(decision+"\n"+i.Message.Content, maxMessageLength),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+			Components:      []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		logger.Error("Error responding to interaction", "error", err)
+	}
+}
@@ -0,0 +1,140 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// GuildArchive is everything the bot knows about a guild.
+type GuildArchive struct {
+	GuildID     string          `json:"guild_id"`
+	ExportedAt  time.Time       `json:"exported_at"`
+	Config      GuildConfig     `json:"config"`
+	Flags       map[string]bool `json:"flags,omitempty"`
+	Feeds       []Feed          `json:"feeds,omitempty"`
+	DeadLetters []DeadLetter    `json:"dead_letters,omitempty"`
+	Schedules   []*Schedule     `json:"schedules"`
+	Audit       []AuditEntry    `json:"audit"`
+}
+
+// PurgeGuild removes the configuration, flags, feeds, failed deliveries and
+// audit trail of the guild from the store and returns them.
+func (st *Store) PurgeGuild(guildID string) (GuildArchive, error) {
+	archive := GuildArchive{GuildID: guildID, ExportedAt: time.Now(), Audit: []AuditEntry{}}
+	err := st.update(func(data *storeData) {
+		archive.Config = data.Guilds[guildID]
+		archive.Flags = data.Flags[guildID]
+		archive.Feeds = data.Feeds[guildID]
+		delete(data.Guilds, guildID)
+		delete(data.Flags, guildID)
+		delete(data.Feeds, guildID)
+
+		kept := data.Audit[:0]
+		for _, entry := range data.Audit {
+			if entry.GuildID == guildID {
+				archive.Audit = append(archive.Audit, entry)
+			} else {
+				kept = append(kept, entry)
+			}
+		}
+		data.Audit = kept
+		delete(data.AuditDropped, guildID)
+		data.DeadLetters = slices.DeleteFunc(data.DeadLetters, func(letter DeadLetter) bool {
+			if letter.Schedule.GuildID != guildID {
+				return false
+			}
+			archive.DeadLetters = append(archive.DeadLetters, letter)
+			return true
+		})
+	})
+	return archive, err
+}
+
+// guildDelete archives and purges the data of a guild the bot was removed
+// from. The archive is sent to the owner of the guild, or written in the
+// archive directory if they cannot be reached.
+func guildDelete(s *discordgo.Session, g *discordgo.GuildDelete) {
+	// an unavailable guild is an outage, not a removal
+	if g.Unavailable {
+		return
+	}
+
+	// the pending schedules won't be able to be sent anymore
+	pending := schedules.guild(g.ID)
+	for _, sch := range pending {
+		schedules.take(sch.ID)
+	}
+	archive, err := store.PurgeGuild(g.ID)
+	if err != nil {
+		logger.Error("Error purging guild", "error", err, "guild", g.ID)
+		return
+	}
+	archive.Schedules = pending
+	logger.Info("Bot removed from guild, data purged", "guild", g.ID, "schedules", len(pending), "audit", len(archive.Audit))
+
+	content, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		logger.Error("Error encoding guild archive", "error", err, "guild", g.ID)
+		return
+	}
+	err = sendArchive(s, archive.Config.OwnerID, g.ID, content)
+	if err == nil {
+		return
+	}
+	logger.Error("Error sending guild archive to its owner", "error", err, "guild", g.ID, "owner", archive.Config.OwnerID)
+
+	archiveDir := settings().archiveDir
+	path := filepath.Join(archiveDir, "guild-"+g.ID+".json")
+	err = os.MkdirAll(archiveDir, 0o700)
+	if err == nil {
+		err = os.WriteFile(path, content, 0o600)
+	}
+	if err != nil {
+		logger.Error("Error writing guild archive", "error", err, "guild", g.ID, "path", path)
+		return
+	}
+	logger.Info("Guild archive written", "guild", g.ID, "path", path)
+}
+
+// sendArchive sends the archive of the guild to its owner in DM.
+func sendArchive(s *discordgo.Session, ownerID string, guildID string, content []byte) error {
+	if ownerID == "" {
+		return errors.New("owner unknown")
+	}
+	channel, err := s.UserChannelCreate(ownerID)
+	if err != nil {
+		return err
+	}
+	_, err = s.ChannelMessageSendComplex(channel.ID, &discordgo.MessageSend{
+		Content: "I was removed from your server " + guildID + ". Here is everything that was scheduled there, its history and its configuration. Nothing is kept on my side.",
+		Files: []*discordgo.File{
+			{
+				Name:        "sendlater-" + guildID + ".json",
+				ContentType: "application/json",
+				Reader:      bytes.NewReader(content),
+			},
+		},
+	})
+	return err
+}
@@ -0,0 +1,301 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// attachmentSweepPeriod is how often the stored files that are not used
+// anymore are deleted, a file being kept at least this long.
+const attachmentSweepPeriod = time.Hour
+
+// missingFile returns the name of the first stored file of the schedule that
+// is not on the disk anymore, or an empty string.
+func (sch *Schedule) missingFile() string {
+	for _, file := range sch.Files {
+		if file.Path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.Path); errors.Is(err, fs.ErrNotExist) {
+			return file.Name
+		}
+	}
+	return ""
+}
+
+// attachmentTooLarge returns the error of a file over the size limit.
+func attachmentTooLarge(name string) error {
+	return &userError{
+		Code:    codeTooLarge,
+		Message: "the file " + name + " is larger than " + strconv.FormatInt(settings().maxAttachmentSize>>20, 10) + " MB",
+		Hint:    "Upload a smaller file, or share a link to it in the message.",
+	}
+}
+
+// checkAttachmentSize rejects a file larger than the limit, with the size
+// given by Discord, before it is downloaded.
+func checkAttachmentSize(name string, size int) error {
+	if int64(size) > settings().maxAttachmentSize {
+		return attachmentTooLarge(name)
+	}
+	return nil
+}
+
+// fetchAttachment streams the file at the URL into w, and fails as soon as it
+// is larger than the limit, whatever size the server announced.
+func fetchAttachment(ctx context.Context, attachmentUrl string, w io.Writer) (string, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, attachmentUrl, nil)
+	if err != nil {
+		return "", 0, errors.New("Could not get attachment: " + err.Error())
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logger.Error("Could not get attachment", "error", err, "url", attachmentUrl)
+		return "", 0, errors.New("Could not get attachment: " + err.Error())
+	}
+	defer resp.Body.Close()
+	name := path.Base(req.URL.Path)
+	maxAttachmentSize := settings().maxAttachmentSize
+	if resp.ContentLength > maxAttachmentSize {
+		return "", 0, attachmentTooLarge(name)
+	}
+	size, err := io.Copy(w, io.LimitReader(resp.Body, maxAttachmentSize+1))
+	if err != nil {
+		logger.Error("Could not get attachment", "error", err, "url", attachmentUrl)
+		return "", size, errors.New("Could not get attachment: " + err.Error())
+	}
+	if size > maxAttachmentSize {
+		return "", size, attachmentTooLarge(name)
+	}
+	return resp.Header.Get("Content-type"), size, nil
+}
+
+// storeAttachment streams the file at the URL to the disk, where it waits for
+// its schedule to be sent.
+func storeAttachment(ctx context.Context, attachmentUrl string, name string, contentType string) (file ScheduledFile, err error) {
+	_, sp := startSpan(ctx, "store attachment", spanKindClient)
+	var size int64
+	defer func() {
+		sp.setAttribute("size", strconv.FormatInt(size, 10))
+		sp.end(err)
+	}()
+	file = ScheduledFile{Name: name, ContentType: contentType}
+	err = os.MkdirAll(AttachmentDir, 0o700)
+	if err != nil {
+		return file, errors.New("Could not store attachment: " + err.Error())
+	}
+	stored, err := os.CreateTemp(AttachmentDir, "*")
+	if err != nil {
+		return file, errors.New("Could not store attachment: " + err.Error())
+	}
+	_, size, err = fetchAttachment(ctx, attachmentUrl, stored)
+	if closeErr := stored.Close(); err == nil && closeErr != nil {
+		err = errors.New("Could not store attachment: " + closeErr.Error())
+	}
+	if err != nil {
+		_ = os.Remove(stored.Name())
+		return file, err
+	}
+	file.Path = stored.Name()
+	return file, nil
+}
+
+// reader returns the content of the file, read from the disk as it is sent
+// when it was stored there.
+func (f ScheduledFile) reader() io.Reader {
+	if f.Path != "" {
+		return &storedFile{path: f.Path}
+	}
+	return bytes.NewReader(f.Data)
+}
+
+// storedFile opens the file on its first read and closes it at its end or on
+// an error, so that a message built but not sent doesn't keep it open. It is
+// closed by closeFiles when the upload stopped before.
+type storedFile struct {
+	path   string
+	file   *os.File
+	closed bool
+}
+
+func (f *storedFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file == nil {
+		file, err := os.Open(f.path)
+		if err != nil {
+			f.closed = true
+			return 0, errors.New("Could not read attachment: " + err.Error())
+		}
+		f.file = file
+	}
+	n, err := f.file.Read(p)
+	if err != nil {
+		_ = f.Close()
+	}
+	return n, err
+}
+
+// Close closes the file if it was opened.
+func (f *storedFile) Close() error {
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// closeFiles closes the stored files of a message once it was sent, or failed
+// to be, as discordgo doesn't close what it reads.
+func closeFiles(files []*discordgo.File) {
+	for _, file := range files {
+		if closer, ok := file.Reader.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}
+}
+
+// inlineFiles returns the files with the content of those stored on the disk,
+// for a backup restored on another host.
+func inlineFiles(files []ScheduledFile) []ScheduledFile {
+	inlined := make([]ScheduledFile, len(files))
+	for n, file := range files {
+		inlined[n] = file
+		if file.Path == "" {
+			continue
+		}
+		data, err := os.ReadFile(file.Path)
+		if err != nil {
+			logger.Warn("Cannot read attachment", "error", err, "path", file.Path)
+			continue
+		}
+		inlined[n].Data = data
+		inlined[n].Path = ""
+	}
+	return inlined
+}
+
+// heldFiles are the files of the schedules being sent, which are neither
+// pending nor in the outbox for a moment.
+var heldFiles = struct {
+	sync.Mutex
+	count map[string]int
+}{count: map[string]int{}}
+
+// holdFiles keeps the stored files of the schedule until the returned
+// function is called.
+func holdFiles(sch *Schedule) func() {
+	heldFiles.Lock()
+	defer heldFiles.Unlock()
+	for _, file := range sch.Files {
+		if file.Path != "" {
+			heldFiles.count[file.Path]++
+		}
+	}
+	return func() {
+		heldFiles.Lock()
+		defer heldFiles.Unlock()
+		for _, file := range sch.Files {
+			if file.Path == "" {
+				continue
+			}
+			heldFiles.count[file.Path]--
+			if heldFiles.count[file.Path] <= 0 {
+				delete(heldFiles.count, file.Path)
+			}
+		}
+	}
+}
+
+// attachmentPaths adds the stored files of the interrupted and failed
+// deliveries to used.
+func (st *Store) attachmentPaths(used map[string]bool) {
+	st.view(func(data *storeData) {
+		for _, entry := range data.Outbox {
+			for _, file := range entry.Schedule.Files {
+				used[file.Path] = true
+			}
+		}
+		for _, dead := range data.DeadLetters {
+			for _, file := range dead.Schedule.Files {
+				used[file.Path] = true
+			}
+		}
+	})
+}
+
+// sweepAttachments deletes the stored files that no pending, interrupted or
+// failed schedule uses anymore, unless they were just downloaded for a
+// schedule being created.
+func sweepAttachments(now time.Time) {
+	entries, err := os.ReadDir(AttachmentDir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn("Cannot list attachments", "error", err)
+		}
+		return
+	}
+	used := schedules.filePaths()
+	store.attachmentPaths(used)
+	heldFiles.Lock()
+	for path := range heldFiles.count {
+		used[path] = true
+	}
+	heldFiles.Unlock()
+	for _, entry := range entries {
+		path := filepath.Join(AttachmentDir, entry.Name())
+		info, err := entry.Info()
+		if used[path] || err != nil || now.Sub(info.ModTime()) < attachmentSweepPeriod {
+			continue
+		}
+		err = os.Remove(path)
+		if err != nil {
+			logger.Warn("Cannot delete attachment", "error", err, "path", path)
+			continue
+		}
+		logger.Debug("Attachment deleted", "path", path)
+	}
+}
+
+// watchAttachments deletes the stored files that are not used anymore, once
+// at startup and then periodically.
+func watchAttachments() {
+	go func() {
+		defer recoverPanics(map[string]string{"task": "attachments"})
+		ticker := time.NewTicker(attachmentSweepPeriod)
+		defer ticker.Stop()
+		for {
+			sweepAttachments(time.Now())
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,244 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// maxAuditEntries is how many entries of the audit trail of each guild are
+// kept in the store, which is written whole on every change, the oldest ones
+// are dropped first.
+const maxAuditEntries = 10000
+
+// Actions recorded in the audit trail.
+const (
+	AuditScheduled = "scheduled"
+	AuditEdited    = "edited"
+	AuditCancelled = "cancelled"
+	AuditSent      = "sent"
+	AuditFailed    = "failed"
+	AuditApproved  = "approved"
+	AuditRejected  = "rejected"
+)
+
+// AuditEntry records who did what to a schedule, and when.
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	GuildID    string    `json:"guild_id"`
+	UserID     string    `json:"user_id"`
+	Action     string    `json:"action"`
+	ScheduleID string    `json:"schedule_id"`
+	ChannelID  string    `json:"channel_id"`
+	SendAt     time.Time `json:"send_at"`
+	Content    string    `json:"content"`
+	// Anonymous is set when the message is sent without showing its author.
+	Anonymous bool `json:"anonymous,omitempty"`
+}
+
+// Audit appends an entry for the action of the user on the schedule. It is
+// written with the next write of the store rather than on its own, and
+// failing to write it doesn't prevent the action.
+func (st *Store) Audit(action string, userID string, sch *Schedule) {
+	entry := AuditEntry{
+		Time:       time.Now(),
+		GuildID:    sch.GuildID,
+		UserID:     userID,
+		Action:     action,
+		ScheduleID: sch.ID,
+		ChannelID:  sch.ChannelID,
+		SendAt:     sch.SendAt,
+		Content:    sch.preview(),
+		Anonymous:  sch.Sender == senderAnonymous,
+	}
+	st.updateLater(func(data *storeData) {
+		data.Audit = append(data.Audit, entry)
+		count := 0
+		for _, kept := range data.Audit {
+			if kept.GuildID == entry.GuildID {
+				count++
+			}
+		}
+		if count <= maxAuditEntries {
+			return
+		}
+		// a busy guild only drops its own entries, and the exports tell
+		// what is missing
+		oldest := slices.IndexFunc(data.Audit, func(kept AuditEntry) bool {
+			return kept.GuildID == entry.GuildID
+		})
+		if data.AuditDropped == nil {
+			data.AuditDropped = map[string]time.Time{}
+		}
+		data.AuditDropped[entry.GuildID] = data.Audit[oldest].Time
+		data.Audit = slices.Delete(data.Audit, oldest, oldest+1)
+	})
+}
+
+// audit records the action of the user on the schedule, publishes it to the
+// subscribers of the events and posts it in the audit channel of the guild,
+// if any.
+func audit(s *discordgo.Session, action string, userID string, sch *Schedule) {
+	store.Audit(action, userID, sch)
+	publish(action, userID, sch)
+	channelID := store.GuildConfig(sch.GuildID).AuditChannelID
+	if channelID == "" {
+		return
+	}
+	content := "Message `" + sch.ID + "` " + action + " by <@" + userID + "> in " + sch.target() + " for <t:" + strconv.FormatInt(sch.SendAt.Unix(), 10) + ":F>"
+	if sch.Sender == senderAnonymous {
+		content += " (anonymous)"
+	}
+	// the moderators see what is sent without opening the export, except the
+	// messages sent in DM, private to their author
+	if preview := oneLine(sch.preview()); preview != "" && !sch.DM {
+		content += "\n> " + truncate(preview, 200)
+	}
+	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		logger.Error("Error posting audit entry", "error", err, "channel", channelID, "id", sch.ID)
+	}
+}
+
+// AuditEntries returns the entries of the guild recorded between from and to.
+func (st *Store) AuditEntries(guildID string, from time.Time, to time.Time) []AuditEntry {
+	entries := []AuditEntry{}
+	st.view(func(data *storeData) {
+		for _, entry := range data.Audit {
+			if entry.GuildID == guildID && !entry.Time.Before(from) && entry.Time.Before(to) {
+				entries = append(entries, entry)
+			}
+		}
+	})
+	return entries
+}
+
+// AuditTruncated reports whether entries of the guild recorded between from
+// and to were dropped to keep the store small.
+func (st *Store) AuditTruncated(guildID string, from time.Time, to time.Time) bool {
+	truncated := false
+	st.view(func(data *storeData) {
+		dropped, found := data.AuditDropped[guildID]
+		truncated = found && !dropped.Before(from) && dropped.Before(to)
+	})
+	return truncated
+}
+
+func handleAudit(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if i.GuildID == "" {
+		respond(s, i, "The audit trail is only available in a server")
+		return
+	}
+	if !hasPermission(i, discordgo.PermissionManageServer) {
+		respond(s, i, "Only the server admins can export the audit trail")
+		return
+	}
+
+	location := store.GuildConfig(i.GuildID).location()
+	format := ""
+	from := time.Time{}
+	to := time.Now().In(location)
+	for _, option := range options {
+		var err error
+		if option.Name == "format" {
+			format = option.StringValue()
+		} else if option.Name == "from" {
+			from, err = time.ParseInLocation("02/01/2006", option.StringValue(), location)
+		} else if option.Name == "to" {
+			to, err = time.ParseInLocation("02/01/2006", option.StringValue(), location)
+		}
+		if err != nil {
+			respondError(s, i, "Error exporting audit trail", checkDate(option.StringValue(), time.Now().In(location)))
+			return
+		}
+	}
+	// the last day is included
+	to = time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, location)
+
+	entries := store.AuditEntries(i.GuildID, from, to)
+	var content []byte
+	var err error
+	contentType := ""
+	if format == "json" {
+		contentType = "application/json"
+		content, err = json.MarshalIndent(entries, "", "  ")
+	} else {
+		contentType = "text/csv"
+		content, err = encodeAuditCSV(entries)
+	}
+	if err != nil {
+		logger.Error("Error exporting audit trail", "error", err, "guild", i.GuildID)
+		respondError(s, i, "Error exporting audit trail", err)
+		return
+	}
+
+	message := "Here is the audit trail of this server"
+	if store.AuditTruncated(i.GuildID, from, to) {
+		message += ", the oldest entries were dropped as only the last " + strconv.Itoa(maxAuditEntries) + " are kept"
+	}
+	logger.Info("Exporting audit trail", "guild", i.GuildID, "user", interactionUser(i).ID, "count", len(entries))
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Files: []*discordgo.File{
+				{
+					Name:        "audit." + format,
+					ContentType: contentType,
+					Reader:      bytes.NewReader(content),
+				},
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error responding to interaction", "error", err)
+	}
+}
+
+// encodeAuditCSV writes the entries as CSV with a header line.
+func encodeAuditCSV(entries []AuditEntry) ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	records := [][]string{{"time", "guild_id", "user_id", "action", "schedule_id", "channel_id", "send_at", "content", "anonymous"}}
+	for _, entry := range entries {
+		records = append(records, []string{
+			entry.Time.Format(time.RFC3339),
+			entry.GuildID,
+			entry.UserID,
+			entry.Action,
+			entry.ScheduleID,
+			entry.ChannelID,
+			entry.SendAt.Format(time.RFC3339),
+			entry.Content,
+			strconv.FormatBool(entry.Anonymous),
+		})
+	}
+	err := w.WriteAll(records)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
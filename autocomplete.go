@@ -0,0 +1,176 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxChoices is the maximum number of autocomplete suggestions.
+const maxChoices = 25
+
+// relativeTimes are suggested when nothing is typed yet.
+var relativeTimes = []struct {
+	label string
+	delay time.Duration
+}{
+	{"in 15 minutes", 15 * time.Minute},
+	{"in 30 minutes", 30 * time.Minute},
+	{"in 1 hour", time.Hour},
+	{"in 2 hours", 2 * time.Hour},
+}
+
+// namedTimes are suggested when their name starts with what is typed.
+var namedTimes = []struct {
+	name   string
+	hour   int
+	minute int
+}{
+	{"morning", 9, 0},
+	{"noon", 12, 0},
+	{"afternoon", 15, 0},
+	{"tonight", 20, 0},
+}
+
+// handleAutocomplete suggests values for the focused option of a command.
+func handleAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	config := store.GuildConfig(i.GuildID)
+	options := data.Options
+	if unsuffixedName(data.Name, data.CommandType) == "sendlater" {
+		if len(options) == 0 {
+			return
+		}
+		// the broadcasts are read in the time zone of the bot
+		if options[0].Name == "broadcast" {
+			config = GuildConfig{}
+		}
+		options = options[0].Options
+	}
+
+	typed := ""
+	date := ""
+	focused := ""
+	for _, option := range options {
+		if option.Focused {
+			focused = option.Name
+			typed = option.StringValue()
+		} else if option.Name == "date" {
+			date = option.StringValue()
+		}
+	}
+
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	if focused == "time" {
+		choices = timeSuggestions(typed, date, time.Now().In(config.location()))
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		logger.Error("Error responding to autocomplete", "error", err)
+	}
+}
+
+// timeSuggestions returns the times the member may mean by typed, as HH:MM
+// values labelled with how they were read: a delay like "30", "in 45m" or
+// "2h", a name like "tonight", or a time like "14", "1430" or "14h30".
+// Delays and names are only suggested on the current day, when the date is
+// not set or set to today, as the date is a separate option.
+func timeSuggestions(typed string, date string, now time.Time) []*discordgo.ApplicationCommandOptionChoice {
+	typed = strings.ToLower(strings.TrimSpace(typed))
+	today := date == "" || date == now.Format("02/01/2006")
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	suggest := func(label string, t time.Time) {
+		if len(choices) == maxChoices {
+			return
+		}
+		value := t.Format("15:04")
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: label + " → " + value, Value: value})
+	}
+	// only the times to come on the current day can be reached with a delay
+	upcoming := func(t time.Time) bool {
+		return t.After(now) && t.YearDay() == now.YearDay() && t.Year() == now.Year()
+	}
+
+	if today && typed == "" {
+		for _, relative := range relativeTimes {
+			if t := now.Add(relative.delay); upcoming(t) {
+				suggest(relative.label, t)
+			}
+		}
+	}
+	// a delay like 2h is not also read as a time of the day
+	delayed := false
+	if today && typed != "" {
+		delay := strings.TrimSpace(strings.TrimPrefix(typed, "in "))
+		if minutes, err := strconv.Atoi(delay); err == nil && minutes > 0 {
+			if t := now.Add(time.Duration(minutes) * time.Minute); upcoming(t) {
+				suggest("in "+strconv.Itoa(minutes)+" minutes", t)
+			}
+		} else if d, err := parseDelay(delay); err == nil {
+			delayed = true
+			if t := now.Add(d); upcoming(t) {
+				suggest("in "+delay, t)
+			}
+		}
+	}
+	for _, named := range namedTimes {
+		t := time.Date(now.Year(), now.Month(), now.Day(), named.hour, named.minute, 0, 0, now.Location())
+		if strings.HasPrefix(named.name, typed) && (!today || upcoming(t)) {
+			suggest(named.name, t)
+		}
+	}
+	if hour, minute, ok := parseClock(typed); ok && !delayed {
+		t := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+		label := "at " + t.Format("15:04")
+		if today && !t.After(now) {
+			label += ", already passed today"
+		}
+		suggest(label, t)
+	}
+	return choices
+}
+
+// parseClock reads a time of the day written 14, 14:30, 14h30, 1430 or 9:5
+// (as 09:50, the minutes being typed).
+func parseClock(typed string) (int, int, bool) {
+	hours, minutes, found := strings.Cut(strings.ReplaceAll(typed, "h", ":"), ":")
+	if !found && len(hours) > 2 {
+		hours, minutes = hours[:len(hours)-2], hours[len(hours)-2:]
+	}
+	if len(minutes) == 1 {
+		minutes += "0"
+	}
+	hour, err := strconv.Atoi(hours)
+	if err != nil || hour < 0 || hour > 23 || len(hours) > 2 {
+		return 0, 0, false
+	}
+	minute := 0
+	if minutes != "" {
+		minute, err = strconv.Atoi(minutes)
+		if err != nil || minute < 0 || minute > 59 || len(minutes) > 2 {
+			return 0, 0, false
+		}
+	}
+	return hour, minute, true
+}
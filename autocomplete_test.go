@@ -0,0 +1,33 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeSuggestions(t *testing.T) {
+	now := time.Date(2025, 6, 15, 9, 0, 0, 0, time.UTC)
+	choices := timeSuggestions("2h", "", now)
+	if len(choices) != 1 || choices[0].Value != "11:00" {
+		t.Errorf("2h today: got %d choices, want only in 2h", len(choices))
+	}
+	choices = timeSuggestions("14h30", "", now)
+	if len(choices) != 1 || choices[0].Value != "14:30" {
+		t.Errorf("14h30 today: got %d choices, want only at 14:30", len(choices))
+	}
+}
@@ -0,0 +1,209 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// backupMaxSize is the maximum size of a backup to restore, the files of the
+// schedules are in it.
+const backupMaxSize = 32 << 20
+
+// ScheduleBackup is the export of the pending schedules of a guild, to
+// restore them on another host or after losing the bot.
+type ScheduleBackup struct {
+	GuildID    string      `json:"guild_id"`
+	ExportedAt time.Time   `json:"exported_at"`
+	Schedules  []*Schedule `json:"schedules"`
+}
+
+// exportSchedules returns the backup of the pending schedules of the guild.
+func exportSchedules(guildID string, now time.Time) ScheduleBackup {
+	list := schedules.guild(guildID)
+	// the stored files are included, to restore the backup on another host
+	for n, sch := range list {
+		if len(sch.Files) > 0 {
+			copied := *sch
+			copied.Files = inlineFiles(sch.Files)
+			list[n] = &copied
+		}
+	}
+	return ScheduleBackup{GuildID: guildID, ExportedAt: now, Schedules: list}
+}
+
+// restoreSchedules schedules again the schedules of the backup under their
+// own IDs, and returns how many were restored and why the others were not.
+// The schedules already pending are left as they are, so a backup can be
+// restored twice, and those that were due before now are not sent.
+func restoreSchedules(s *discordgo.Session, guildID string, backup ScheduleBackup, userID string, now time.Time) (int, []error) {
+	if backup.GuildID != guildID {
+		return 0, []error{errors.New("the backup is the one of the server " + backup.GuildID)}
+	}
+	restored := 0
+	errs := []error{}
+	for _, sch := range backup.Schedules {
+		if sch == nil || sch.ID == "" {
+			errs = append(errs, errors.New("a message has no ID"))
+			continue
+		}
+		if sch.GuildID != guildID {
+			errs = append(errs, errors.New(sch.ID+": the message is not in this server"))
+			continue
+		}
+		if schedules.get(sch.ID) != nil {
+			errs = append(errs, errors.New(sch.ID+": the message is already pending"))
+			continue
+		}
+		if !sch.Paused && !sch.SendAt.After(now) {
+			errs = append(errs, errors.New(sch.ID+": the message was due at "+sch.SendAt.Format(time.RFC3339)+", it is not sent"))
+			continue
+		}
+		schedules.readd(sch)
+		logger.Debug("Schedule restored", "id", sch.ID, "guild", sch.GuildID, "sendAt", sch.SendAt)
+		audit(s, AuditScheduled, userID, sch)
+		if sch.AwaitingApproval {
+			requestApproval(s, sch)
+		}
+		watchSchedule(s, sch)
+		restored++
+	}
+	logger.Info("Schedules restored", "guild", guildID, "count", restored, "errors", len(errs), "user", userID)
+	return restored, errs
+}
+
+// decodeBackup reads a backup, of at most backupMaxSize bytes.
+func decodeBackup(r io.Reader) (ScheduleBackup, error) {
+	backup := ScheduleBackup{}
+	data, err := io.ReadAll(io.LimitReader(r, backupMaxSize+1))
+	if err != nil {
+		return backup, errors.New("Error reading backup: " + err.Error())
+	}
+	if len(data) > backupMaxSize {
+		return backup, errors.New("the backup is larger than " + strconv.Itoa(backupMaxSize>>20) + " MB")
+	}
+	err = json.Unmarshal(data, &backup)
+	if err != nil {
+		return backup, errors.New("Error decoding backup: " + err.Error())
+	}
+	return backup, nil
+}
+
+// Actions of the backup subcommand.
+const (
+	backupExport  = "export"
+	backupRestore = "restore"
+)
+
+func handleBackup(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if i.GuildID == "" {
+		respond(s, i, "The backups are only available in a server")
+		return
+	}
+	if !hasPermission(i, discordgo.PermissionManageServer) {
+		respond(s, i, "Only the server admins can export and restore the messages")
+		return
+	}
+	action := ""
+	attachmentUrl := ""
+	for _, option := range options {
+		if option.Name == "action" {
+			action = option.StringValue()
+		} else if option.Name == "file" {
+			attachmentUrl = i.ApplicationCommandData().Resolved.Attachments[option.Value.(string)].URL
+		}
+	}
+	user := interactionUser(i)
+
+	switch action {
+	case backupExport:
+		backup := exportSchedules(i.GuildID, time.Now())
+		data, err := json.MarshalIndent(backup, "", "  ")
+		if err != nil {
+			respondError(s, i, "Error exporting messages", err)
+			return
+		}
+		logger.Info("Exporting schedules", "guild", i.GuildID, "count", len(backup.Schedules), "user", user.ID)
+		// the backup holds every message to come, it is only shown to the
+		// admin
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Here are the " + strconv.Itoa(len(backup.Schedules)) + " messages scheduled in this server, restore them with `/sendlater backup action:Restore`",
+				Flags:   discordgo.MessageFlagsEphemeral,
+				Files: []*discordgo.File{
+					{
+						Name:        "sendlater-" + i.GuildID + ".json",
+						ContentType: "application/json",
+						Reader:      bytes.NewReader(data),
+					},
+				},
+			},
+		})
+		if err != nil {
+			logger.Error("Error responding to interaction", "error", err)
+		}
+	case backupRestore:
+		if attachmentUrl == "" {
+			respondEphemeral(s, i, "The backup file is required to restore the messages")
+			return
+		}
+		// downloading the backup can take longer than the time allowed to
+		// respond
+		deferResponse(s, i, discordgo.MessageFlagsEphemeral)
+		data, _, err := downloadFile(interactionContext(i), attachmentUrl)
+		if err != nil {
+			respondError(s, i, "Error restoring messages", err)
+			return
+		}
+		backup, err := decodeBackup(bytes.NewReader(data))
+		if err != nil {
+			respondError(s, i, "Error restoring messages", err)
+			return
+		}
+		restored, errs := restoreSchedules(s, i.GuildID, backup, user.ID, time.Now())
+		reply := "Restored " + strconv.Itoa(restored) + " messages"
+		for _, err := range errs {
+			reply += "\n- " + err.Error()
+		}
+		respondEphemeral(s, i, truncate(reply, maxMessageLength))
+	}
+}
+
+func apiExportSchedules(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, exportSchedules(r.PathValue("guild"), time.Now()))
+}
+
+func apiRestoreSchedules(s *discordgo.Session, w http.ResponseWriter, r *http.Request) {
+	backup, err := decodeBackup(r.Body)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	restored, errs := restoreSchedules(s, r.PathValue("guild"), backup, s.State.User.ID, time.Now())
+	skipped := []string{}
+	for _, err := range errs {
+		skipped = append(skipped, err.Error())
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]any{"restored": restored, "skipped": skipped})
+}
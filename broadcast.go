@@ -0,0 +1,116 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+)
+
+// handleBroadcast schedules the same message in the channel of the given name
+// in every guild of the bot. It is reserved to the bot owners.
+func handleBroadcast(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	user := interactionUser(i)
+	if !isOwner(user.ID) {
+		logger.Warn("Broadcast refused", "user", user.ID)
+		respond(s, i, "Only the bot owners can broadcast messages")
+		return
+	}
+
+	message := ""
+	sendTime := ""
+	date := ""
+	channelName := ""
+	for _, option := range options {
+		if option.Name == "message" {
+			message = option.StringValue()
+		} else if option.Name == "time" {
+			sendTime = option.StringValue()
+		} else if option.Name == "date" {
+			date = option.StringValue()
+		} else if option.Name == "channel_name" {
+			channelName = strings.TrimPrefix(strings.TrimSpace(option.StringValue()), "#")
+		}
+	}
+
+	// the message is sent at the same moment everywhere, so the time is read
+	// in the time zone of the bot rather than in the one of each guild
+	fixedTime, err := parseSendTime(date, sendTime, GuildConfig{})
+	if err != nil {
+		logger.Error("Error broadcasting message: ", "error", err)
+		respondError(s, i, "Error broadcasting message", err)
+		return
+	}
+
+	scheduled := 0
+	missing := []string{}
+	for _, target := range broadcastChannels(s, channelName) {
+		if target.channel == nil {
+			missing = append(missing, target.guild.Name)
+			continue
+		}
+		startSchedule(s, &Schedule{
+			GuildID:     target.guild.ID,
+			ChannelID:   target.channel.ID,
+			ChannelName: target.channel.Name,
+			AuthorID:    user.ID,
+			Content:     message,
+			SendAt:      fixedTime,
+		})
+		scheduled++
+	}
+	logger.Info("Message broadcast", "message", message, "channel", channelName, "guilds", scheduled, "missing", len(missing), "user", user.ID)
+
+	content := "Message scheduled in #" + channelName + " in " + strconv.Itoa(scheduled) + " servers."
+	if len(missing) > 0 {
+		content += "\nNo #" + channelName + " channel in: " + strings.Join(missing, ", ")
+	}
+	respond(s, i, truncate(content, maxMessageLength))
+}
+
+// broadcastTarget is the channel of a guild receiving a broadcast, nil when
+// the guild has no channel of that name.
+type broadcastTarget struct {
+	guild   *discordgo.Guild
+	channel *discordgo.Channel
+}
+
+// broadcastChannels finds the text or announcement channel named name in
+// every guild of the bot.
+func broadcastChannels(s *discordgo.Session, name string) []broadcastTarget {
+	s.State.RLock()
+	defer s.State.RUnlock()
+
+	targets := []broadcastTarget{}
+	for _, guild := range s.State.Guilds {
+		if !guildAllowed(guild.ID) {
+			continue
+		}
+		target := broadcastTarget{guild: guild}
+		for _, channel := range guild.Channels {
+			if channel.Name != name {
+				continue
+			}
+			if channel.Type == discordgo.ChannelTypeGuildText || channel.Type == discordgo.ChannelTypeGuildNews {
+				target.channel = channel
+				break
+			}
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
@@ -0,0 +1,225 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// calendarHorizon is how far ahead the announcements of the calendar are
+// scheduled, the later events are scheduled by the next polls.
+const calendarHorizon = 7 * 24 * time.Hour
+
+// defaultCalendarTemplate is the announcement of an event when
+// SENDLATER_CALENDAR_TEMPLATE is not set.
+const defaultCalendarTemplate = "📅 **{title}** starts {relative}\n{location}\n{description}"
+
+// maxCalendarSize is the size of the largest calendar that is read, a
+// calendar of a few years of events being far smaller.
+const maxCalendarSize = 10 << 20
+
+var calendarClient = &http.Client{Timeout: 30 * time.Second}
+
+// calendarAnnouncement is an announcement scheduled for an occurrence of an
+// event of the calendar.
+type calendarAnnouncement struct {
+	scheduleID string
+	content    string
+}
+
+// calendarWatcher schedules the announcements of the events of a calendar in
+// a channel. It is only used by its goroutine.
+type calendarWatcher struct {
+	url       string
+	channelID string
+	lead      time.Duration
+	template  string
+	// announcements are by event UID and start time, they are kept after
+	// being sent or cancelled so that a member can cancel an announcement
+	// without the next poll scheduling it again
+	announcements map[string]calendarAnnouncement
+}
+
+// watchCalendar polls the iCalendar feed at url, like the secret address of
+// a Google Calendar, every interval and announces each event in the channel
+// lead before it starts, so the events team only maintains the calendar.
+func watchCalendar(s *discordgo.Session, url string, channelID string, lead string, interval string, template string) {
+	if url == "" {
+		return
+	}
+	// the settings are checked by checkSettings
+	leadDelay, _ := parseDelay(lead)
+	period, _ := parseDelay(interval)
+	w := &calendarWatcher{
+		url:           url,
+		channelID:     channelID,
+		lead:          leadDelay,
+		template:      template,
+		announcements: map[string]calendarAnnouncement{},
+	}
+	go func() {
+		defer recoverPanics(map[string]string{"task": "calendar", "channel.id": channelID})
+		logger.Info("Watching calendar", "channel", channelID, "lead", leadDelay, "interval", period)
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			err := w.sync(s, time.Now())
+			if err != nil {
+				logger.Error("Error syncing calendar", "error", err, "channel", channelID)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// sync schedules the announcements of the events starting in the horizon,
+// replaces those of the events that changed and cancels those of the events
+// that were removed or moved.
+func (w *calendarWatcher) sync(s *discordgo.Session, now time.Time) error {
+	content, err := fetchCalendar(w.url)
+	if err != nil {
+		return err
+	}
+	events, errs := decodeICal(content)
+	for _, err := range errs {
+		logger.Warn("Invalid calendar event", "error", err, "channel", w.channelID)
+	}
+	channel, err := s.State.Channel(w.channelID)
+	if err != nil {
+		channel, err = s.Channel(w.channelID)
+		if err != nil {
+			return errors.New("Error getting calendar channel: " + err.Error())
+		}
+	}
+
+	// the announcements are sent lead before the events, so we look for the
+	// events starting after now + lead
+	from := now.Add(w.lead)
+	until := from.Add(calendarHorizon)
+	seen := map[string]bool{}
+	for _, event := range events {
+		uid := event.UID
+		if uid == "" {
+			uid = event.Summary
+		}
+		for _, start := range event.occurrences(from, until, true) {
+			key := uid + "@" + strconv.FormatInt(start.Unix(), 10)
+			seen[key] = true
+			content := renderCalendarTemplate(w.template, event, start)
+			previous, found := w.announcements[key]
+			if found && previous.content == content {
+				continue
+			}
+			if found {
+				// the event changed, its announcement is replaced if it is
+				// still pending
+				if schedules.get(previous.scheduleID) == nil {
+					w.announcements[key] = calendarAnnouncement{scheduleID: previous.scheduleID, content: content}
+					continue
+				}
+				w.cancel(s, previous.scheduleID)
+			}
+			sch := &Schedule{
+				GuildID:     channel.GuildID,
+				ChannelID:   channel.ID,
+				ChannelName: channel.Name,
+				AuthorID:    s.State.User.ID,
+				Content:     truncate(content, maxMessageLength),
+				SendAt:      start.Add(-w.lead),
+			}
+			startSchedule(s, sch)
+			w.announcements[key] = calendarAnnouncement{scheduleID: sch.ID, content: content}
+			logger.Info("Calendar event announced", "event", event.Summary, "start", start, "id", sch.ID)
+		}
+	}
+
+	// the events removed from the calendar or moved are not announced
+	for key, announcement := range w.announcements {
+		if seen[key] {
+			continue
+		}
+		if sch := schedules.get(announcement.scheduleID); sch != nil && sch.SendAt.After(now) {
+			w.cancel(s, announcement.scheduleID)
+		}
+		delete(w.announcements, key)
+	}
+	return nil
+}
+
+// cancel cancels the announcement with the given schedule ID.
+func (w *calendarWatcher) cancel(s *discordgo.Session, id string) {
+	sch := schedules.take(id)
+	if sch == nil {
+		return
+	}
+	audit(s, AuditCancelled, s.State.User.ID, sch)
+	logger.Info("Calendar announcement cancelled", "id", id)
+}
+
+// fetchCalendar returns the content of the iCalendar feed at url.
+func fetchCalendar(url string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", errors.New("Error getting calendar: " + err.Error())
+	}
+	resp, err := calendarClient.Do(req)
+	if err != nil {
+		return "", errors.New("Error getting calendar: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("Error getting calendar: " + resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCalendarSize+1))
+	if err != nil {
+		return "", errors.New("Error getting calendar: " + err.Error())
+	}
+	if len(data) > maxCalendarSize {
+		return "", errors.New("Error getting calendar: it is larger than " + strconv.Itoa(maxCalendarSize>>20) + " MB")
+	}
+	return string(data), nil
+}
+
+// renderCalendarTemplate fills the placeholders of the template with the
+// event: {title}, {description}, {location}, {start} as a full date and
+// {relative} as a countdown, both shown in the time zone of each member.
+// The lines left empty by the placeholders are removed.
+func renderCalendarTemplate(template string, event icalEvent, start time.Time) string {
+	unix := strconv.FormatInt(start.Unix(), 10)
+	content := strings.NewReplacer(
+		"{title}", event.Summary,
+		"{description}", event.Description,
+		"{location}", event.Location,
+		"{start}", "<t:"+unix+":F>",
+		"{relative}", "<t:"+unix+":R>",
+	).Replace(strings.ReplaceAll(template, `\n`, "\n"))
+	lines := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,339 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"cmp"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// campaignPrefix starts the custom ID of the campaign modal, followed by the
+// ID of the target channel.
+const campaignPrefix = "campaign:"
+
+// campaignMaxSteps is the maximum number of messages of a campaign.
+const campaignMaxSteps = 20
+
+// campaignStep is a message of a campaign, sent Offset after its start.
+type campaignStep struct {
+	Offset  time.Duration
+	Content string
+}
+
+// handleCampaign opens a modal where the steps of the campaign are written,
+// one per line, along with its start time and date.
+func handleCampaign(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if !store.FlagEnabled(i.GuildID, FlagCampaigns) {
+		respond(s, i, "Campaigns are not enabled in this server")
+		return
+	}
+	config := store.GuildConfig(i.GuildID)
+
+	channelID := i.ChannelID
+	for _, option := range options {
+		if option.Name == "channel" {
+			channelID = option.ChannelValue(s).ID
+		}
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: campaignPrefix + channelID,
+			Title:    "Schedule a campaign",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "steps",
+						Label:       "Messages, one per line after their delay",
+						Style:       discordgo.TextInputParagraph,
+						Placeholder: "+0 Welcome!\n+1h Read the rules\n+1d How is it going?",
+						Required:    true,
+						MaxLength:   4000,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "time",
+						Label:       "Start time (HH:MM)",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "18:00",
+						Required:    true,
+						MinLength:   5,
+						MaxLength:   5,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "date",
+						Label:       "Start date (dd/mm/yyyy), default: today",
+						Style:       discordgo.TextInputShort,
+						Placeholder: time.Now().In(config.location()).Format("02/01/2006"),
+						Required:    false,
+						MaxLength:   10,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error opening campaign modal", "error", err)
+	}
+}
+
+// parseCampaign reads the steps of a campaign, one per line, like "+1h Read
+// the rules". The delay may start with T, like T+1d, and +0 is the start. The
+// steps are sorted by delay.
+func parseCampaign(text string) ([]campaignStep, error) {
+	steps := []campaignStep{}
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		delay, content, _ := strings.Cut(line, " ")
+		content = strings.TrimSpace(content)
+		if content == "" {
+			return nil, errors.New("line " + strconv.Itoa(n+1) + " has no message")
+		}
+		delay = strings.TrimPrefix(strings.TrimPrefix(delay, "T"), "+")
+		offset := time.Duration(0)
+		if delay != "0" {
+			var err error
+			offset, err = parseDelay(delay)
+			if err != nil {
+				return nil, errors.New("line " + strconv.Itoa(n+1) + ": " + err.Error())
+			}
+		}
+		steps = append(steps, campaignStep{Offset: offset, Content: content})
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("the campaign has no messages")
+	}
+	if len(steps) > campaignMaxSteps {
+		return nil, errors.New("a campaign has at most " + strconv.Itoa(campaignMaxSteps) + " messages")
+	}
+	slices.SortStableFunc(steps, func(a, b campaignStep) int {
+		return cmp.Compare(a.Offset, b.Offset)
+	})
+	return steps, nil
+}
+
+// handleCampaignSubmit schedules the steps of the campaign modal, sharing
+// the ID of the campaign.
+func handleCampaignSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !store.FlagEnabled(i.GuildID, FlagCampaigns) {
+		respond(s, i, "Campaigns are not enabled in this server")
+		return
+	}
+	data := i.ModalSubmitData()
+	channelID := strings.TrimPrefix(data.CustomID, campaignPrefix)
+	values := modalValues(data)
+	config := store.GuildConfig(i.GuildID)
+	author := interactionUser(i)
+
+	steps, err := parseCampaign(values["steps"])
+	if err != nil {
+		respondError(s, i, "Error scheduling campaign", err)
+		return
+	}
+	if len(steps) > config.quotaLeft(i.GuildID, author.ID) {
+		respond(s, i, "This campaign would exceed your pending messages in this server, cancel some or wait for them to be sent")
+		return
+	}
+
+	channel, err := s.Channel(channelID)
+	if err != nil {
+		logger.Error("Error scheduling campaign: ", "error", err)
+		respondError(s, i, "Error scheduling campaign", discordError("cannot get the channel", err))
+		return
+	}
+	err = checkChannel(channel, false)
+	for _, step := range steps {
+		if err == nil {
+			err = checkCanPost(s, author.ID, channel, step.Content, nil)
+		}
+	}
+	if err != nil {
+		respondError(s, i, "Error scheduling campaign", err)
+		return
+	}
+
+	date := values["date"]
+	if date == "" {
+		date = time.Now().In(config.location()).Format("02/01/2006")
+	}
+	start, err := parseSendTime(date, values["time"], config)
+	if err != nil {
+		logger.Error("Error scheduling campaign: ", "error", err)
+		respondError(s, i, "Error scheduling campaign", err)
+		return
+	}
+	// every step must be allowed, so that the campaign is scheduled whole
+	now := time.Now()
+	for _, step := range steps {
+		sendAt := start.Add(step.Offset)
+		if config.inQuietHours(sendAt) {
+			respondError(s, i, "Error scheduling campaign", &userError{Code: codeQuietHours, Message: "the message at +" + formatDelay(step.Offset) + " is during the quiet hours of the server (" + config.QuietHours + ")", Hint: "Change its delay or the start of the campaign."})
+			return
+		}
+		if err := checkSendBounds(sendAt, now); err != nil {
+			respondError(s, i, "Error scheduling campaign", err)
+			return
+		}
+	}
+
+	campaignID := newID()
+	for _, step := range steps {
+		startSchedule(s, &Schedule{
+			GuildID:     channel.GuildID,
+			ChannelID:   channel.ID,
+			ChannelName: channel.Name,
+			AuthorID:    author.ID,
+			Content:     step.Content,
+			Thread:      channel.IsThread(),
+			CampaignID:  campaignID,
+			SendAt:      start.Add(step.Offset),
+		})
+	}
+	logger.Info("Campaign scheduled", "id", campaignID, "steps", len(steps), "channel", channel.Name, "date", date, "sendTime", values["time"])
+	respondEmbed(s, i, campaignEmbed("Campaign scheduled", campaignID, schedules.campaign(campaignID)))
+}
+
+// campaignEmbed lists the pending steps of a campaign.
+func campaignEmbed(title string, campaignID string, steps []*Schedule) *discordgo.MessageEmbed {
+	lines := []string{}
+	for _, sch := range steps {
+		line := "<t:" + strconv.FormatInt(sch.SendAt.Unix(), 10) + ":f> " + truncate(oneLine(sch.preview()), 60) + " (`" + sch.ID + "`)"
+		lines = append(lines, line)
+	}
+	embed := &discordgo.MessageEmbed{
+		Title: title,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "ID", Value: "`" + campaignID + "`", Inline: true},
+			{Name: "Messages", Value: truncate(strings.Join(lines, "\n"), 1024)},
+		},
+	}
+	if len(steps) > 0 {
+		embed.Fields = slices.Insert(embed.Fields, 1, &discordgo.MessageEmbedField{Name: "Channel", Value: steps[0].target(), Inline: true})
+		if steps[0].Paused {
+			embed.Footer = &discordgo.MessageEmbedFooter{Text: "Paused, the messages wait until it is resumed"}
+		}
+	}
+	return embed
+}
+
+// handleCampaigns lists the campaigns of the server, or pauses, resumes or
+// cancels all the pending steps of one.
+func handleCampaigns(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	user := interactionUser(i)
+	action := manageList
+	id := ""
+	for _, option := range options {
+		if option.Name == "action" {
+			action = option.StringValue()
+		} else if option.Name == "id" {
+			id = option.StringValue()
+		}
+	}
+
+	if action == manageList {
+		campaigns := map[string][]*Schedule{}
+		ids := []string{}
+		for _, sch := range schedules.guild(i.GuildID) {
+			if sch.CampaignID == "" {
+				continue
+			}
+			if _, found := campaigns[sch.CampaignID]; !found {
+				ids = append(ids, sch.CampaignID)
+			}
+			campaigns[sch.CampaignID] = append(campaigns[sch.CampaignID], sch)
+		}
+		content := ""
+		for _, campaignID := range ids {
+			steps := campaigns[campaignID]
+			content += "- `" + campaignID + "` in " + steps[0].target() + ", " + strconv.Itoa(len(steps)) + " messages left, next <t:" + strconv.FormatInt(steps[0].SendAt.Unix(), 10) + ":R>"
+			if steps[0].Paused {
+				content += " (paused)"
+			}
+			content += "\n"
+		}
+		if content == "" {
+			content = "No campaigns in this server."
+		}
+		respond(s, i, truncate(content, maxMessageLength))
+		return
+	}
+
+	// only the author and the members allowed to manage messages can change
+	// a campaign
+	steps := schedules.campaign(id)
+	if len(steps) == 0 || steps[0].GuildID != i.GuildID {
+		respondError(s, i, "Error managing campaign", errors.New("no campaign with ID "+id))
+		return
+	}
+	if steps[0].AuthorID != user.ID && !hasPermission(i, discordgo.PermissionManageMessages) {
+		respondError(s, i, "Error managing campaign", errors.New("only its author or a moderator can manage it"))
+		return
+	}
+
+	now := time.Now()
+	changed := 0
+	for _, sch := range steps {
+		switch action {
+		case manageCancel:
+			if schedules.remove(sch) {
+				audit(s, AuditCancelled, user.ID, sch)
+				changed++
+			}
+		case managePause, manageResume:
+			if sch.Paused == (action == managePause) {
+				continue
+			}
+			// the steps are replaced by copies, as the dispatcher workers may
+			// be reading them, and delayed by the pause once resumed
+			step := *sch
+			step.Paused = action == managePause
+			if step.Paused {
+				step.PausedAt = now
+			} else {
+				step.SendAt = step.SendAt.Add(now.Sub(sch.PausedAt))
+				step.PausedAt = time.Time{}
+			}
+			if schedules.replace(&step) != nil {
+				watchSchedule(s, &step)
+				changed++
+			}
+		}
+	}
+	logger.Info("Campaign managed", "id", id, "action", action, "steps", changed, "user", user.ID)
+
+	switch action {
+	case manageCancel:
+		respond(s, i, "Campaign cancelled, "+strconv.Itoa(changed)+" messages will not be sent.")
+	default:
+		title := "Campaign resumed"
+		if action == managePause {
+			title = "Campaign paused"
+		}
+		respondEmbed(s, i, campaignEmbed(title, id, schedules.campaign(id)))
+	}
+}
@@ -0,0 +1,61 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"time"
+)
+
+// handleCheck tells when a message with the given time and date would be
+// sent, so that a misread date is caught before scheduling.
+func handleCheck(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	sendTime := ""
+	date := ""
+	for _, option := range options {
+		if option.Name == "time" {
+			sendTime = option.StringValue()
+		} else if option.Name == "date" {
+			date = option.StringValue()
+		}
+	}
+
+	config := store.GuildConfig(i.GuildID)
+	now := time.Now().In(config.location())
+	if date == "" {
+		date = now.Format("02/01/2006")
+	}
+	fixedTime, err := parseSendTime(date, sendTime, config)
+	if err != nil {
+		respondError(s, i, "A message for "+date+" "+sendTime+" could not be scheduled", err)
+		return
+	}
+
+	sendAt := strconv.FormatInt(fixedTime.Unix(), 10)
+	embed := &discordgo.MessageEmbed{
+		Title:       "Time check",
+		Description: "Nothing was scheduled.",
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Read as", Value: fixedTime.Format("Monday 02 January 2006 15:04") + " (" + config.location().String() + ")"},
+			{Name: "Sent", Value: "<t:" + sendAt + ":F> (<t:" + sendAt + ":R>) in your own time zone"},
+		},
+	}
+	if !fixedTime.After(now) {
+		embed.Fields[1].Value = "Right away, this time is already passed"
+	}
+	respondEmbed(s, i, embed)
+}
@@ -0,0 +1,405 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// sendlaterctl manages the schedules of send-later-discord-bot from the
+// terminal, through the HTTP API of the bot.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"send-later-discord-bot/signature"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: sendlaterctl [-url URL] [-token TOKEN] [-json] <command> [options]
+
+Commands:
+  list -guild ID | -author ID   lists the pending messages of a server or a member
+  show ID                       shows a pending message
+  create -channel ID -at TIME [-author ID] [-tts] [-silent] CONTENT
+                                schedules CONTENT, or the standard input if it is -
+  cancel ID...                  cancels pending messages
+  export -guild ID              writes the backup of a server on the standard output
+  restore -guild ID FILE        restores a backup, - reading the standard input
+  verify -secret SECRET TIMESTAMP SIGNATURE
+                                checks the signature of a payload posted by the
+                                bot, read on the standard input
+
+TIME is 2025-01-31T09:00:00Z, 2025-01-31 09:00 in the local time zone, or a
+delay like +2h. The URL and the token of the API default to SENDLATER_API_URL
+and SENDLATER_API_TOKEN, verify doesn't need them.
+
+Options:
+`
+
+// schedule is a schedule as answered by the API.
+type schedule struct {
+	ID               string    `json:"id"`
+	GuildID          string    `json:"guild_id"`
+	ChannelID        string    `json:"channel_id"`
+	AuthorID         string    `json:"author_id"`
+	Content          string    `json:"content"`
+	SendAt           time.Time `json:"send_at"`
+	TTS              bool      `json:"tts"`
+	Silent           bool      `json:"silent"`
+	Files            []string  `json:"files"`
+	AwaitingApproval bool      `json:"awaiting_approval"`
+}
+
+// client calls the API of the bot.
+type client struct {
+	url   string
+	token string
+	http  *http.Client
+}
+
+func main() {
+	flags := flag.NewFlagSet("sendlaterctl", flag.ExitOnError)
+	apiURL := flags.String("url", envOr("SENDLATER_API_URL", "http://localhost:8081"), "the URL of the API of the bot")
+	token := flags.String("token", os.Getenv("SENDLATER_API_TOKEN"), "the token of the API")
+	asJSON := flags.Bool("json", false, "prints the answers of the API as JSON")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if flags.Arg(0) == "verify" {
+		err := verify(flags.Args()[1:], os.Stdin, time.Now())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "sendlaterctl: "+err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "sendlaterctl: no API token, set -token or SENDLATER_API_TOKEN")
+		os.Exit(2)
+	}
+
+	c := &client{url: strings.TrimSuffix(*apiURL, "/"), token: *token, http: &http.Client{Timeout: time.Minute}}
+	command, args := flags.Arg(0), flags.Args()[1:]
+	var err error
+	switch command {
+	case "list":
+		err = c.list(args, *asJSON)
+	case "show":
+		err = c.show(args, *asJSON)
+	case "create":
+		err = c.create(args, *asJSON)
+	case "cancel":
+		err = c.cancel(args)
+	case "export":
+		err = c.export(args)
+	case "restore":
+		err = c.restore(args, *asJSON)
+	default:
+		fmt.Fprintln(os.Stderr, "sendlaterctl: unknown command "+command)
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sendlaterctl: "+err.Error())
+		os.Exit(1)
+	}
+}
+
+func envOr(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// do sends the request to the API and decodes its JSON answer in result,
+// when it is not nil. The errors of the API are returned with their message.
+func (c *client) do(method string, path string, body io.Reader, result any) error {
+	req, err := http.NewRequest(method, c.url+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		apiErr := struct {
+			Error string `json:"error"`
+		}{}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return errors.New(apiErr.Error)
+		}
+		return errors.New("the API answered " + resp.Status)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (c *client) list(args []string, asJSON bool) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	guild := flags.String("guild", "", "the ID of the server")
+	author := flags.String("author", "", "the ID of the member")
+	flags.Parse(args)
+	query := url.Values{}
+	if *guild != "" {
+		query.Set("guild_id", *guild)
+	}
+	if *author != "" {
+		query.Set("author_id", *author)
+	}
+	list := []schedule{}
+	err := c.do(http.MethodGet, "/schedules?"+query.Encode(), nil, &list)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(list)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSEND AT\tCHANNEL\tAUTHOR\tCONTENT")
+	for _, sch := range list {
+		fmt.Fprintln(w, sch.ID+"\t"+sch.SendAt.Local().Format("2006-01-02 15:04")+"\t"+sch.ChannelID+"\t"+sch.AuthorID+"\t"+preview(sch))
+	}
+	return w.Flush()
+}
+
+func (c *client) show(args []string, asJSON bool) error {
+	if len(args) != 1 {
+		return errors.New("show takes the ID of a message")
+	}
+	sch := schedule{}
+	err := c.do(http.MethodGet, "/schedules/"+url.PathEscape(args[0]), nil, &sch)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(sch)
+	}
+	printSchedule(sch)
+	return nil
+}
+
+func (c *client) create(args []string, asJSON bool) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	channel := flags.String("channel", "", "the ID of the channel")
+	at := flags.String("at", "", "when to send the message")
+	author := flags.String("author", "", "the ID of the member scheduling it, the bot by default")
+	tts := flags.Bool("tts", false, "sends the message as text-to-speech")
+	silent := flags.Bool("silent", false, "sends the message without notifications")
+	flags.Parse(args)
+	if *channel == "" || *at == "" || flags.NArg() != 1 {
+		return errors.New("create takes -channel, -at and the content of the message")
+	}
+	sendAt, err := parseTime(*at, time.Now())
+	if err != nil {
+		return err
+	}
+	content := flags.Arg(0)
+	if content == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		content = strings.TrimSpace(string(data))
+	}
+	body, err := json.Marshal(map[string]any{
+		"channel_id": *channel,
+		"author_id":  *author,
+		"content":    content,
+		"send_at":    sendAt,
+		"tts":        *tts,
+		"silent":     *silent,
+	})
+	if err != nil {
+		return err
+	}
+	sch := schedule{}
+	err = c.do(http.MethodPost, "/schedules", bytes.NewReader(body), &sch)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(sch)
+	}
+	fmt.Println("Scheduled " + sch.ID + " for " + sch.SendAt.Local().Format("2006-01-02 15:04"))
+	return nil
+}
+
+func (c *client) cancel(args []string) error {
+	if len(args) == 0 {
+		return errors.New("cancel takes the IDs of the messages")
+	}
+	failed := false
+	for _, id := range args {
+		err := c.do(http.MethodDelete, "/schedules/"+url.PathEscape(id), nil, nil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "sendlaterctl: "+id+": "+err.Error())
+			failed = true
+			continue
+		}
+		fmt.Println("Cancelled " + id)
+	}
+	if failed {
+		return errors.New("some messages were not cancelled")
+	}
+	return nil
+}
+
+func (c *client) export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	guild := flags.String("guild", "", "the ID of the server")
+	flags.Parse(args)
+	if *guild == "" {
+		return errors.New("export takes -guild")
+	}
+	var backup json.RawMessage
+	err := c.do(http.MethodGet, "/guilds/"+url.PathEscape(*guild)+"/backup", nil, &backup)
+	if err != nil {
+		return err
+	}
+	return printJSON(backup)
+}
+
+func (c *client) restore(args []string, asJSON bool) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	guild := flags.String("guild", "", "the ID of the server")
+	flags.Parse(args)
+	if *guild == "" || flags.NArg() != 1 {
+		return errors.New("restore takes -guild and the backup file")
+	}
+	in := os.Stdin
+	if flags.Arg(0) != "-" {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	result := struct {
+		Restored int      `json:"restored"`
+		Skipped  []string `json:"skipped"`
+	}{}
+	err := c.do(http.MethodPost, "/guilds/"+url.PathEscape(*guild)+"/backup", in, &result)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(result)
+	}
+	fmt.Printf("Restored %d messages\n", result.Restored)
+	for _, reason := range result.Skipped {
+		fmt.Println("- " + reason)
+	}
+	return nil
+}
+
+// verify checks the signature of the payload read from in, with the values of
+// its timestamp and signature headers, as a receiver of the bot would.
+func verify(args []string, in io.Reader, now time.Time) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	secret := flags.String("secret", "", "the secret of the URL receiving the payload")
+	flags.Parse(args)
+	if *secret == "" || flags.NArg() != 2 {
+		return errors.New("verify takes -secret, the timestamp and the signature")
+	}
+	body, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	err = signature.Verify(*secret, flags.Arg(0), flags.Arg(1), body, now)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Valid signature")
+	return nil
+}
+
+// parseTime reads an RFC 3339 time, a local "2006-01-02 15:04" time or a
+// delay from now like +2h.
+func parseTime(text string, now time.Time) (time.Time, error) {
+	if delay, found := strings.CutPrefix(text, "+"); found {
+		d, err := time.ParseDuration(delay)
+		if err != nil {
+			return time.Time{}, errors.New("invalid delay " + text + ", use a duration like +30m or +2h")
+		}
+		return now.Add(d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04", text, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("invalid time " + text + ", use 2025-01-31T09:00:00Z, 2025-01-31 09:00 or +2h")
+	}
+	return t, nil
+}
+
+// preview returns the first line of the content, shortened for a table.
+func preview(sch schedule) string {
+	text, _, _ := strings.Cut(sch.Content, "\n")
+	if text == "" && len(sch.Files) > 0 {
+		text = "📎 " + strings.Join(sch.Files, " ")
+	}
+	runes := []rune(text)
+	if len(runes) > 50 {
+		return string(runes[:49]) + "…"
+	}
+	return text
+}
+
+func printSchedule(sch schedule) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID:\t"+sch.ID)
+	fmt.Fprintln(w, "Send at:\t"+sch.SendAt.Local().Format("2006-01-02 15:04 MST"))
+	fmt.Fprintln(w, "Server:\t"+sch.GuildID)
+	fmt.Fprintln(w, "Channel:\t"+sch.ChannelID)
+	fmt.Fprintln(w, "Author:\t"+sch.AuthorID)
+	if len(sch.Files) > 0 {
+		fmt.Fprintln(w, "Files:\t"+strings.Join(sch.Files, ", "))
+	}
+	if sch.AwaitingApproval {
+		fmt.Fprintln(w, "Status:\tawaiting approval")
+	}
+	w.Flush()
+	fmt.Println()
+	fmt.Println(sch.Content)
+}
+
+func printJSON(value any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
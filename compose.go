@@ -0,0 +1,146 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"time"
+)
+
+// composePrefix starts the custom ID of the composer modal, followed by the
+// ID of the target channel and the recurrence rule, separated by a colon.
+const composePrefix = "compose:"
+
+// handleCompose opens a modal where the message can be written on several
+// lines, along with its time and date.
+func handleCompose(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config := store.GuildConfig(i.GuildID)
+
+	channelID := i.ChannelID
+	repeat := ""
+	for _, option := range options {
+		if option.Name == "channel" {
+			channelID = option.ChannelValue(s).ID
+		} else if option.Name == "repeat" {
+			repeat = option.StringValue()
+		}
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: composePrefix + channelID + ":" + repeat,
+			Title:    "Schedule a message",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "message",
+						Label:     "Message",
+						Style:     discordgo.TextInputParagraph,
+						Required:  true,
+						MaxLength: 2000,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "time",
+						Label:       "Time (HH:MM)",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "18:00",
+						Required:    true,
+						MinLength:   5,
+						MaxLength:   5,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "date",
+						Label:       "Date (dd/mm/yyyy), default: today",
+						Style:       discordgo.TextInputShort,
+						Placeholder: time.Now().In(config.location()).Format("02/01/2006"),
+						Required:    false,
+						MaxLength:   10,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error opening composer", "error", err)
+	}
+}
+
+// handleComposeSubmit schedules the message written in the composer modal.
+func handleComposeSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	channelID, repeat, _ := strings.Cut(strings.TrimPrefix(data.CustomID, composePrefix), ":")
+
+	values := modalValues(data)
+
+	channel, err := s.Channel(channelID)
+	if err != nil {
+		logger.Error("Error scheduling message: ", "error", err)
+		respondError(s, i, "Error scheduling message", discordError("cannot get the channel", err))
+		return
+	}
+
+	err = checkChannel(channel, false)
+	if err == nil {
+		err = checkCanPost(s, interactionUser(i).ID, channel, values["message"], nil)
+	}
+	if err != nil {
+		respondError(s, i, "Error scheduling message", err)
+		return
+	}
+
+	config := store.GuildConfig(i.GuildID)
+	date := values["date"]
+	if date == "" {
+		date = time.Now().In(config.location()).Format("02/01/2006")
+	}
+	fixedTime, err := parseSendTime(date, values["time"], config)
+	if err != nil {
+		logger.Error("Error scheduling message: ", "error", err)
+		respondError(s, i, "Error scheduling message", err)
+		return
+	}
+	sch := &Schedule{
+		GuildID:     channel.GuildID,
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		AuthorID:    interactionUser(i).ID,
+		Content:     values["message"],
+		Repeat:      repeat,
+		SendAt:      fixedTime,
+	}
+	startSchedule(s, sch)
+	logger.Info("Message scheduled\n", "message", values["message"], "date", date, "sendTime", values["time"], "channel", channel.Name, "repeat", repeat)
+	respondEmbed(s, i, scheduledEmbed("Message scheduled", sch))
+}
+
+// modalValues returns the trimmed values of the text inputs of a modal, by
+// custom ID.
+func modalValues(data discordgo.ModalSubmitInteractionData) map[string]string {
+	values := map[string]string{}
+	for _, row := range data.Components {
+		for _, component := range row.(*discordgo.ActionsRow).Components {
+			input := component.(*discordgo.TextInput)
+			values[input.CustomID] = strings.TrimSpace(input.Value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,315 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configKeys are the settings that can be set in the configuration file, by
+// their environment variable.
+var configKeys = []string{
+	"DISCORD_TOKEN",
+	"DISCORD_TOKEN_SECONDARY",
+	"DISCORD_TOKEN_FILE",
+	"DISCORD_TOKEN_SECONDARY_FILE",
+	"SENDLATER_VAULT_SECRET",
+	"SENDLATER_STORE",
+	"SENDLATER_ARCHIVE_DIR",
+	"SENDLATER_ATTACHMENT_DIR",
+	"SENDLATER_OWNERS",
+	"SENDLATER_TIMEZONE",
+	"SENDLATER_COMMAND_PERMISSION",
+	"SENDLATER_DM_COMMANDS",
+	"SENDLATER_ALLOWED_GUILDS",
+	"SENDLATER_DENIED_GUILDS",
+	"SENDLATER_PUBLIC_REPLIES",
+	"SENDLATER_MIN_DELAY",
+	"SENDLATER_MAX_HORIZON",
+	"SENDLATER_HEALTH_ADDR",
+	"SENDLATER_PPROF",
+	"SENDLATER_PPROF_ADDR",
+	"SENDLATER_PRESENCE",
+	"SENDLATER_API_ADDR",
+	"SENDLATER_API_TOKENS",
+	"SENDLATER_GRPC_ADDR",
+	"SENDLATER_DASHBOARD_ADDR",
+	"SENDLATER_DASHBOARD_URL",
+	"SENDLATER_OAUTH_CLIENT_ID",
+	"SENDLATER_OAUTH_CLIENT_SECRET",
+	"SENDLATER_EVENT_WEBHOOKS",
+	"SENDLATER_EVENT_WEBHOOK_SECRET",
+	"SENDLATER_EVENT_WEBHOOK_SECRETS",
+	"SENDLATER_EVENT_WEBHOOK_ACTIONS",
+	"SENDLATER_CALENDAR_URL",
+	"SENDLATER_CALENDAR_CHANNEL",
+	"SENDLATER_CALENDAR_LEAD",
+	"SENDLATER_CALENDAR_INTERVAL",
+	"SENDLATER_CALENDAR_TEMPLATE",
+	"SENDLATER_KEEP_COMMANDS",
+	"SENDLATER_COMMAND_GUILDS",
+	"SENDLATER_ENVIRONMENT",
+	"SENDLATER_COMMAND_SUFFIX",
+	"SENDLATER_MAX_PENDING",
+	"SENDLATER_RATE_LIMIT",
+	"SENDLATER_SEND_RATE",
+	"SENDLATER_CHANNEL_SEND_RATE",
+	"SENDLATER_DELIVERY_RETRIES",
+	"SENDLATER_DELIVERY_RETRY_DELAY",
+	"SENDLATER_MAX_ATTACHMENT_SIZE",
+	"SENDLATER_LOG_LEVEL",
+	"SENDLATER_LOG_FORMAT",
+	"SENDLATER_LOG_FILE",
+	"SENDLATER_LOG_MAX_SIZE",
+	"SENDLATER_LOG_MAX_FILES",
+	"SENDLATER_ALERT_CHANNEL",
+	"SENDLATER_ALERT_SMTP",
+	"SENDLATER_ALERT_SMTP_USER",
+	"SENDLATER_ALERT_SMTP_PASSWORD",
+	"SENDLATER_ALERT_EMAIL_FROM",
+	"SENDLATER_ALERT_EMAIL_TO",
+	"SENDLATER_ALERT_WEBHOOK",
+	"SENDLATER_ALERT_WEBHOOK_SECRET",
+	"SENDLATER_ALERT_FAILURES",
+	"SENDLATER_ALERT_FAILURE_WINDOW",
+	"SENDLATER_SENTRY_DSN",
+	"SENDLATER_ERROR_WEBHOOK",
+	"SENDLATER_ERROR_WEBHOOK_SECRET",
+}
+
+// configFlags are the settings that can also be set with a flag, which
+// overrides the environment and the configuration file.
+var configFlags = map[string]string{
+	"token":       "DISCORD_TOKEN",
+	"store":       "SENDLATER_STORE",
+	"timezone":    "SENDLATER_TIMEZONE",
+	"min-delay":   "SENDLATER_MIN_DELAY",
+	"max-horizon": "SENDLATER_MAX_HORIZON",
+	"log-level":   "SENDLATER_LOG_LEVEL",
+	"log-format":  "SENDLATER_LOG_FORMAT",
+	"log-file":    "SENDLATER_LOG_FILE",
+}
+
+// fileKeys are the environment variables set from the configuration file, as
+// opposed to the ones of the environment, so that a reload can replace them.
+var fileKeys []string
+
+// loadConfig applies the configuration file and the flags to the environment,
+// then reads and checks the settings, see applyConfig.
+func loadConfig(args []string) error {
+	err := applyConfig(args)
+	if err != nil {
+		return err
+	}
+	readSettings()
+	err = readTokens()
+	if err != nil {
+		return err
+	}
+	err = checkSettings()
+	if err != nil {
+		return err
+	}
+	return publishSettings()
+}
+
+// applyConfig applies the configuration file and the flags to the
+// environment. The environment overrides the configuration file, and the
+// flags override both.
+func applyConfig(args []string) error {
+	flags := flag.NewFlagSet("send-later-discord-bot", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv("SENDLATER_CONFIG"), "path of the configuration file")
+	values := map[string]*string{}
+	for name, key := range configFlags {
+		values[name] = flags.String(name, "", "overrides "+key)
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *configPath != "" {
+		settings, err := readConfigFile(*configPath)
+		if err != nil {
+			return err
+		}
+		for key, value := range settings {
+			if _, found := os.LookupEnv(key); !found {
+				os.Setenv(key, value)
+				fileKeys = append(fileKeys, key)
+			}
+		}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if key, found := configFlags[f.Name]; found {
+			os.Setenv(key, *values[f.Name])
+		}
+	})
+	return nil
+}
+
+// readConfigFile reads a configuration file in a subset of TOML: one
+// key = value per line, where the value is a quoted string, a number, a
+// boolean or an array of strings. The keys are the environment variables in
+// lower case without the SENDLATER_ prefix, like log_level, and tables
+// prefix the keys of their lines, so log_level can also be level under [log].
+func readConfigFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New("Error opening configuration file: " + err.Error())
+	}
+	defer file.Close()
+
+	settings := map[string]string{}
+	table := ""
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		name, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, errors.New(path + ":" + strconv.Itoa(n) + ": expected key = value")
+		}
+		name = strings.TrimSpace(name)
+		if table != "" {
+			name = table + "_" + name
+		}
+		key := configKey(name)
+		if key == "" {
+			return nil, errors.New(path + ":" + strconv.Itoa(n) + ": unknown setting " + name)
+		}
+		settings[key], err = parseConfigValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.New(path + ":" + strconv.Itoa(n) + ": " + name + ": " + err.Error())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("Error reading configuration file: " + err.Error())
+	}
+	return settings, nil
+}
+
+// configKey returns the environment variable of the setting named name in the
+// configuration file, or an empty string if there is none.
+func configKey(name string) string {
+	name = strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+	if key, found := configAliases[name]; found {
+		return key
+	}
+	for _, key := range configKeys {
+		if key == name || key == "SENDLATER_"+name {
+			return key
+		}
+	}
+	return ""
+}
+
+// configAliases are the names in the configuration file of the settings not
+// prefixed by SENDLATER_.
+var configAliases = map[string]string{
+	"TOKEN":                "DISCORD_TOKEN",
+	"TOKEN_FILE":           "DISCORD_TOKEN_FILE",
+	"SECONDARY_TOKEN":      "DISCORD_TOKEN_SECONDARY",
+	"SECONDARY_TOKEN_FILE": "DISCORD_TOKEN_SECONDARY_FILE",
+}
+
+// parseConfigValue returns the value of a line of the configuration file as
+// it would be written in the environment, arrays being comma separated.
+func parseConfigValue(value string) (string, error) {
+	// a comment can follow an unquoted value
+	if !strings.HasPrefix(value, `"`) && !strings.HasPrefix(value, "[") {
+		value, _, _ = strings.Cut(value, "#")
+		return strings.TrimSpace(value), nil
+	}
+	if strings.HasPrefix(value, "[") {
+		end := strings.LastIndex(value, "]")
+		if end == -1 {
+			return "", errors.New("unterminated array")
+		}
+		items := []string{}
+		for _, item := range strings.Split(value[1:end], ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			unquoted, err := strconv.Unquote(item)
+			if err != nil {
+				return "", errors.New("invalid string " + item)
+			}
+			items = append(items, unquoted)
+		}
+		return strings.Join(items, ","), nil
+	}
+	quoted, err := strconv.QuotedPrefix(value)
+	if err != nil {
+		return "", errors.New("invalid string " + value)
+	}
+	return strconv.Unquote(quoted)
+}
+
+// checkSettings reports the first invalid setting, so that a typo stops the
+// bot at startup rather than when the setting is used.
+func checkSettings() error {
+	if Token == "" && SecondaryToken == "" {
+		return errors.New("no bot token configured, set DISCORD_TOKEN, DISCORD_TOKEN_FILE or token in the configuration file")
+	}
+	for _, key := range []string{"SENDLATER_DM_COMMANDS", "SENDLATER_PUBLIC_REPLIES", "SENDLATER_PPROF", "SENDLATER_PRESENCE", "SENDLATER_KEEP_COMMANDS"} {
+		if value, found := os.LookupEnv(key); found && value != "true" && value != "false" {
+			return errors.New(key + " must be true or false, not " + value)
+		}
+	}
+	for _, key := range []string{"SENDLATER_MAX_PENDING", "SENDLATER_RATE_LIMIT", "SENDLATER_LOG_MAX_SIZE", "SENDLATER_LOG_MAX_FILES", "SENDLATER_ALERT_FAILURES", "SENDLATER_SEND_RATE", "SENDLATER_CHANNEL_SEND_RATE", "SENDLATER_MAX_ATTACHMENT_SIZE"} {
+		if value, found := os.LookupEnv(key); found {
+			if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+				return errors.New(key + " must be a positive number, not " + value)
+			}
+		}
+	}
+	if value, found := os.LookupEnv("SENDLATER_DELIVERY_RETRIES"); found {
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return errors.New("SENDLATER_DELIVERY_RETRIES must be 0 or a positive number, not " + value)
+		}
+	}
+	if delay, err := time.ParseDuration(envOr("SENDLATER_DELIVERY_RETRY_DELAY", "2s")); err != nil || delay <= 0 {
+		return errors.New("SENDLATER_DELIVERY_RETRY_DELAY must be a duration like 2s, not " + os.Getenv("SENDLATER_DELIVERY_RETRY_DELAY"))
+	}
+	if len(EventWebhookSecrets) > 0 && len(EventWebhookSecrets) != len(EventWebhooks) {
+		return errors.New("SENDLATER_EVENT_WEBHOOK_SECRETS must have one secret for each URL of SENDLATER_EVENT_WEBHOOKS")
+	}
+	if CalendarURL != "" {
+		if CalendarChannel == "" {
+			return errors.New("SENDLATER_CALENDAR_CHANNEL must be set to announce the calendar")
+		}
+		if _, err := parseDelay(CalendarLead); err != nil {
+			return errors.New("SENDLATER_CALENDAR_LEAD: " + err.Error())
+		}
+		if _, err := parseDelay(CalendarInterval); err != nil {
+			return errors.New("SENDLATER_CALENDAR_INTERVAL: " + err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scheduledEmbed confirms a schedule with what the bot understood of it, so
+// that the member can check the time it was read as.
+func scheduledEmbed(title string, sch *Schedule) *discordgo.MessageEmbed {
+	sendAt := strconv.FormatInt(sch.SendAt.Unix(), 10)
+	embed := &discordgo.MessageEmbed{
+		Title: title,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "ID", Value: "`" + sch.ID + "`", Inline: true},
+			{Name: "Channel", Value: truncate(sch.target(), 1024), Inline: true},
+			{Name: "Time", Value: "<t:" + sendAt + ":F> (<t:" + sendAt + ":R>)"},
+		},
+	}
+	if sch.Repeat != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Repeats", Value: sch.Repeat, Inline: true})
+	}
+	if preview := sch.preview(); preview != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Preview", Value: truncate(preview, 1024)})
+	}
+	if sch.AwaitingApproval {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: "Waiting for the approval of the moderators"}
+	}
+	return embed
+}
+
+// Custom IDs of the buttons of a preview, followed by the key of its draft.
+const (
+	previewPrefix  = "preview_"
+	previewConfirm = previewPrefix + "confirm:"
+	previewCancel  = previewPrefix + "cancel:"
+)
+
+// draftLifetime is how long a preview can be confirmed, as the interaction
+// can't be answered after 15 minutes anyway.
+const draftLifetime = 15 * time.Minute
+
+// draft is a schedule previewed by its author and not confirmed yet.
+type draft struct {
+	sch     *Schedule
+	expires time.Time
+}
+
+// draftRegistry holds the drafts by key.
+type draftRegistry struct {
+	mu      sync.Mutex
+	pending map[string]draft
+}
+
+var drafts = &draftRegistry{pending: map[string]draft{}}
+
+// add keeps the schedule until its preview is answered and returns its key.
+// The expired drafts are dropped.
+func (r *draftRegistry) add(sch *Schedule, now time.Time) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, d := range r.pending {
+		if now.After(d.expires) {
+			delete(r.pending, key)
+		}
+	}
+	key := newID()
+	for r.pending[key].sch != nil {
+		key = newID()
+	}
+	r.pending[key] = draft{sch: sch, expires: now.Add(draftLifetime)}
+	return key
+}
+
+// take removes the draft and returns its schedule, or nil if it expired.
+func (r *draftRegistry) take(key string, now time.Time) *Schedule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, found := r.pending[key]
+	delete(r.pending, key)
+	if !found || now.After(d.expires) {
+		return nil
+	}
+	return d.sch
+}
+
+// respondPreview shows the member the message of the schedule as it will be
+// sent, without pinging anyone, with buttons to schedule it or not.
+func respondPreview(s *discordgo.Session, i *discordgo.InteractionCreate, sch *Schedule) {
+	key := drafts.add(sch, time.Now())
+	message := messageSend(sch)
+	defer closeFiles(message.Files)
+	embeds := slices.Clone(message.Embeds)
+	if len(embeds) < 10 {
+		embeds = append(embeds, scheduledEmbed("Preview", sch))
+	}
+	sendResponse(s, i, &discordgo.InteractionResponseData{
+		Content:         message.Content,
+		Embeds:          embeds,
+		Files:           message.Files,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+		Flags:           discordgo.MessageFlagsEphemeral,
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Schedule",
+					Style:    discordgo.SuccessButton,
+					CustomID: previewConfirm + key,
+				},
+				discordgo.Button{
+					Label:    "Cancel",
+					Style:    discordgo.SecondaryButton,
+					CustomID: previewCancel + key,
+				},
+			}},
+		},
+	})
+}
+
+// handlePreviewComponent schedules or drops the draft of a preview, and
+// replaces the preview by the outcome.
+func handlePreviewComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	customID := i.MessageComponentData().CustomID
+	now := time.Now()
+	response := &discordgo.InteractionResponseData{
+		Components:  []discordgo.MessageComponent{},
+		Embeds:      []*discordgo.MessageEmbed{},
+		Attachments: &[]*discordgo.MessageAttachment{},
+	}
+
+	if key, found := strings.CutPrefix(customID, previewCancel); found {
+		drafts.take(key, now)
+		response.Content = "Scheduling cancelled."
+	} else {
+		sch := drafts.take(strings.TrimPrefix(customID, previewConfirm), now)
+		// the limits may have been reached since the preview
+		if sch == nil {
+			response.Content = "This preview expired, schedule the message again."
+		} else if store.GuildConfig(sch.GuildID).quotaLeft(sch.GuildID, sch.AuthorID) == 0 {
+			response.Content = "You have too many pending messages in this server, cancel some or wait for them to be sent"
+		} else if err := checkSendBounds(sch.SendAt, now); err != nil {
+			response.Content = "Error scheduling message: " + err.Error()
+		} else {
+			startSchedule(s, sch)
+			logger.Info("Message scheduled from preview", "id", sch.ID, "channel", sch.ChannelName)
+			embed := scheduledEmbed("Message scheduled", sch)
+			if sch.Sender == senderAnonymous {
+				embed.Title = "Message scheduled anonymously"
+				embed.Description = "Only the moderators can see in the audit trail that you scheduled it."
+			}
+			response.Embeds = []*discordgo.MessageEmbed{embed}
+		}
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: response,
+	})
+	if err != nil {
+		logger.Error("Error responding to interaction", "error", err)
+	}
+}
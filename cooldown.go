@@ -0,0 +1,78 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// cooldownWindow is the period of the rate limits.
+const cooldownWindow = time.Minute
+
+// cooldowns keeps the recent uses of the scheduling commands by the members,
+// to stop rapid-fire scheduling.
+type cooldowns struct {
+	mu   sync.Mutex
+	uses map[string][]time.Time
+	// pruned is when the members without recent uses were last dropped
+	pruned time.Time
+}
+
+var scheduleCooldowns = &cooldowns{uses: map[string][]time.Time{}}
+
+// use records a use by the user in the guild if they are under limit uses in
+// the window, and otherwise returns how long they must wait.
+func (c *cooldowns) use(guildID string, userID string, limit int, now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := guildID + ":" + userID
+	recent := []time.Time{}
+	for _, use := range c.uses[key] {
+		if now.Sub(use) < cooldownWindow {
+			recent = append(recent, use)
+		}
+	}
+	if len(recent) >= limit {
+		c.uses[key] = recent
+		return cooldownWindow - now.Sub(recent[0])
+	}
+	c.uses[key] = append(recent, now)
+	if now.Sub(c.pruned) >= cooldownWindow {
+		for key, uses := range c.uses {
+			if len(uses) == 0 || now.Sub(uses[len(uses)-1]) >= cooldownWindow {
+				delete(c.uses, key)
+			}
+		}
+		c.pruned = now
+	}
+	return 0
+}
+
+// rateLimit returns how many scheduling commands a member can use per minute.
+func (c GuildConfig) rateLimit() int {
+	if c.RateLimit == 0 {
+		return settings().rateLimit
+	}
+	return c.RateLimit
+}
+
+// cooldownMessage tells the member how long to wait.
+func cooldownMessage(wait time.Duration) string {
+	seconds := int(wait.Round(time.Second).Seconds())
+	return "You are scheduling too fast, try again in " + strconv.Itoa(max(seconds, 1)) + " seconds"
+}
@@ -0,0 +1,34 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCooldowns(t *testing.T) {
+	c := &cooldowns{uses: map[string][]time.Time{}}
+	now := time.Now()
+	c.use("guild", "early", 1, now)
+	if wait := c.use("guild", "early", 1, now.Add(time.Second)); wait != cooldownWindow-time.Second {
+		t.Errorf("second use over the limit: got a wait of %v", wait)
+	}
+	c.use("guild", "late", 1, now.Add(cooldownWindow))
+	if _, kept := c.uses["guild:early"]; kept || len(c.uses) != 1 {
+		t.Errorf("idle member not dropped: %v", c.uses)
+	}
+}
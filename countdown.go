@@ -0,0 +1,206 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultCountdown is what a countdown announces when no label is given.
+const defaultCountdown = "Announcement"
+
+// countdownRetry is how long to wait before updating a countdown again after
+// an error.
+const countdownRetry = 10 * time.Minute
+
+// countdownMessages holds the IDs of the countdown messages, keyed by the ID
+// of their schedule, so that the edited versions of a schedule keep the same
+// countdown.
+var countdownMessages sync.Map
+
+func handleCountdown(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config := store.GuildConfig(i.GuildID)
+
+	message := ""
+	sendTime := ""
+	date := ""
+	label := defaultCountdown
+	var channel *discordgo.Channel
+	for _, option := range options {
+		if option.Name == "message" {
+			message = option.StringValue()
+		} else if option.Name == "time" {
+			sendTime = option.StringValue()
+		} else if option.Name == "date" {
+			date = option.StringValue()
+		} else if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		} else if option.Name == "label" {
+			label = option.StringValue()
+		}
+	}
+
+	var err error
+	if channel == nil {
+		channel, err = s.Channel(i.ChannelID)
+		if err != nil {
+			logger.Error("Error scheduling countdown: ", "error", err)
+			respondError(s, i, "Error scheduling countdown", discordError("cannot get this channel", err))
+			return
+		}
+	}
+	err = checkChannel(channel, false)
+	if err == nil {
+		err = checkCanPost(s, interactionUser(i).ID, channel, message+label, nil)
+	}
+	if err != nil {
+		respondError(s, i, "Error scheduling countdown", err)
+		return
+	}
+
+	if date == "" {
+		date = time.Now().In(config.location()).Format("02/01/2006")
+	}
+	fixedTime, err := parseSendTime(date, sendTime, config)
+	if err != nil {
+		logger.Error("Error scheduling countdown: ", "error", err)
+		respondError(s, i, "Error scheduling countdown", err)
+		return
+	}
+	sch := &Schedule{
+		GuildID:     channel.GuildID,
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		AuthorID:    interactionUser(i).ID,
+		Content:     message,
+		Thread:      channel.IsThread(),
+		Countdown:   label,
+		SendAt:      fixedTime,
+	}
+	startSchedule(s, sch)
+	// the countdown is posted right away, then updated by the watcher, unless
+	// the moderators have to approve the message first
+	if !sch.AwaitingApproval {
+		updateCountdown(s, sch, time.Now())
+	}
+	logger.Info("Countdown scheduled", "message", message, "label", label, "channel", channel.Name, "date", date, "sendTime", sendTime)
+	respondEmbed(s, i, scheduledEmbed("Countdown scheduled", sch))
+}
+
+// updateCountdown posts the countdown of the schedule, or edits it with the
+// time left, and returns when to update it next. The further the send time,
+// the less often it is updated, to stay well within the rate limits of
+// Discord.
+func updateCountdown(s *discordgo.Session, sch *Schedule, now time.Time) time.Duration {
+	left := sch.SendAt.Sub(now)
+	content := "⏳ **" + sch.Countdown + "** in " + formatCountdown(left)
+	mentions := &discordgo.MessageAllowedMentions{}
+
+	var err error
+	if messageID, found := countdownMessages.Load(sch.ID); found {
+		_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:              messageID.(string),
+			Channel:         sch.ChannelID,
+			Content:         &content,
+			AllowedMentions: mentions,
+		})
+	} else {
+		var message *discordgo.Message
+		message, err = s.ChannelMessageSendComplex(sch.ChannelID, &discordgo.MessageSend{
+			Content:         content,
+			AllowedMentions: mentions,
+		})
+		if err == nil {
+			countdownMessages.Store(sch.ID, message.ID)
+		}
+	}
+	if err != nil {
+		logger.Error("Error updating countdown", "error", err, "id", sch.ID, "channel", sch.ChannelName)
+		watchDiscordError(err)
+		return countdownRetry
+	}
+
+	switch {
+	case left > 24*time.Hour:
+		return time.Hour
+	case left > time.Hour:
+		return 10 * time.Minute
+	default:
+		return time.Minute
+	}
+}
+
+// replaceCountdown edits the countdown of the schedule into its message. The
+// message is sent as usual if there is no countdown, or if it was deleted.
+// The mentions of an edited message don't notify anyone. The files of the
+// message, including a content too long for a message, are uploaded with the
+// edit.
+func replaceCountdown(s *discordgo.Session, sch *Schedule) (*discordgo.Message, error) {
+	message := messageSend(sch)
+	defer func() { closeFiles(message.Files) }()
+	if messageID, found := countdownMessages.LoadAndDelete(sch.ID); found {
+		edited, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:              messageID.(string),
+			Channel:         sch.ChannelID,
+			Content:         &message.Content,
+			Embeds:          &message.Embeds,
+			AllowedMentions: message.AllowedMentions,
+			Files:           message.Files,
+		})
+		if err == nil {
+			return edited, nil
+		}
+		logger.Warn("Cannot replace countdown, sending the message instead", "error", err, "id", sch.ID)
+		// the files were read by the edit
+		closeFiles(message.Files)
+		message = messageSend(sch)
+	}
+	return s.ChannelMessageSendComplex(sch.ChannelID, message)
+}
+
+// removeCountdown deletes the countdown of a cancelled schedule.
+func removeCountdown(s *discordgo.Session, sch *Schedule) {
+	messageID, found := countdownMessages.LoadAndDelete(sch.ID)
+	if !found {
+		return
+	}
+	err := s.ChannelMessageDelete(sch.ChannelID, messageID.(string))
+	if err != nil {
+		logger.Error("Error deleting countdown", "error", err, "id", sch.ID)
+	}
+}
+
+// formatCountdown writes the time left in days, hours and minutes, rounded up
+// to the minute.
+func formatCountdown(left time.Duration) string {
+	minutes := int((left + time.Minute - 1) / time.Minute)
+	if minutes <= 0 {
+		return "less than a minute"
+	}
+	days, hours := minutes/(24*60), minutes/60%24
+	minutes %= 60
+	switch {
+	case days > 0:
+		return strconv.Itoa(days) + "d " + strconv.Itoa(hours) + "h"
+	case hours > 0:
+		return strconv.Itoa(hours) + "h " + strconv.Itoa(minutes) + "m"
+	default:
+		return strconv.Itoa(minutes) + "m"
+	}
+}
@@ -0,0 +1,652 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoints of the OAuth2 login with Discord.
+const (
+	oauthAuthorizeURL = "https://discord.com/oauth2/authorize"
+	oauthTokenURL     = "https://discord.com/api/oauth2/token"
+	oauthAPIURL       = "https://discord.com/api/v10"
+)
+
+// dashboardSessionCookie holds the ID of the session of a member logged in
+// the dashboard, which expire after dashboardSessionLength.
+const (
+	dashboardSessionCookie = "sendlater_session"
+	dashboardStateCookie   = "sendlater_state"
+	dashboardSessionLength = 12 * time.Hour
+)
+
+// dashboardSession is a member logged in the dashboard, with the guilds they
+// are in and their permissions there, as told by Discord at login.
+type dashboardSession struct {
+	UserID   string
+	Username string
+	Guilds   map[string]int64
+	CSRF     string
+	Expires  time.Time
+}
+
+// dashboard is the web UI where the members manage their schedules on a
+// calendar.
+type dashboard struct {
+	s            *discordgo.Session
+	baseURL      string
+	clientID     string
+	clientSecret string
+	client       *http.Client
+
+	mu       sync.Mutex
+	sessions map[string]*dashboardSession
+}
+
+// serveDashboard serves the dashboard on addr in the background, unless addr
+// is empty. baseURL is where the members reach it, Discord redirects them to
+// its /callback after they log in.
+func serveDashboard(s *discordgo.Session, addr string, baseURL string, clientID string, clientSecret string) {
+	if addr == "" {
+		return
+	}
+	if clientSecret == "" || baseURL == "" {
+		logger.Error("The dashboard is not served without SENDLATER_DASHBOARD_URL and SENDLATER_OAUTH_CLIENT_SECRET", "address", addr)
+		return
+	}
+	if clientID == "" {
+		clientID = s.State.User.ID
+	}
+	d := &dashboard{
+		s:            s,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		sessions:     map[string]*dashboardSession{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", d.handleHome)
+	mux.HandleFunc("GET /login", d.handleLogin)
+	mux.HandleFunc("GET /callback", d.handleCallback)
+	mux.HandleFunc("POST /logout", d.handleLogout)
+	mux.HandleFunc("GET /guilds/{guild}", d.handleCalendar)
+	mux.HandleFunc("POST /guilds/{guild}/schedules", d.handleCreate)
+	mux.HandleFunc("GET /schedules/{id}", d.handleShow)
+	mux.HandleFunc("POST /schedules/{id}", d.handleEdit)
+	mux.HandleFunc("POST /schedules/{id}/cancel", d.handleCancel)
+	go func() {
+		logger.Info("Serving dashboard", "address", addr, "url", d.baseURL)
+		err := http.ListenAndServe(addr, mux)
+		logger.Error("Dashboard stopped", "error", err, "address", addr)
+	}()
+}
+
+// randomToken returns a random hex token for the sessions and the OAuth2
+// states.
+func randomToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// setCookie sets a cookie only sent to the dashboard, over HTTPS when it is
+// served over it.
+func (d *dashboard) setCookie(w http.ResponseWriter, name string, value string, maxAge time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(d.baseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// session returns the session of the request, or nil if the member is not
+// logged in.
+func (d *dashboard) session(r *http.Request) *dashboardSession {
+	cookie, err := r.Cookie(dashboardSessionCookie)
+	if err != nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	for id, sess := range d.sessions {
+		if now.After(sess.Expires) {
+			delete(d.sessions, id)
+		}
+	}
+	return d.sessions[cookie.Value]
+}
+
+// requireSession returns the session of the request, or redirects to the
+// home page and returns nil. The forms must carry the CSRF token of the
+// session.
+func (d *dashboard) requireSession(w http.ResponseWriter, r *http.Request) *dashboardSession {
+	sess := d.session(r)
+	if sess == nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return nil
+	}
+	if r.Method == http.MethodPost && subtle.ConstantTimeCompare([]byte(r.FormValue("csrf")), []byte(sess.CSRF)) != 1 {
+		http.Error(w, "Invalid form, reload the page and try again", http.StatusForbidden)
+		return nil
+	}
+	return sess
+}
+
+func (d *dashboard) handleLogin(w http.ResponseWriter, r *http.Request) {
+	state := randomToken()
+	d.setCookie(w, dashboardStateCookie, state, 10*time.Minute)
+	query := url.Values{
+		"client_id":     {d.clientID},
+		"redirect_uri":  {d.baseURL + "/callback"},
+		"response_type": {"code"},
+		"scope":         {"identify guilds"},
+		"state":         {state},
+		"prompt":        {"none"},
+	}
+	http.Redirect(w, r, oauthAuthorizeURL+"?"+query.Encode(), http.StatusFound)
+}
+
+func (d *dashboard) handleCallback(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(dashboardStateCookie)
+	if err != nil || r.URL.Query().Get("state") == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.URL.Query().Get("state"))) != 1 {
+		http.Error(w, "Invalid login, try again", http.StatusBadRequest)
+		return
+	}
+	d.setCookie(w, dashboardStateCookie, "", -time.Second)
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	sess, err := d.login(code)
+	if err != nil {
+		logger.Error("Error logging in the dashboard", "error", err)
+		http.Error(w, "Could not log in with Discord, try again later", http.StatusBadGateway)
+		return
+	}
+	id := randomToken()
+	d.mu.Lock()
+	d.sessions[id] = sess
+	d.mu.Unlock()
+	d.setCookie(w, dashboardSessionCookie, id, dashboardSessionLength)
+	logger.Info("Member logged in the dashboard", "user", sess.UserID, "guilds", len(sess.Guilds))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// login exchanges the OAuth2 code for a token, and reads the member and their
+// guilds with it. The token is not kept.
+func (d *dashboard) login(code string) (*dashboardSession, error) {
+	form := url.Values{
+		"client_id":     {d.clientID},
+		"client_secret": {d.clientSecret},
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {d.baseURL + "/callback"},
+	}
+	resp, err := d.client.PostForm(oauthTokenURL, form)
+	if err != nil {
+		return nil, errors.New("Error getting OAuth2 token: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("Error getting OAuth2 token: " + resp.Status)
+	}
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return nil, errors.New("Error decoding OAuth2 token: " + err.Error())
+	}
+
+	user := discordgo.User{}
+	err = d.getOAuth(token.AccessToken, "/users/@me", &user)
+	if err != nil {
+		return nil, err
+	}
+	guilds := []struct {
+		ID          string `json:"id"`
+		Owner       bool   `json:"owner"`
+		Permissions string `json:"permissions"`
+	}{}
+	err = d.getOAuth(token.AccessToken, "/users/@me/guilds", &guilds)
+	if err != nil {
+		return nil, err
+	}
+	sess := &dashboardSession{
+		UserID:   user.ID,
+		Username: user.Username,
+		Guilds:   map[string]int64{},
+		CSRF:     randomToken(),
+		Expires:  time.Now().Add(dashboardSessionLength),
+	}
+	for _, guild := range guilds {
+		permissions, _ := strconv.ParseInt(guild.Permissions, 10, 64)
+		if guild.Owner {
+			permissions |= discordgo.PermissionAdministrator
+		}
+		sess.Guilds[guild.ID] = permissions
+	}
+	return sess, nil
+}
+
+// getOAuth reads the Discord API at path on behalf of the member.
+func (d *dashboard) getOAuth(accessToken string, path string, result any) error {
+	req, err := http.NewRequest(http.MethodGet, oauthAPIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return errors.New("Error reading " + path + ": " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("Error reading " + path + ": " + resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (d *dashboard) handleLogout(w http.ResponseWriter, r *http.Request) {
+	sess := d.requireSession(w, r)
+	if sess == nil {
+		return
+	}
+	cookie, _ := r.Cookie(dashboardSessionCookie)
+	d.mu.Lock()
+	delete(d.sessions, cookie.Value)
+	d.mu.Unlock()
+	d.setCookie(w, dashboardSessionCookie, "", -time.Second)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// dashboardGuild is a guild the member can open in the dashboard.
+type dashboardGuild struct {
+	ID      string
+	Name    string
+	Manager bool
+}
+
+// guilds returns the guilds of the bot the member is in, by name.
+func (d *dashboard) guilds(sess *dashboardSession) []dashboardGuild {
+	d.s.State.RLock()
+	defer d.s.State.RUnlock()
+	list := []dashboardGuild{}
+	for _, guild := range d.s.State.Guilds {
+		if _, member := sess.Guilds[guild.ID]; member && guildAllowed(guild.ID) {
+			list = append(list, dashboardGuild{ID: guild.ID, Name: guild.Name, Manager: sess.manager(guild.ID)})
+		}
+	}
+	slices.SortFunc(list, func(a, b dashboardGuild) int { return strings.Compare(a.Name, b.Name) })
+	return list
+}
+
+// manager reports whether the member manages the guild, and so sees and
+// cancels the schedules of everyone there.
+func (sess *dashboardSession) manager(guildID string) bool {
+	return sess.Guilds[guildID]&(discordgo.PermissionManageServer|discordgo.PermissionAdministrator) != 0
+}
+
+// canManage reports whether the member can see and change the schedule.
+func (sess *dashboardSession) canManage(sch *Schedule) bool {
+	if _, member := sess.Guilds[sch.GuildID]; !member {
+		return false
+	}
+	return sch.AuthorID == sess.UserID || sess.manager(sch.GuildID)
+}
+
+func (d *dashboard) handleHome(w http.ResponseWriter, r *http.Request) {
+	sess := d.session(r)
+	if sess == nil {
+		d.render(w, "login", map[string]any{})
+		return
+	}
+	d.render(w, "home", map[string]any{"Session": sess, "Guilds": d.guilds(sess)})
+}
+
+// calendarDay is a day of the calendar with the schedules sent that day.
+type calendarDay struct {
+	Date      time.Time
+	InMonth   bool
+	Schedules []*Schedule
+}
+
+func (d *dashboard) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	sess := d.requireSession(w, r)
+	if sess == nil {
+		return
+	}
+	guild, err := d.s.State.Guild(r.PathValue("guild"))
+	if _, member := sess.Guilds[r.PathValue("guild")]; err != nil || !member || !guildAllowed(guild.ID) {
+		http.NotFound(w, r)
+		return
+	}
+	location := store.GuildConfig(guild.ID).location()
+	now := time.Now().In(location)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, location)
+	if t, err := time.ParseInLocation("2006-01", r.URL.Query().Get("month"), location); err == nil {
+		month = t
+	}
+
+	// the weeks start on monday, from the one of the first day of the month
+	start := month.AddDate(0, 0, -(int(month.Weekday())+6)%7)
+	weeks := [][]*calendarDay{}
+	days := map[string]*calendarDay{}
+	for day := start; day.Before(month.AddDate(0, 1, 0)); {
+		week := []*calendarDay{}
+		for n := 0; n < 7; n++ {
+			cd := &calendarDay{Date: day, InMonth: day.Month() == month.Month()}
+			week = append(week, cd)
+			days[day.Format("2006-01-02")] = cd
+			day = day.AddDate(0, 0, 1)
+		}
+		weeks = append(weeks, week)
+	}
+	for _, sch := range schedules.shared(guild.ID, sess.UserID) {
+		if !sess.canManage(sch) {
+			continue
+		}
+		if cd := days[sch.SendAt.In(location).Format("2006-01-02")]; cd != nil {
+			cd.Schedules = append(cd.Schedules, sch)
+		}
+	}
+
+	d.render(w, "calendar", map[string]any{
+		"Session":  sess,
+		"Guild":    guild,
+		"Manager":  sess.manager(guild.ID),
+		"Month":    month,
+		"Previous": month.AddDate(0, -1, 0).Format("2006-01"),
+		"Next":     month.AddDate(0, 1, 0).Format("2006-01"),
+		"Weeks":    weeks,
+		"Location": location,
+		"Channels": d.channels(guild.ID),
+		"Error":    r.URL.Query().Get("error"),
+	})
+}
+
+// channels returns the channels of the guild messages can be scheduled in
+// from the dashboard.
+func (d *dashboard) channels(guildID string) []*discordgo.Channel {
+	list := []*discordgo.Channel{}
+	guild, err := d.s.State.Guild(guildID)
+	if err != nil {
+		return list
+	}
+	d.s.State.RLock()
+	defer d.s.State.RUnlock()
+	for _, channel := range guild.Channels {
+		if checkChannel(channel, false) == nil && !channel.IsThread() {
+			list = append(list, channel)
+		}
+	}
+	slices.SortFunc(list, func(a, b *discordgo.Channel) int { return a.Position - b.Position })
+	return list
+}
+
+// parseDashboardTime reads the value of a datetime-local input in the time
+// zone of the guild.
+func parseDashboardTime(value string, location *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02T15:04", value, location)
+	if err != nil {
+		return time.Time{}, errors.New("invalid send time " + value)
+	}
+	return t, nil
+}
+
+func (d *dashboard) handleCreate(w http.ResponseWriter, r *http.Request) {
+	sess := d.requireSession(w, r)
+	if sess == nil {
+		return
+	}
+	guildID := r.PathValue("guild")
+	back := "/guilds/" + url.PathEscape(guildID)
+	if _, member := sess.Guilds[guildID]; !member {
+		http.NotFound(w, r)
+		return
+	}
+	config := store.GuildConfig(guildID)
+	member, err := d.s.GuildMember(guildID, sess.UserID)
+	if err != nil || !config.canSchedule(member) {
+		redirectError(w, r, back, errors.New("you are not allowed to schedule messages in this server"))
+		return
+	}
+	sendAt, err := parseDashboardTime(r.FormValue("send_at"), config.location())
+	if err == nil && !sendAt.After(time.Now()) {
+		err = errors.New("the send time is in the past")
+	}
+	if err != nil {
+		redirectError(w, r, back, err)
+		return
+	}
+	channelID := r.FormValue("channel")
+	if channel, err := d.s.State.Channel(channelID); err != nil || channel.GuildID != guildID {
+		redirectError(w, r, back, errors.New("unknown channel"))
+		return
+	}
+	// the checks of the API apply, with the member as author
+	sch, _, err := createAPISchedule(d.s, apiCreateRequest{
+		ChannelID: channelID,
+		AuthorID:  sess.UserID,
+		Content:   strings.TrimSpace(r.FormValue("content")),
+		SendAt:    sendAt,
+	})
+	if err != nil {
+		redirectError(w, r, back, err)
+		return
+	}
+	logger.Info("Message scheduled from the dashboard", "id", sch.ID, "user", sess.UserID)
+	http.Redirect(w, r, back+"?month="+sendAt.In(config.location()).Format("2006-01"), http.StatusSeeOther)
+}
+
+// redirectError goes back to the page at path, showing the error.
+func redirectError(w http.ResponseWriter, r *http.Request, path string, err error) {
+	http.Redirect(w, r, path+"?error="+url.QueryEscape(err.Error()), http.StatusSeeOther)
+}
+
+// managedSchedule returns the pending schedule of the request, if the member
+// can change it.
+func (d *dashboard) managedSchedule(w http.ResponseWriter, r *http.Request, sess *dashboardSession) *Schedule {
+	sch := schedules.get(r.PathValue("id"))
+	if sch == nil || !sess.canManage(sch) {
+		http.NotFound(w, r)
+		return nil
+	}
+	return sch
+}
+
+func (d *dashboard) handleShow(w http.ResponseWriter, r *http.Request) {
+	sess := d.requireSession(w, r)
+	if sess == nil {
+		return
+	}
+	sch := d.managedSchedule(w, r, sess)
+	if sch == nil {
+		return
+	}
+	location := store.GuildConfig(sch.GuildID).location()
+	d.render(w, "schedule", map[string]any{
+		"Session":  sess,
+		"Schedule": sch,
+		"SendAt":   sch.SendAt.In(location).Format("2006-01-02T15:04"),
+		"Location": location,
+		"Error":    r.URL.Query().Get("error"),
+	})
+}
+
+func (d *dashboard) handleEdit(w http.ResponseWriter, r *http.Request) {
+	sess := d.requireSession(w, r)
+	if sess == nil {
+		return
+	}
+	sch := d.managedSchedule(w, r, sess)
+	if sch == nil {
+		return
+	}
+	back := "/schedules/" + url.PathEscape(sch.ID)
+	config := store.GuildConfig(sch.GuildID)
+	content := strings.TrimSpace(r.FormValue("content"))
+	sendAt, err := parseDashboardTime(r.FormValue("send_at"), config.location())
+	switch {
+	case err != nil:
+	case content == "" && sch.Action == "" && sch.Poll == nil && len(sch.Embeds) == 0 && len(sch.Files) == 0:
+		err = errors.New("the message is empty")
+	case len([]rune(content)) > maxMessageLength:
+		err = errors.New("the content is longer than 2000 characters")
+	case !sendAt.After(time.Now()):
+		err = errors.New("the send time is in the past")
+	case config.inQuietHours(sendAt):
+		err = errors.New("the time is during the quiet hours of the server")
+	default:
+		err = checkSendBounds(sendAt, time.Now())
+	}
+	if err == nil && content != sch.Content {
+		err = checkEditContent(d.s, sch, sess.UserID, content)
+	}
+	if err != nil {
+		redirectError(w, r, back, err)
+		return
+	}
+
+	// the edit goes through the same checks and review as /sendlater update
+	permissions, err := d.s.UserChannelPermissions(sess.UserID, sch.ChannelID)
+	moderator := err == nil && permissions&(discordgo.PermissionManageMessages|discordgo.PermissionAdministrator) != 0
+	edited := *sch
+	edited.Content = content
+	edited.SendAt = sendAt
+	err = commitEdit(d.s, &edited, sess.UserID, moderator)
+	if err != nil {
+		redirectError(w, r, back, err)
+		return
+	}
+	logger.Info("Message edited from the dashboard", "id", sch.ID, "user", sess.UserID)
+	http.Redirect(w, r, "/guilds/"+url.PathEscape(sch.GuildID)+"?month="+sendAt.In(config.location()).Format("2006-01"), http.StatusSeeOther)
+}
+
+func (d *dashboard) handleCancel(w http.ResponseWriter, r *http.Request) {
+	sess := d.requireSession(w, r)
+	if sess == nil {
+		return
+	}
+	sch := d.managedSchedule(w, r, sess)
+	if sch == nil {
+		return
+	}
+	if schedules.remove(sch) {
+		audit(d.s, AuditCancelled, sess.UserID, sch)
+		logger.Info("Message cancelled from the dashboard", "id", sch.ID, "user", sess.UserID)
+	}
+	http.Redirect(w, r, "/guilds/"+url.PathEscape(sch.GuildID), http.StatusSeeOther)
+}
+
+func (d *dashboard) render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := dashboardTemplates.ExecuteTemplate(w, name, data)
+	if err != nil {
+		logger.Error("Error rendering dashboard", "error", err, "page", name)
+	}
+}
+
+var dashboardTemplates = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"preview": func(sch *Schedule) string { return truncate(sch.preview(), 60) },
+	"clock": func(t time.Time, location *time.Location) string {
+		return t.In(location).Format("15:04")
+	},
+}).Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html lang="en"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Send Later</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 1100px; padding: 0 1em; color: #222 }
+header { display: flex; justify-content: space-between; align-items: center }
+table { border-collapse: collapse; width: 100%; table-layout: fixed }
+td { border: 1px solid #ccc; vertical-align: top; height: 6em; padding: .3em; font-size: .85em }
+td.out { background: #f4f4f4; color: #999 }
+td a { display: block; overflow: hidden; white-space: nowrap; text-overflow: ellipsis }
+.error { background: #fdd; padding: .5em }
+textarea { width: 100%; height: 8em }
+form.inline { display: inline }
+</style></head><body>
+<header><h1><a href="/">Send Later</a></h1>{{with .Session}}<form class="inline" method="post" action="/logout"><input type="hidden" name="csrf" value="{{.CSRF}}">{{.Username}} <button>Log out</button></form>{{end}}</header>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+{{end}}
+
+{{define "footer"}}</body></html>{{end}}
+
+{{define "login"}}{{template "header" .}}
+<p>Log in to see, schedule, edit and cancel your messages on a calendar.</p>
+<p><a href="/login">Log in with Discord</a></p>
+{{template "footer"}}{{end}}
+
+{{define "home"}}{{template "header" .}}
+<h2>Servers</h2>
+<ul>{{range .Guilds}}<li><a href="/guilds/{{.ID}}">{{.Name}}</a>{{if .Manager}} (manager){{end}}</li>{{else}}<li>The bot is in none of your servers.</li>{{end}}</ul>
+{{template "footer"}}{{end}}
+
+{{define "calendar"}}{{template "header" .}}
+<h2>{{.Guild.Name}}: {{.Month.Format "January 2006"}}</h2>
+<p><a href="?month={{.Previous}}">← Previous</a> · <a href="?month={{.Next}}">Next →</a> · Times in {{.Location}}{{if .Manager}}, the messages of every member are shown{{end}}</p>
+<table>
+<tr><th>Mon</th><th>Tue</th><th>Wed</th><th>Thu</th><th>Fri</th><th>Sat</th><th>Sun</th></tr>
+{{$location := .Location}}{{range .Weeks}}<tr>{{range .}}<td{{if not .InMonth}} class="out"{{end}}><b>{{.Date.Day}}</b>
+{{range .Schedules}}<a href="/schedules/{{.ID}}" title="{{.ChannelName}}">{{clock .SendAt $location}} {{preview .}}</a>{{end}}</td>{{end}}</tr>
+{{end}}</table>
+<h2>Schedule a message</h2>
+<form method="post" action="/guilds/{{.Guild.ID}}/schedules">
+<input type="hidden" name="csrf" value="{{.Session.CSRF}}">
+<p><label>Channel <select name="channel">{{range .Channels}}<option value="{{.ID}}">#{{.Name}}</option>{{end}}</select></label>
+<label>Send at <input type="datetime-local" name="send_at" required></label></p>
+<p><textarea name="content" maxlength="2000" required placeholder="Message"></textarea></p>
+<p><button>Schedule</button></p>
+</form>
+{{template "footer"}}{{end}}
+
+{{define "schedule"}}{{template "header" .}}
+{{with .Schedule}}<h2>Message {{.ID}} in #{{.ChannelName}}</h2>
+<p>By <code>{{.AuthorID}}</code>{{if .AwaitingApproval}}, awaiting approval{{end}}{{if .Files}}, with {{len .Files}} files{{end}}{{if .Embeds}}, with {{len .Embeds}} embeds{{end}}</p>{{end}}
+<form method="post" action="/schedules/{{.Schedule.ID}}">
+<input type="hidden" name="csrf" value="{{.Session.CSRF}}">
+<p><label>Send at <input type="datetime-local" name="send_at" value="{{.SendAt}}" required></label> ({{.Location}})</p>
+<p><textarea name="content" maxlength="2000">{{.Schedule.Content}}</textarea></p>
+<p><button>Save</button> <a href="/guilds/{{.Schedule.GuildID}}">Back</a></p>
+</form>
+<form method="post" action="/schedules/{{.Schedule.ID}}/cancel">
+<input type="hidden" name="csrf" value="{{.Session.CSRF}}">
+<button>Cancel the message</button>
+</form>
+{{template "footer"}}{{end}}
+`))
@@ -0,0 +1,192 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// maxDeadLetters is how many failed deliveries are kept, the oldest ones are
+// dropped first.
+const maxDeadLetters = 1000
+
+// DeadLetter is a schedule that could not be delivered, kept until the admins
+// of its guild requeue or discard it.
+type DeadLetter struct {
+	Schedule *Schedule `json:"schedule"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// AddDeadLetter keeps the schedule that failed with err.
+func (st *Store) AddDeadLetter(sch *Schedule, err error, now time.Time) error {
+	return st.update(func(data *storeData) {
+		data.DeadLetters = append(data.DeadLetters, DeadLetter{Schedule: sch, Error: err.Error(), FailedAt: now})
+		if len(data.DeadLetters) > maxDeadLetters {
+			data.DeadLetters = slices.Delete(data.DeadLetters, 0, len(data.DeadLetters)-maxDeadLetters)
+		}
+	})
+}
+
+// DeadLetters returns the failed deliveries of the guild, oldest first.
+func (st *Store) DeadLetters(guildID string) []DeadLetter {
+	list := []DeadLetter{}
+	st.view(func(data *storeData) {
+		for _, letter := range data.DeadLetters {
+			if letter.Schedule.GuildID == guildID {
+				list = append(list, letter)
+			}
+		}
+	})
+	return list
+}
+
+// TakeDeadLetter removes the failed delivery of the schedule with the given
+// ID from the guild and returns it, or nil if there is none.
+func (st *Store) TakeDeadLetter(guildID string, id string) (*DeadLetter, error) {
+	var taken *DeadLetter
+	err := st.update(func(data *storeData) {
+		n := slices.IndexFunc(data.DeadLetters, func(letter DeadLetter) bool {
+			return letter.Schedule.GuildID == guildID && letter.Schedule.ID == id
+		})
+		if n < 0 {
+			return
+		}
+		letter := data.DeadLetters[n]
+		taken = &letter
+		data.DeadLetters = slices.Delete(data.DeadLetters, n, n+1)
+	})
+	return taken, err
+}
+
+// deadLetter keeps the schedule that could not be delivered, so that it is
+// not lost with only a log line.
+func deadLetter(s *discordgo.Session, sch *Schedule, err error) {
+	// only the target channels that didn't get the message are sent again
+	var partial *deliveryError
+	if errors.As(err, &partial) {
+		if len(partial.Failed) == 0 {
+			return
+		}
+		sch = sch.retarget(s, partial.Failed)
+	}
+	storeErr := store.AddDeadLetter(sch, err, time.Now())
+	if storeErr != nil {
+		logger.Error("Error keeping failed message", "error", storeErr, "id", sch.ID)
+	}
+}
+
+// failedCommand is the name of the command managing the failed deliveries of
+// a server.
+const failedCommand = "failed"
+
+// Ways of managing the failed deliveries.
+const (
+	failedList    = "list"
+	failedRequeue = "requeue"
+	failedDiscard = "discard"
+)
+
+// failedCommandDefinition returns the /failed command.
+func failedCommandDefinition() *discordgo.ApplicationCommand {
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, action := range []string{failedList, failedRequeue, failedDiscard} {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: action, Value: action})
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        failedCommand,
+		Description: "[Admins] Lists, sends again or discards the messages that could not be delivered",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "action",
+				Description: "What to do",
+				Required:    true,
+				Choices:     choices,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "id",
+				Description: "[Optionnal] The ID of the message, except for the list",
+				Required:    false,
+			},
+		},
+	}
+}
+
+// handleFailed lists, requeues or discards the failed deliveries of the
+// guild.
+func handleFailed(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if i.GuildID == "" {
+		respondEphemeral(s, i, "The failed messages are only available in a server")
+		return
+	}
+	if !hasPermission(i, discordgo.PermissionManageServer) {
+		respondEphemeral(s, i, "Only the server admins can manage the failed messages")
+		return
+	}
+	user := interactionUser(i)
+	action := failedList
+	id := ""
+	for _, option := range options {
+		if option.Name == "action" {
+			action = option.StringValue()
+		} else if option.Name == "id" {
+			id = option.StringValue()
+		}
+	}
+
+	if action == failedList {
+		content := ""
+		for _, letter := range store.DeadLetters(i.GuildID) {
+			sch := letter.Schedule
+			content += "- `" + sch.ID + "` for " + sch.target() + ", failed <t:" + strconv.FormatInt(letter.FailedAt.Unix(), 10) + ":R>: " + letter.Error + "\n  " + truncate(sch.preview(), 100) + "\n"
+		}
+		if content == "" {
+			content = "No messages failed to be delivered in this server."
+		}
+		respondEphemeral(s, i, truncate(content, maxMessageLength))
+		return
+	}
+
+	letter, err := store.TakeDeadLetter(i.GuildID, id)
+	if err == nil && letter == nil {
+		err = errors.New("no failed message with ID " + id)
+	}
+	if err != nil {
+		respondError(s, i, "Error managing failed message", err)
+		return
+	}
+	switch action {
+	case failedDiscard:
+		respondEphemeral(s, i, "Failed message discarded.")
+	case failedRequeue:
+		// the message is sent on the next check, the recurring ones already
+		// have their next occurrence scheduled
+		sch := letter.Schedule
+		sch.SendAt = time.Now()
+		sch.Repeat = ""
+		schedules.readd(sch)
+		audit(s, AuditScheduled, user.ID, sch)
+		watchSchedule(s, sch)
+		respondEphemeral(s, i, "The message `"+sch.ID+"` will be sent again within a minute.")
+	}
+	logger.Info("Failed message managed", "id", id, "action", action, "user", user.ID)
+}
@@ -0,0 +1,110 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"slices"
+	"time"
+)
+
+// Deletion is a sent message waiting to be deleted, kept in the store so
+// that a restart doesn't leave it in the channel.
+type Deletion struct {
+	ScheduleID string `json:"schedule_id"`
+	ChannelID  string `json:"channel_id"`
+	MessageID  string `json:"message_id"`
+	// Post is set for the first message of a forum post, deleted with the
+	// whole post.
+	Post     bool      `json:"post,omitempty"`
+	DeleteAt time.Time `json:"delete_at"`
+}
+
+// AddDeletions records the messages to delete. They are written with the end
+// of the delivery that sent them.
+func (st *Store) AddDeletions(deletions []Deletion) {
+	st.updateLater(func(data *storeData) {
+		data.Deletions = append(data.Deletions, deletions...)
+	})
+}
+
+// EndDeletion records that the message was deleted, or failed to be.
+func (st *Store) EndDeletion(deletion Deletion) {
+	st.updateLater(func(data *storeData) {
+		data.Deletions = slices.DeleteFunc(data.Deletions, func(kept Deletion) bool {
+			return kept.ChannelID == deletion.ChannelID && kept.MessageID == deletion.MessageID
+		})
+	})
+}
+
+// Deletions returns the messages waiting to be deleted.
+func (st *Store) Deletions() []Deletion {
+	list := []Deletion{}
+	st.view(func(data *storeData) {
+		list = append(list, data.Deletions...)
+	})
+	return list
+}
+
+// deleteLater deletes the sent messages once the DeleteAfter delay of the
+// schedule is passed. A forum post is deleted along with its messages.
+func deleteLater(s *discordgo.Session, sch *Schedule, sent []*discordgo.Message) {
+	deleteAt := time.Now().Add(sch.DeleteAfter)
+	deletions := []Deletion{}
+	for n, message := range sent {
+		deletions = append(deletions, Deletion{
+			ScheduleID: sch.ID,
+			ChannelID:  message.ChannelID,
+			MessageID:  message.ID,
+			Post:       n == 0 && sch.ForumTitle != "" && !sch.DM,
+			DeleteAt:   deleteAt,
+		})
+	}
+	store.AddDeletions(deletions)
+	for _, deletion := range deletions {
+		watchDeletion(s, deletion)
+	}
+}
+
+// watchDeletion deletes the message at its time, at once if it is passed.
+func watchDeletion(s *discordgo.Session, deletion Deletion) {
+	time.AfterFunc(time.Until(deletion.DeleteAt), func() {
+		var err error
+		if deletion.Post {
+			_, err = s.ChannelDelete(deletion.ChannelID)
+		} else {
+			err = s.ChannelMessageDelete(deletion.ChannelID, deletion.MessageID)
+		}
+		if err != nil {
+			logger.Error("Error deleting sent message", "error", err, "id", deletion.ScheduleID, "message", deletion.MessageID)
+		} else {
+			logger.Info("Sent message deleted", "id", deletion.ScheduleID, "message", deletion.MessageID)
+		}
+		store.EndDeletion(deletion)
+	})
+}
+
+// restoreDeletions watches again the messages that were waiting to be
+// deleted when the bot stopped.
+func restoreDeletions(s *discordgo.Session) {
+	deletions := store.Deletions()
+	for _, deletion := range deletions {
+		watchDeletion(s, deletion)
+	}
+	if len(deletions) > 0 {
+		logger.Info("Deletions of sent messages restored", "count", len(deletions))
+	}
+}
@@ -0,0 +1,270 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"container/heap"
+	"github.com/bwmarrin/discordgo"
+	"sync"
+	"time"
+)
+
+// dispatchWorkers is how many schedules are handled at the same time once
+// due, the others wait in line.
+const dispatchWorkers = 32
+
+// prunePeriod is how often the cancelled and replaced schedules are dropped
+// from the dispatcher before their time.
+const prunePeriod = 10 * time.Minute
+
+// scheduleWatch is the state of a pending schedule in the dispatcher.
+type scheduleWatch struct {
+	sch           *Schedule
+	wake          time.Time
+	notified      bool
+	nextCountdown time.Time
+	index         int
+}
+
+// watchQueue orders the watched schedules by the time they must be looked at
+// next.
+type watchQueue []*scheduleWatch
+
+func (q watchQueue) Len() int           { return len(q) }
+func (q watchQueue) Less(a, b int) bool { return q[a].wake.Before(q[b].wake) }
+func (q watchQueue) Swap(a, b int) {
+	q[a], q[b] = q[b], q[a]
+	q[a].index = a
+	q[b].index = b
+}
+
+func (q *watchQueue) Push(x any) {
+	w := x.(*scheduleWatch)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *watchQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*q = old[:len(old)-1]
+	return w
+}
+
+// scheduleDispatcher wakes the pending schedules when they are due, from a
+// single timer, so that a pending schedule costs its content and not a
+// goroutine.
+type scheduleDispatcher struct {
+	mu      sync.Mutex
+	queue   watchQueue
+	byID    map[string]*scheduleWatch
+	wakeup  chan struct{}
+	due     chan *scheduleWatch
+	session *discordgo.Session
+	start   sync.Once
+}
+
+var dispatcher = &scheduleDispatcher{
+	byID:   map[string]*scheduleWatch{},
+	wakeup: make(chan struct{}, 1),
+	due:    make(chan *scheduleWatch),
+}
+
+// watch starts watching the schedule, in place of a previous version with
+// the same ID.
+func (d *scheduleDispatcher) watch(s *discordgo.Session, sch *Schedule) {
+	d.start.Do(func() {
+		d.session = s
+		for i := 0; i < dispatchWorkers; i++ {
+			go d.work()
+		}
+		go d.run()
+	})
+	// like the schedules themselves, the countdown is first updated a
+	// minute after it is posted
+	w := &scheduleWatch{sch: sch, nextCountdown: time.Now().Add(time.Minute)}
+	w.wake = w.nextWake(time.Now())
+	d.mu.Lock()
+	if previous := d.byID[sch.ID]; previous != nil {
+		heap.Remove(&d.queue, previous.index)
+	}
+	d.byID[sch.ID] = w
+	heap.Push(&d.queue, w)
+	d.mu.Unlock()
+	d.poke()
+}
+
+// requeue puts back a schedule after it was looked at, unless it was watched
+// again in the meantime.
+func (d *scheduleDispatcher) requeue(w *scheduleWatch) {
+	d.mu.Lock()
+	if _, exists := d.byID[w.sch.ID]; exists {
+		d.mu.Unlock()
+		return
+	}
+	d.byID[w.sch.ID] = w
+	heap.Push(&d.queue, w)
+	d.mu.Unlock()
+	d.poke()
+}
+
+// poke makes the dispatcher look at its next schedule again.
+func (d *scheduleDispatcher) poke() {
+	select {
+	case d.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// run hands the due schedules to the workers, and sleeps until the next one.
+func (d *scheduleDispatcher) run() {
+	defer recoverPanics(nil)
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	prune := time.NewTicker(prunePeriod)
+	defer prune.Stop()
+	for {
+		now := time.Now()
+		d.mu.Lock()
+		due := []*scheduleWatch{}
+		for len(d.queue) > 0 && !d.queue[0].wake.After(now) {
+			w := heap.Pop(&d.queue).(*scheduleWatch)
+			delete(d.byID, w.sch.ID)
+			due = append(due, w)
+		}
+		wait := time.Hour
+		if len(d.queue) > 0 {
+			wait = d.queue[0].wake.Sub(now)
+		}
+		d.mu.Unlock()
+		for _, w := range due {
+			d.due <- w
+		}
+		if len(due) > 0 {
+			// new schedules may be due after waiting for the workers
+			continue
+		}
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-d.wakeup:
+		case <-prune.C:
+			d.prune()
+		}
+	}
+}
+
+// work looks at the due schedules, and puts back those still pending.
+func (d *scheduleDispatcher) work() {
+	for w := range d.due {
+		if w.check(d.session, time.Now()) {
+			w.wake = w.nextWake(time.Now())
+			d.requeue(w)
+		}
+	}
+}
+
+// prune drops the schedules cancelled or edited since they were watched,
+// which would otherwise be kept until their time.
+func (d *scheduleDispatcher) prune() {
+	cancelled := []*Schedule{}
+	d.mu.Lock()
+	kept := d.queue[:0]
+	for _, w := range d.queue {
+		current := schedules.get(w.sch.ID)
+		if current == w.sch {
+			w.index = len(kept)
+			kept = append(kept, w)
+			continue
+		}
+		delete(d.byID, w.sch.ID)
+		if current == nil && w.sch.Countdown != "" && !w.sch.AwaitingApproval {
+			cancelled = append(cancelled, w.sch)
+		}
+	}
+	clear(d.queue[len(kept):])
+	d.queue = kept
+	heap.Init(&d.queue)
+	d.mu.Unlock()
+	for _, sch := range cancelled {
+		removeCountdown(d.session, sch)
+	}
+}
+
+// nextWake returns when the schedule must be looked at next: its countdown
+// update, its heads-up or its time.
+func (w *scheduleWatch) nextWake(now time.Time) time.Time {
+	sch := w.sch
+	// the steps of a paused campaign are checked every minute until resumed
+	if sch.Paused && sch.CampaignID != "" {
+		return now.Add(time.Minute)
+	}
+	wake := sch.SendAt
+	if sch.Countdown != "" && !sch.AwaitingApproval && w.nextCountdown.Before(wake) {
+		wake = w.nextCountdown
+	}
+	if !w.notified && !sch.Paused && sch.NotifyBefore > 0 {
+		if headsUp := sch.SendAt.Add(-sch.NotifyBefore); headsUp.Before(wake) {
+			wake = headsUp
+		}
+	}
+	return wake
+}
+
+// check updates the countdown of the schedule, tells its author it is
+// coming, or sends it, and reports whether it must still be watched.
+func (w *scheduleWatch) check(s *discordgo.Session, now time.Time) bool {
+	sch := w.sch
+	defer recoverPanics(map[string]string{"schedule.id": sch.ID, "guild.id": sch.GuildID, "channel.id": sch.ChannelID})
+	// the schedule was cancelled or edited in the meantime, the countdown is
+	// removed in the first case and updated by the watcher of the
+	// replacement otherwise
+	if current := schedules.get(sch.ID); current != sch {
+		if current == nil && sch.Countdown != "" && !sch.AwaitingApproval && sch.SendAt.After(now) {
+			removeCountdown(s, sch)
+		}
+		return false
+	}
+	if sch.Countdown != "" && !sch.AwaitingApproval && sch.SendAt.After(now) && !now.Before(w.nextCountdown) {
+		w.nextCountdown = now.Add(updateCountdown(s, sch, now))
+	}
+	// the author is told once when the heads-up delay is reached
+	if !w.notified && !sch.Paused && sch.NotifyBefore > 0 && !now.Before(sch.SendAt.Add(-sch.NotifyBefore)) {
+		sendHeadsUp(s, sch)
+		w.notified = true
+	}
+	// the steps of a paused campaign wait until it is resumed
+	if sch.Paused && sch.CampaignID != "" {
+		logger.Debug("Campaign step held while paused", "id", sch.ID, "campaign", sch.CampaignID)
+		return true
+	}
+	if sch.SendAt.After(now) {
+		return true
+	}
+	if late := now.Sub(sch.SendAt); late > stallDelay {
+		alert("Deliveries are late", "Message "+sch.ID+" is sent "+late.Round(time.Second).String()+" after its time")
+	}
+	// the schedule stays pending until its turn in the queue of its channel,
+	// where it can still be cancelled
+	sendQueues.run(sch.ChannelID, func() {
+		if schedules.remove(sch) {
+			dispatch(s, sch)
+		}
+	})
+	return false
+}
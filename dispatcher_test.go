@@ -0,0 +1,138 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// benchmarkSchedules is how many schedules the benchmarks keep pending, the
+// scale the dispatcher is meant for.
+const benchmarkSchedules = 50000
+
+// pendSchedules registers and watches n schedules due at sendAt.
+func pendSchedules(n int, sendAt time.Time, paused bool) []*Schedule {
+	list := make([]*Schedule, n)
+	for k := range list {
+		list[k] = &Schedule{GuildID: "guild", ChannelID: "channel", AuthorID: "author", Content: "Hello there", Paused: paused, SendAt: sendAt.Add(time.Duration(k) * time.Millisecond)}
+		schedules.add(list[k])
+		watchSchedule(nil, list[k])
+	}
+	return list
+}
+
+// benchmarkStore replaces the store with one in a temporary directory, with
+// benchmarkSchedules schedules pending in it.
+func benchmarkStore(b *testing.B) {
+	saved := store
+	var err error
+	store, err = openStore(filepath.Join(b.TempDir(), "sendlater.json"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		store.flush()
+		store = saved
+	})
+	store.update(func(data *storeData) {
+		data.Pending = map[string]*Schedule{}
+		for k := range benchmarkSchedules {
+			id := strconv.Itoa(k)
+			data.Pending[id] = &Schedule{ID: id, GuildID: "guild", ChannelID: "channel", AuthorID: "author", Content: "Hello there", SendAt: time.Now().Add(time.Hour)}
+		}
+	})
+}
+
+// BenchmarkDeliveryStore records the steps of a delivery in a store with 50k
+// pending schedules, as dispatch does, and waits for them to be written.
+func BenchmarkDeliveryStore(b *testing.B) {
+	benchmarkStore(b)
+	sch := &Schedule{ID: "delivered", GuildID: "guild", ChannelID: "channel", AuthorID: "author", Content: "Hello there", SendAt: time.Now()}
+	for range b.N {
+		store.BeginDelivery(sch, time.Now())
+		store.RecordSent(sch.ID, sch.ChannelID, "message")
+		store.EndDelivery(sch.ID)
+		store.Audit(AuditSent, sch.AuthorID, sch)
+		store.flush()
+	}
+}
+
+// BenchmarkDispatcherWatch watches 50k pending schedules, and reports the
+// memory each one takes in the registry and the dispatcher.
+func BenchmarkDispatcherWatch(b *testing.B) {
+	var before, after runtime.MemStats
+	for range b.N {
+		b.StopTimer()
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		b.StartTimer()
+		list := pendSchedules(benchmarkSchedules, time.Now().Add(time.Hour), false)
+		b.StopTimer()
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/benchmarkSchedules, "B/schedule")
+		for _, sch := range list {
+			schedules.take(sch.ID)
+		}
+		dispatcher.prune()
+		b.StartTimer()
+	}
+}
+
+// BenchmarkDispatcherFire watches 50k schedules due at once, and waits until
+// the dispatcher has fired every one of them. They are paused so that
+// nothing is sent to Discord.
+func BenchmarkDispatcherFire(b *testing.B) {
+	benchmarkStore(b)
+	for range b.N {
+		pendSchedules(benchmarkSchedules, time.Now().Add(-time.Minute), true)
+		for {
+			count, _ := schedules.next()
+			dispatcher.mu.Lock()
+			queued := len(dispatcher.queue)
+			dispatcher.mu.Unlock()
+			if count == 0 && queued == 0 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestDispatcherOrder(t *testing.T) {
+	now := time.Now()
+	list := pendSchedules(100, now.Add(time.Hour), false)
+	defer func() {
+		for _, sch := range list {
+			schedules.take(sch.ID)
+		}
+		dispatcher.prune()
+	}()
+	dispatcher.mu.Lock()
+	defer dispatcher.mu.Unlock()
+	if len(dispatcher.queue) != 100 || dispatcher.queue[0].sch != list[0] {
+		t.Fatalf("first of %d watched schedules is not the earliest one", len(dispatcher.queue))
+	}
+	for k, w := range dispatcher.queue {
+		if w.index != k {
+			t.Fatalf("schedule %s at %d has index %d", w.sch.ID, k, w.index)
+		}
+	}
+}
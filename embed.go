@@ -0,0 +1,178 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// embedPayload is an embed as attached by the users. It follows the Discord
+// format, except that the color may also be written "#rrggbb".
+type embedPayload struct {
+	discordgo.MessageEmbed
+	Color json.RawMessage `json:"color,omitempty"`
+}
+
+// messagePayload is a message in the format of the Discord webhooks, as
+// exported by Discohook.
+type messagePayload struct {
+	Content         string                            `json:"content"`
+	Embeds          []embedPayload                    `json:"embeds"`
+	AllowedMentions *discordgo.MessageAllowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+// discohookShare is the format of the messages shared from Discohook, only
+// the first message is used.
+type discohookShare struct {
+	Messages []struct {
+		Data messagePayload `json:"data"`
+	} `json:"messages"`
+}
+
+// parseMessage decodes a JSON attachment, either a message in the webhook
+// format (content, embeds and allowed_mentions), a Discohook share or a
+// single embed.
+func parseMessage(data []byte) (messagePayload, []*discordgo.MessageEmbed, error) {
+	keys := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &keys)
+	if err != nil {
+		return messagePayload{}, nil, errors.New("invalid JSON: " + err.Error())
+	}
+
+	payload := messagePayload{}
+	_, hasContent := keys["content"]
+	_, hasEmbeds := keys["embeds"]
+	_, hasMessages := keys["messages"]
+	if hasMessages {
+		share := discohookShare{}
+		err = json.Unmarshal(data, &share)
+		if err != nil || len(share.Messages) == 0 {
+			return messagePayload{}, nil, errors.New("invalid Discohook message")
+		}
+		payload = share.Messages[0].Data
+	} else if hasContent || hasEmbeds {
+		err = json.Unmarshal(data, &payload)
+		if err != nil {
+			return messagePayload{}, nil, errors.New("invalid webhook message: " + err.Error())
+		}
+	} else {
+		embed, err := parseEmbed(data)
+		if err != nil {
+			return messagePayload{}, nil, err
+		}
+		return payload, []*discordgo.MessageEmbed{embed}, nil
+	}
+
+	if payload.Content == "" && len(payload.Embeds) == 0 {
+		return messagePayload{}, nil, errors.New("the message has no content nor embeds")
+	}
+	if len(payload.Embeds) > 10 {
+		return messagePayload{}, nil, errors.New("the message has more than 10 embeds")
+	}
+	if utf8.RuneCountInString(payload.Content) > 2000 {
+		return messagePayload{}, nil, errors.New("the message content is longer than 2000 characters")
+	}
+	embeds := []*discordgo.MessageEmbed{}
+	for n, embed := range payload.Embeds {
+		embed.MessageEmbed.Color, err = parseEmbedColor(embed.Color)
+		if err == nil {
+			err = validateEmbed(&embed.MessageEmbed)
+		}
+		if err != nil {
+			return messagePayload{}, nil, errors.New("embed " + strconv.Itoa(n+1) + ": " + err.Error())
+		}
+		embeds = append(embeds, &embed.MessageEmbed)
+	}
+	return payload, embeds, nil
+}
+
+// parseEmbed decodes and validates an embed, so that it is rejected when
+// scheduling rather than when sending.
+func parseEmbed(data []byte) (*discordgo.MessageEmbed, error) {
+	payload := embedPayload{}
+	err := json.Unmarshal(data, &payload)
+	if err != nil {
+		return nil, errors.New("invalid embed JSON: " + err.Error())
+	}
+	embed := payload.MessageEmbed
+	embed.Color, err = parseEmbedColor(payload.Color)
+	if err != nil {
+		return nil, err
+	}
+	return &embed, validateEmbed(&embed)
+}
+
+// parseEmbedColor reads a color written as a number or as "#rrggbb".
+func parseEmbedColor(raw json.RawMessage) (int, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+	color := 0
+	if json.Unmarshal(raw, &color) == nil {
+		return color, nil
+	}
+	text := ""
+	err := json.Unmarshal(raw, &text)
+	if err != nil {
+		return 0, errors.New("invalid embed color, use a number or \"#rrggbb\"")
+	}
+	value, err := strconv.ParseInt(strings.TrimPrefix(text, "#"), 16, 32)
+	if err != nil || value > 0xffffff || value < 0 {
+		return 0, errors.New("invalid embed color " + text + ", use \"#rrggbb\"")
+	}
+	return int(value), nil
+}
+
+// validateEmbed checks the limits Discord puts on embeds.
+func validateEmbed(embed *discordgo.MessageEmbed) error {
+	if embed.Title == "" && embed.Description == "" && len(embed.Fields) == 0 && embed.Image == nil {
+		return errors.New("the embed needs at least a title, a description, a field or an image")
+	}
+	total := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	if utf8.RuneCountInString(embed.Title) > 256 {
+		return errors.New("the embed title is longer than 256 characters")
+	}
+	if utf8.RuneCountInString(embed.Description) > 4096 {
+		return errors.New("the embed description is longer than 4096 characters")
+	}
+	if len(embed.Fields) > 25 {
+		return errors.New("the embed has more than 25 fields")
+	}
+	for n, field := range embed.Fields {
+		if field.Name == "" || field.Value == "" {
+			return errors.New("the embed field " + strconv.Itoa(n+1) + " needs a name and a value")
+		}
+		if utf8.RuneCountInString(field.Name) > 256 || utf8.RuneCountInString(field.Value) > 1024 {
+			return errors.New("the embed field " + strconv.Itoa(n+1) + " is too long (name 256, value 1024 characters)")
+		}
+		total += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	if embed.Footer != nil {
+		if utf8.RuneCountInString(embed.Footer.Text) > 2048 {
+			return errors.New("the embed footer is longer than 2048 characters")
+		}
+		total += utf8.RuneCountInString(embed.Footer.Text)
+	}
+	if total > 6000 {
+		return errors.New("the embed is longer than 6000 characters in total")
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// ErrorReport is an error of the bot with the context it happened in.
+type ErrorReport struct {
+	// Level is "error", or "fatal" for a panic.
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Stack   string            `json:"stack,omitempty"`
+	Time    time.Time         `json:"time"`
+}
+
+// ErrorReporter sends the errors of the bot to an error tracker.
+type ErrorReporter interface {
+	Report(report ErrorReport) error
+}
+
+var errorReporters []ErrorReporter
+
+// errorReportClient is the client of the error trackers, with a timeout so
+// that an unreachable tracker doesn't pile up goroutines.
+var errorReportClient = &http.Client{Timeout: 5 * time.Second}
+
+// setupErrorReporters creates the error reporters configured in the
+// environment.
+func setupErrorReporters() error {
+	if dsn := os.Getenv("SENDLATER_SENTRY_DSN"); dsn != "" {
+		reporter, err := newSentryReporter(dsn)
+		if err != nil {
+			return err
+		}
+		errorReporters = append(errorReporters, reporter)
+	}
+	if url := os.Getenv("SENDLATER_ERROR_WEBHOOK"); url != "" {
+		errorReporters = append(errorReporters, &webhookReporter{url: url, secret: os.Getenv("SENDLATER_ERROR_WEBHOOK_SECRET")})
+	}
+	logger.Info("Error reporters configured", "count", len(errorReporters))
+	return nil
+}
+
+// reportError sends the error to every error reporter, in the background.
+func reportError(message string, err error, tags map[string]string) {
+	sendReport(ErrorReport{Level: "error", Message: message + ": " + err.Error(), Tags: tags, Time: time.Now()})
+}
+
+// sendReport sends the report to every error reporter, in the background.
+func sendReport(report ErrorReport) {
+	for _, reporter := range errorReporters {
+		go func(reporter ErrorReporter) {
+			err := reporter.Report(report)
+			if err != nil {
+				logger.Error("Error reporting error", "error", err, "message", report.Message)
+			}
+		}(reporter)
+	}
+}
+
+// recoverPanics is deferred by the goroutines of the bot so that a panic only
+// stops the goroutine instead of crashing the bot, and with it every pending
+// schedule.
+func recoverPanics(tags map[string]string) {
+	if recovered := recover(); recovered != nil {
+		capturePanic(recovered, tags)
+	}
+}
+
+// capturePanic logs the recovered panic with its stack and sends it to every
+// error reporter.
+func capturePanic(recovered any, tags map[string]string) {
+	stack := string(debug.Stack())
+	attrs := []any{"panic", recovered, "stack", stack}
+	for key, value := range tags {
+		attrs = append(attrs, key, value)
+	}
+	logger.Error("Panic recovered", attrs...)
+	sendReport(ErrorReport{Level: "fatal", Message: fmt.Sprint("panic: ", recovered), Tags: tags, Stack: stack, Time: time.Now()})
+}
+
+// sentryReporter sends the errors to Sentry.
+type sentryReporter struct {
+	endpoint string
+	key      string
+}
+
+// newSentryReporter reads a DSN like https://<key>@<host>/<project>.
+func newSentryReporter(dsn string) (*sentryReporter, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil || parsed.User == nil || parsed.Host == "" {
+		return nil, errors.New("invalid Sentry DSN")
+	}
+	path, project, _ := strings.Cut(strings.Trim(parsed.Path, "/"), "/")
+	if project == "" {
+		path, project = "", path
+	}
+	if path != "" {
+		path = "/" + path
+	}
+	return &sentryReporter{
+		endpoint: parsed.Scheme + "://" + parsed.Host + path + "/api/" + project + "/store/",
+		key:      parsed.User.Username(),
+	}, nil
+}
+
+func (r *sentryReporter) Report(report ErrorReport) error {
+	event := map[string]any{
+		"event_id":  newID() + newID() + newID() + newID(),
+		"timestamp": report.Time.UTC().Format(time.RFC3339),
+		"level":     report.Level,
+		"platform":  "go",
+		"logger":    "send-later-discord-bot",
+		"message":   map[string]string{"formatted": report.Message},
+		"tags":      report.Tags,
+	}
+	if report.Stack != "" {
+		event["extra"] = map[string]string{"stack": report.Stack}
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.New("Error encoding event: " + err.Error())
+	}
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("Error creating request: " + err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_client=send-later-discord-bot/1.0, sentry_key="+r.key)
+	resp, err := errorReportClient.Do(req)
+	if err != nil {
+		return errors.New("Error posting event: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New("Error posting event: " + resp.Status)
+	}
+	return nil
+}
+
+// webhookReporter posts the errors as signed JSON payloads.
+type webhookReporter struct {
+	url    string
+	secret string
+}
+
+func (r *webhookReporter) Report(report ErrorReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return errors.New("Error encoding report: " + err.Error())
+	}
+	return postSigned(r.url, r.secret, body)
+}
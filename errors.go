@@ -0,0 +1,169 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Codes of the errors of the members, documented in the README.
+const (
+	codeInvalidDate    = "invalid_date"
+	codeInvalidTime    = "invalid_time"
+	codeInvalidDelay   = "invalid_delay"
+	codeQuietHours     = "quiet_hours"
+	codeTooSoon        = "too_soon"
+	codeTooFar         = "too_far"
+	codeTooLarge       = "too_large"
+	codeInvalidLink    = "invalid_link"
+	codeInvalidMessage = "invalid_message"
+	codeInvalidOption  = "invalid_option"
+	codeInvalidChannel = "invalid_channel"
+	codeNotAllowed     = "not_allowed"
+	codeMissingAccess  = "missing_access"
+	codeNotFound       = "not_found"
+	codeUnavailable    = "unavailable"
+)
+
+// userError is a mistake of the member, explained with a hint on how to fix it
+// and a code to look up in the documentation.
+type userError struct {
+	Code    string
+	Message string
+	Hint    string
+}
+
+func (e *userError) Error() string {
+	return e.Message
+}
+
+// respondError tells the member why the command failed, only to them. The
+// hint and the code of a userError are shown below its message.
+func respondError(s *discordgo.Session, i *discordgo.InteractionCreate, context string, err error) {
+	content := context + ": " + err.Error()
+	var userErr *userError
+	if errors.As(err, &userErr) {
+		if userErr.Hint != "" {
+			content += "\n" + userErr.Hint
+		}
+		content += "\n-# Error code `" + userErr.Code + "`"
+	}
+	respondEphemeral(s, i, content)
+}
+
+// discordError explains the failure of a request to Discord, or of a
+// download, made to do what the member asked. A userError is returned as it
+// is.
+func discordError(action string, err error) error {
+	var userErr *userError
+	if errors.As(err, &userErr) {
+		return err
+	}
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Message != nil {
+		switch restErr.Message.Code {
+		case discordgo.ErrCodeUnknownChannel, discordgo.ErrCodeUnknownMessage:
+			return &userError{Code: codeNotFound, Message: action + ": it doesn't exist anymore", Hint: "Check the link or the ID, and that it was not deleted."}
+		case discordgo.ErrCodeMissingAccess, discordgo.ErrCodeMissingPermissions:
+			return &userError{Code: codeMissingAccess, Message: action + ": I don't have access to it", Hint: "Ask an admin to let me view the channel and read its history."}
+		}
+	}
+	return &userError{Code: codeUnavailable, Message: action + ": " + err.Error(), Hint: "Try again in a moment."}
+}
+
+var (
+	isoDate      = regexp.MustCompile(`^(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})$`)
+	numericDate  = regexp.MustCompile(`^(\d{1,2})[-/. ](\d{1,2})[-/. ](\d{2}|\d{4})$`)
+	dayMonthDate = regexp.MustCompile(`^(\d{1,2})[-/. ](\d{1,2})$`)
+)
+
+// checkDate explains why date is not a dd/mm/yyyy date, suggesting what the
+// member may have meant.
+func checkDate(date string, now time.Time) error {
+	if _, err := time.Parse("02/01/2006", date); err == nil {
+		return nil
+	}
+
+	day, month, year := "", "", ""
+	if match := isoDate.FindStringSubmatch(date); match != nil {
+		year, month, day = match[1], match[2], match[3]
+	} else if match := numericDate.FindStringSubmatch(date); match != nil {
+		day, month, year = match[1], match[2], match[3]
+		if len(year) == 2 {
+			year = "20" + year
+		}
+	} else if match := dayMonthDate.FindStringSubmatch(date); match != nil {
+		day, month, year = match[1], match[2], strconv.Itoa(now.Year())
+	}
+
+	err := &userError{Code: codeInvalidDate, Message: "the date must be dd/mm/yyyy, you wrote " + date, Hint: "For example 31/12/2025 for December 31st."}
+	if suggestion := formatDate(day, month, year); suggestion != "" {
+		err.Hint = "Did you mean " + suggestion + "?"
+	} else if suggestion := formatDate(month, day, year); suggestion != "" {
+		// a date written month first
+		err.Hint = "Did you mean " + suggestion + "? The day comes first."
+	} else if day != "" {
+		err.Message = date + " is not a valid date"
+		err.Hint = "Check the day and the month, the date is dd/mm/yyyy."
+	}
+	return err
+}
+
+// formatDate writes the date as dd/mm/yyyy, or returns "" if it is not a
+// valid date.
+func formatDate(day string, month string, year string) string {
+	d, dayErr := strconv.Atoi(day)
+	m, monthErr := strconv.Atoi(month)
+	y, yearErr := strconv.Atoi(year)
+	if dayErr != nil || monthErr != nil || yearErr != nil {
+		return ""
+	}
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if t.Day() != d || int(t.Month()) != m {
+		return ""
+	}
+	return t.Format("02/01/2006")
+}
+
+// checkTime explains why sendTime is not a HH:MM time, suggesting what the
+// member may have meant.
+func checkTime(sendTime string) error {
+	if _, err := time.Parse("15:04", sendTime); err == nil {
+		return nil
+	}
+	err := &userError{Code: codeInvalidTime, Message: "the time must be HH:MM on 24 hours, you wrote " + sendTime, Hint: "For example 09:05 or 18:30."}
+
+	typed := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(sendTime), " ", ""))
+	typed = strings.ReplaceAll(typed, ".", ":")
+	afternoon := strings.HasSuffix(typed, "pm")
+	morning := strings.HasSuffix(typed, "am")
+	typed = strings.TrimSuffix(strings.TrimSuffix(typed, "pm"), "am")
+	if hour, minute, ok := parseClock(typed); ok {
+		if afternoon && hour < 12 {
+			hour += 12
+		} else if morning && hour == 12 {
+			// 12am is midnight
+			hour = 0
+		}
+		err.Hint = "Did you mean " + time.Date(0, 1, 1, hour, minute, 0, 0, time.UTC).Format("15:04") + "?"
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"testing"
+	"time"
+)
+
+func TestCheckDate(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	if err := checkDate("31/12/2025", now); err != nil {
+		t.Errorf("valid date refused: %v", err)
+	}
+	hints := map[string]string{
+		"2025-12-31": "Did you mean 31/12/2025?",
+		"1/2":        "Did you mean 01/02/2025?",
+		"12/31/2025": "Did you mean 31/12/2025? The day comes first.",
+		"31/02/2025": "Check the day and the month, the date is dd/mm/yyyy.",
+	}
+	for date, hint := range hints {
+		var userErr *userError
+		if !errors.As(checkDate(date, now), &userErr) || userErr.Hint != hint {
+			t.Errorf("%s: got %v, want the hint %q", date, userErr, hint)
+		}
+	}
+}
+
+func TestCheckTime(t *testing.T) {
+	if err := checkTime("18:30"); err != nil {
+		t.Errorf("valid time refused: %v", err)
+	}
+	suggestions := map[string]string{
+		"6pm":   "18:00",
+		"12pm":  "12:00",
+		"12am":  "00:00",
+		"9.05":  "09:05",
+		"18h30": "18:30",
+	}
+	for typed, suggestion := range suggestions {
+		var userErr *userError
+		if !errors.As(checkTime(typed), &userErr) || userErr.Hint != "Did you mean "+suggestion+"?" {
+			t.Errorf("%s: got %v, want the suggestion %s", typed, userErr, suggestion)
+		}
+	}
+}
+
+func TestDiscordError(t *testing.T) {
+	codes := map[error]string{
+		&discordgo.RESTError{Message: &discordgo.APIErrorMessage{Code: discordgo.ErrCodeUnknownMessage}}:     codeNotFound,
+		&discordgo.RESTError{Message: &discordgo.APIErrorMessage{Code: discordgo.ErrCodeMissingPermissions}}: codeMissingAccess,
+		errors.New("connection reset"):  codeUnavailable,
+		attachmentTooLarge("video.mp4"): codeTooLarge,
+	}
+	for err, code := range codes {
+		var userErr *userError
+		if !errors.As(discordError("cannot get the message", err), &userErr) || userErr.Code != code {
+			t.Errorf("%v: got %v, want the code %s", err, userErr, code)
+		}
+	}
+}
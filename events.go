@@ -0,0 +1,142 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// scheduleEvent is a change in the life of a schedule, one of the audit
+// actions.
+type scheduleEvent struct {
+	Action   string
+	UserID   string
+	Schedule *Schedule
+	Time     time.Time
+}
+
+// eventBufferSize is how many events a slow subscriber can fall behind before
+// they are dropped for it.
+const eventBufferSize = 100
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   = map[chan scheduleEvent]struct{}{}
+)
+
+// subscribe returns a channel receiving the events of the schedules, and the
+// function to stop receiving them.
+func subscribe() (<-chan scheduleEvent, func()) {
+	events := make(chan scheduleEvent, eventBufferSize)
+	subscribersMu.Lock()
+	subscribers[events] = struct{}{}
+	subscribersMu.Unlock()
+	return events, func() {
+		subscribersMu.Lock()
+		delete(subscribers, events)
+		subscribersMu.Unlock()
+	}
+}
+
+// publish sends the event to every subscriber. It never blocks, a subscriber
+// whose buffer is full misses the event.
+func publish(action string, userID string, sch *Schedule) {
+	event := scheduleEvent{Action: action, UserID: userID, Schedule: sch, Time: time.Now()}
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	for events := range subscribers {
+		select {
+		case events <- event:
+		default:
+			logger.Warn("Schedule event dropped for a slow subscriber", "action", action, "id", sch.ID)
+		}
+	}
+}
+
+// eventWebhook posts the events of the schedules to an external URL.
+type eventWebhook struct {
+	url     string
+	secret  string
+	actions []string
+}
+
+// eventPayload is the body posted to the event webhooks.
+type eventPayload struct {
+	Action   string      `json:"action"`
+	UserID   string      `json:"user_id"`
+	Time     time.Time   `json:"time"`
+	Schedule apiSchedule `json:"schedule"`
+}
+
+// eventWebhookSecrets returns the secret of each webhook of urls: its own
+// one of secrets, given in the same order, or else the shared one.
+func eventWebhookSecrets(urls []string, secrets []string, shared string) []string {
+	if len(secrets) == len(urls) {
+		return secrets
+	}
+	list := []string{}
+	for range urls {
+		list = append(list, shared)
+	}
+	return list
+}
+
+// postEvents posts the events of the schedules to every webhook of urls,
+// signed with its secret of secrets, in the background. Only the events of
+// actions are posted, or all of them if actions is empty.
+func postEvents(urls []string, secrets []string, actions []string) {
+	if len(urls) == 0 {
+		return
+	}
+	webhooks := []*eventWebhook{}
+	for n, url := range urls {
+		webhooks = append(webhooks, &eventWebhook{url: url, secret: secrets[n], actions: actions})
+	}
+	events, _ := subscribe()
+	go func() {
+		defer recoverPanics(map[string]string{"task": "event webhooks"})
+		logger.Info("Posting schedule events", "webhooks", len(webhooks), "actions", actions)
+		for event := range events {
+			for _, webhook := range webhooks {
+				webhook.post(event)
+			}
+		}
+	}()
+}
+
+// post posts the event to the webhook if it is one of its actions.
+func (w *eventWebhook) post(event scheduleEvent) {
+	if len(w.actions) > 0 && !slices.Contains(w.actions, event.Action) {
+		return
+	}
+	body, err := json.Marshal(eventPayload{
+		Action:   event.Action,
+		UserID:   event.UserID,
+		Time:     event.Time,
+		Schedule: newAPISchedule(event.Schedule),
+	})
+	if err != nil {
+		logger.Error("Error encoding schedule event", "error", err, "id", event.Schedule.ID)
+		return
+	}
+	err = postSigned(w.url, w.secret, body)
+	if err != nil {
+		logger.Error("Error posting schedule event", "error", err, "url", w.url, "action", event.Action, "id", event.Schedule.ID)
+	}
+}
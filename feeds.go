@@ -0,0 +1,491 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feed is an RSS or Atom feed whose new items are posted in a channel in a
+// daily digest, instead of as soon as they are published.
+type Feed struct {
+	URL       string `json:"url"`
+	ChannelID string `json:"channel_id"`
+	// DigestTime is the "HH:MM" time of the digest, in the time zone of the
+	// guild.
+	DigestTime string `json:"digest_time"`
+	AddedBy    string `json:"added_by"`
+	// Seen are the IDs of the latest items of the feed, already posted or
+	// published before the feed was added.
+	Seen []string `json:"seen,omitempty"`
+}
+
+// Limits of the feeds, so that a busy feed does not flood the store nor the
+// channel.
+const (
+	maxFeeds          = 10
+	maxFeedSeen       = 200
+	maxDigestItems    = 20
+	feedPollPeriod    = 30 * time.Minute
+	defaultDigestTime = "09:00"
+)
+
+// maxFeedSize is the size of the largest feed that is read, the feeds only
+// listing their last items.
+const maxFeedSize = 5 << 20
+
+var feedClient = &http.Client{Timeout: 30 * time.Second}
+
+// Feeds returns the feeds of the guild.
+func (st *Store) Feeds(guildID string) []Feed {
+	feeds := []Feed{}
+	st.view(func(data *storeData) {
+		feeds = slices.Clone(data.Feeds[guildID])
+	})
+	return feeds
+}
+
+// allFeeds returns the feeds of every guild, by guild ID.
+func (st *Store) allFeeds() map[string][]Feed {
+	feeds := map[string][]Feed{}
+	st.view(func(data *storeData) {
+		for guildID, list := range data.Feeds {
+			feeds[guildID] = slices.Clone(list)
+		}
+	})
+	return feeds
+}
+
+// SaveFeed adds the feed to the guild, or replaces the one with the same URL.
+func (st *Store) SaveFeed(guildID string, feed Feed) error {
+	return st.update(func(data *storeData) {
+		if data.Feeds == nil {
+			data.Feeds = map[string][]Feed{}
+		}
+		list := data.Feeds[guildID]
+		n := slices.IndexFunc(list, func(f Feed) bool { return f.URL == feed.URL })
+		if n < 0 {
+			data.Feeds[guildID] = append(list, feed)
+			return
+		}
+		list[n] = feed
+	})
+}
+
+// MarkFeedSeen adds ids to the seen items of the feed with the given URL, if
+// it was not removed in the meantime.
+func (st *Store) MarkFeedSeen(guildID string, url string, ids []string) error {
+	return st.update(func(data *storeData) {
+		list := data.Feeds[guildID]
+		n := slices.IndexFunc(list, func(f Feed) bool { return f.URL == url })
+		if n < 0 {
+			return
+		}
+		seen := append(slices.Clone(list[n].Seen), ids...)
+		if len(seen) > maxFeedSeen {
+			seen = seen[len(seen)-maxFeedSeen:]
+		}
+		list[n].Seen = seen
+	})
+}
+
+// RemoveFeed removes the feed with the given URL from the guild, and reports
+// whether it was there.
+func (st *Store) RemoveFeed(guildID string, url string) (bool, error) {
+	found := false
+	err := st.update(func(data *storeData) {
+		list := data.Feeds[guildID]
+		n := slices.IndexFunc(list, func(f Feed) bool { return f.URL == url })
+		if n < 0 {
+			return
+		}
+		found = true
+		data.Feeds[guildID] = slices.Delete(list, n, n+1)
+		if len(data.Feeds[guildID]) == 0 {
+			delete(data.Feeds, guildID)
+		}
+	})
+	return found, err
+}
+
+// feedItem is an item of an RSS feed or an entry of an Atom feed.
+type feedItem struct {
+	ID    string
+	Title string
+	Link  string
+}
+
+// feedDocument reads both RSS and Atom feeds, as only the fields of the
+// format of the document are set.
+type feedDocument struct {
+	// RSS 2.0, and RSS 1.0 whose items are next to the channel
+	ChannelTitle string    `xml:"channel>title"`
+	Items        []rssItem `xml:"channel>item"`
+	RDFItems     []rssItem `xml:"item"`
+	// Atom
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+	GUID  string `xml:"guid"`
+}
+
+type atomEntry struct {
+	Title string `xml:"title"`
+	ID    string `xml:"id"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+}
+
+// parseFeed returns the title and the items of an RSS or Atom feed, in the
+// order of the feed.
+func parseFeed(data []byte) (string, []feedItem, error) {
+	doc := feedDocument{}
+	err := xml.Unmarshal(data, &doc)
+	if err != nil {
+		return "", nil, errors.New("Error reading feed: " + err.Error())
+	}
+	items := []feedItem{}
+	for _, item := range append(doc.Items, doc.RDFItems...) {
+		id := item.GUID
+		if id == "" {
+			id = item.Link
+		}
+		items = append(items, feedItem{ID: id, Title: strings.TrimSpace(item.Title), Link: strings.TrimSpace(item.Link)})
+	}
+	for _, entry := range doc.Entries {
+		item := feedItem{ID: entry.ID, Title: strings.TrimSpace(entry.Title)}
+		for _, link := range entry.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				item.Link = link.Href
+				break
+			}
+		}
+		if item.ID == "" {
+			item.ID = item.Link
+		}
+		items = append(items, item)
+	}
+	title := doc.ChannelTitle
+	if title == "" {
+		title = doc.Title
+	}
+	return strings.TrimSpace(title), items, nil
+}
+
+// fetchFeed returns the title and the items of the feed at url.
+func fetchFeed(url string) (string, []feedItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", nil, errors.New("Error getting feed: " + err.Error())
+	}
+	resp, err := feedClient.Do(req)
+	if err != nil {
+		return "", nil, errors.New("Error getting feed: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, errors.New("Error getting feed: " + resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+	if err != nil {
+		return "", nil, errors.New("Error getting feed: " + err.Error())
+	}
+	if len(data) > maxFeedSize {
+		return "", nil, errors.New("Error getting feed: it is larger than " + strconv.Itoa(maxFeedSize>>20) + " MB")
+	}
+	return parseFeed(data)
+}
+
+// feedDigest is the pending digest of a feed, with the items it posts.
+type feedDigest struct {
+	scheduleID string
+	items      []feedItem
+}
+
+var (
+	digestsMu sync.Mutex
+	// digests are the pending digests, by guild ID and feed URL
+	digests = map[string]*feedDigest{}
+)
+
+// watchFeeds checks the feeds of every guild every feedPollPeriod, and adds
+// their new items to the next digest of each feed.
+func watchFeeds(s *discordgo.Session) {
+	go func() {
+		defer recoverPanics(map[string]string{"task": "feeds"})
+		ticker := time.NewTicker(feedPollPeriod)
+		defer ticker.Stop()
+		for {
+			for guildID, feeds := range store.allFeeds() {
+				if !guildAllowed(guildID) {
+					continue
+				}
+				for _, feed := range feeds {
+					err := checkFeed(s, guildID, feed, time.Now())
+					if err != nil {
+						logger.Error("Error checking feed", "error", err, "guild", guildID, "url", feed.URL)
+					}
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// checkFeed adds the new items of the feed to its pending digest, or
+// schedules one at the next digest time.
+func checkFeed(s *discordgo.Session, guildID string, feed Feed, now time.Time) error {
+	title, items, err := fetchFeed(feed.URL)
+	if err != nil {
+		return err
+	}
+	fresh := []feedItem{}
+	for _, item := range items {
+		if !slices.Contains(feed.Seen, item.ID) {
+			fresh = append(fresh, item)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	digestsMu.Lock()
+	defer digestsMu.Unlock()
+	key := guildID + " " + feed.URL
+	digest := digests[key]
+	var previous *Schedule
+	if digest != nil {
+		previous = schedules.get(digest.scheduleID)
+	}
+	if previous == nil {
+		digest = &feedDigest{}
+	}
+	digest.items = append(digest.items, fresh...)
+	content := digestContent(title, digest.items)
+
+	if previous != nil {
+		// the digest is replaced by a copy with the new items, as a
+		// dispatcher worker may be reading it
+		updated := *previous
+		updated.Content = content
+		if schedules.replace(&updated) != nil {
+			watchSchedule(s, &updated)
+			audit(s, AuditEdited, s.State.User.ID, &updated)
+		}
+	} else {
+		channel, err := s.State.Channel(feed.ChannelID)
+		if err != nil {
+			channel, err = s.Channel(feed.ChannelID)
+			if err != nil {
+				return errors.New("Error getting feed channel: " + err.Error())
+			}
+		}
+		config := store.GuildConfig(guildID)
+		sch := &Schedule{
+			GuildID:     guildID,
+			ChannelID:   channel.ID,
+			ChannelName: channel.Name,
+			AuthorID:    s.State.User.ID,
+			Content:     content,
+			SendAt:      nextDigestTime(feed.DigestTime, config.location(), now),
+		}
+		startSchedule(s, sch)
+		digest.scheduleID = sch.ID
+		digests[key] = digest
+	}
+	logger.Info("Feed items added to the digest", "guild", guildID, "url", feed.URL, "items", len(fresh), "id", digest.scheduleID)
+
+	// the items are remembered once they are in a digest
+	ids := []string{}
+	for _, item := range fresh {
+		ids = append(ids, item.ID)
+	}
+	return store.MarkFeedSeen(guildID, feed.URL, ids)
+}
+
+// digestContent lists the items of a digest as links, the last ones are
+// counted when they don't fit.
+func digestContent(title string, items []feedItem) string {
+	if title == "" {
+		title = "New posts"
+	}
+	content := "📰 **" + title + "**"
+	for n, item := range items {
+		line := "\n- " + item.Title
+		if item.Link != "" {
+			line = "\n- [" + item.Title + "](<" + item.Link + ">)"
+		}
+		more := "\n…and " + strconv.Itoa(len(items)-n) + " more"
+		if n == maxDigestItems || len([]rune(content+line+more)) > maxMessageLength {
+			return content + more
+		}
+		content += line
+	}
+	return content
+}
+
+// nextDigestTime returns the next time of the day at digestTime in location.
+func nextDigestTime(digestTime string, location *time.Location, now time.Time) time.Time {
+	clock, err := time.Parse("15:04", digestTime)
+	if err != nil {
+		clock, _ = time.Parse("15:04", defaultDigestTime)
+	}
+	local := now.In(location)
+	next := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Actions of the feed subcommand.
+const (
+	feedAdd    = "add"
+	feedRemove = "remove"
+	feedList   = "list"
+)
+
+func handleFeed(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if i.GuildID == "" {
+		respond(s, i, "The feeds are only available in a server")
+		return
+	}
+	if !hasPermission(i, discordgo.PermissionManageServer) {
+		respond(s, i, "Only the server admins can manage the feeds")
+		return
+	}
+
+	action := ""
+	url := ""
+	digestTime := defaultDigestTime
+	var channel *discordgo.Channel
+	for _, option := range options {
+		if option.Name == "action" {
+			action = option.StringValue()
+		} else if option.Name == "url" {
+			url = strings.TrimSpace(option.StringValue())
+		} else if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		} else if option.Name == "digest_time" {
+			digestTime = strings.TrimSpace(option.StringValue())
+		}
+	}
+	user := interactionUser(i)
+
+	switch action {
+	case feedList:
+		feeds := store.Feeds(i.GuildID)
+		if len(feeds) == 0 {
+			respond(s, i, "No feeds are posted in this server")
+			return
+		}
+		content := "Feeds posted in this server:"
+		for _, feed := range feeds {
+			content += "\n- <" + feed.URL + "> in <#" + feed.ChannelID + "> at " + feed.DigestTime
+		}
+		respond(s, i, truncate(content, maxMessageLength))
+	case feedRemove:
+		found, err := store.RemoveFeed(i.GuildID, url)
+		if err != nil {
+			respondError(s, i, "Error removing feed", err)
+			return
+		}
+		if !found {
+			respond(s, i, "No feed with the URL "+url+" is posted in this server")
+			return
+		}
+		digestsMu.Lock()
+		digest := digests[i.GuildID+" "+url]
+		delete(digests, i.GuildID+" "+url)
+		digestsMu.Unlock()
+		if digest != nil {
+			if sch := schedules.take(digest.scheduleID); sch != nil {
+				audit(s, AuditCancelled, user.ID, sch)
+			}
+		}
+		logger.Info("Feed removed", "guild", i.GuildID, "url", url, "user", user.ID)
+		respond(s, i, "The feed is not posted anymore")
+	case feedAdd:
+		if url == "" {
+			respond(s, i, "The URL of the feed is required to add it")
+			return
+		}
+		if _, err := time.Parse("15:04", digestTime); err != nil {
+			respond(s, i, "The digest time must be HH:MM, like 09:00")
+			return
+		}
+		if len(store.Feeds(i.GuildID)) >= maxFeeds && !slices.ContainsFunc(store.Feeds(i.GuildID), func(f Feed) bool { return f.URL == url }) {
+			respond(s, i, "This server already has "+strconv.Itoa(maxFeeds)+" feeds, remove one first")
+			return
+		}
+		if channel == nil {
+			var err error
+			channel, err = s.Channel(i.ChannelID)
+			if err != nil {
+				respondError(s, i, "Error adding feed", discordError("cannot get this channel", err))
+				return
+			}
+		}
+		if err := checkChannel(channel, false); err != nil {
+			respondError(s, i, "Error adding feed", err)
+			return
+		}
+
+		// reading the feed can take longer than the time allowed to respond
+		deferResponse(s, i, replyFlags())
+		title, items, err := fetchFeed(url)
+		if err != nil {
+			respondError(s, i, "Error adding feed", err)
+			return
+		}
+		// the items already published are not posted
+		feed := Feed{URL: url, ChannelID: channel.ID, DigestTime: digestTime, AddedBy: user.ID}
+		for _, item := range items {
+			feed.Seen = append(feed.Seen, item.ID)
+		}
+		if len(feed.Seen) > maxFeedSeen {
+			feed.Seen = feed.Seen[len(feed.Seen)-maxFeedSeen:]
+		}
+		err = store.SaveFeed(i.GuildID, feed)
+		if err != nil {
+			respondError(s, i, "Error adding feed", err)
+			return
+		}
+		if title == "" {
+			title = url
+		}
+		logger.Info("Feed added", "guild", i.GuildID, "url", url, "channel", channel.ID, "user", user.ID)
+		respond(s, i, "The new posts of "+title+" will be posted in <#"+channel.ID+"> every day at "+digestTime)
+	}
+}
@@ -0,0 +1,122 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"slices"
+	"strings"
+)
+
+// Feature flags gate experimental capabilities, they are disabled by default
+// and enabled per guild by the bot owners.
+const (
+	FlagWebhookDelivery = "webhook_delivery"
+	FlagCampaigns       = "campaigns"
+	FlagApprovalMode    = "approval_mode"
+)
+
+var knownFlags = []string{FlagWebhookDelivery, FlagCampaigns, FlagApprovalMode}
+
+// flagChoices returns the known flags as choices for a command option.
+func flagChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(knownFlags))
+	for _, flag := range knownFlags {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: flag, Value: flag})
+	}
+	return choices
+}
+
+// FlagEnabled reports whether flag is enabled for the guild.
+func (st *Store) FlagEnabled(guildID string, flag string) bool {
+	enabled := false
+	st.view(func(data *storeData) {
+		enabled = data.Flags[guildID][flag]
+	})
+	return enabled
+}
+
+// SetFlag enables or disables flag for the guild.
+func (st *Store) SetFlag(guildID string, flag string, enabled bool) error {
+	return st.update(func(data *storeData) {
+		if data.Flags == nil {
+			data.Flags = map[string]map[string]bool{}
+		}
+		if enabled {
+			if data.Flags[guildID] == nil {
+				data.Flags[guildID] = map[string]bool{}
+			}
+			data.Flags[guildID][flag] = true
+			return
+		}
+		delete(data.Flags[guildID], flag)
+		if len(data.Flags[guildID]) == 0 {
+			delete(data.Flags, guildID)
+		}
+	})
+}
+
+// isOwner reports whether the user is one of the bot owners.
+func isOwner(userID string) bool {
+	return userID != "" && slices.Contains(settings().owners, userID)
+}
+
+func handleFlag(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	user := interactionUser(i)
+	if !isOwner(user.ID) {
+		logger.Warn("Flag change refused", "user", user.ID)
+		respond(s, i, "Only the bot owners can change feature flags")
+		return
+	}
+
+	flag := ""
+	enabled := false
+	guildID := i.GuildID
+	for _, option := range options {
+		if option.Name == "name" {
+			flag = option.StringValue()
+		} else if option.Name == "enabled" {
+			enabled = option.BoolValue()
+		} else if option.Name == "guild" {
+			guildID = option.StringValue()
+		}
+	}
+	if guildID == "" {
+		respondError(s, i, "Error changing flag", errors.New("a guild is needed outside of a server"))
+		return
+	}
+
+	err := store.SetFlag(guildID, flag, enabled)
+	if err != nil {
+		logger.Error("Error changing flag", "error", err, "flag", flag, "guild", guildID)
+		respondError(s, i, "Error changing flag", err)
+		return
+	}
+	logger.Info("Flag changed", "flag", flag, "enabled", enabled, "guild", guildID, "user", user.ID)
+
+	enabledFlags := []string{}
+	for _, known := range knownFlags {
+		if store.FlagEnabled(guildID, known) {
+			enabledFlags = append(enabledFlags, known)
+		}
+	}
+	if len(enabledFlags) == 0 {
+		respond(s, i, "No feature flag enabled for guild "+guildID)
+		return
+	}
+	respond(s, i, "Feature flags enabled for guild "+guildID+": "+strings.Join(enabledFlags, ", "))
+}
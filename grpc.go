@@ -0,0 +1,346 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"github.com/bwmarrin/discordgo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net"
+	"net/http"
+	"time"
+)
+
+// grpcService is the full name of the gRPC service of proto/sendlater.proto.
+const grpcService = "sendlater.v1.Scheduler"
+
+// grpcFile describes proto/sendlater.proto, so that the messages are read and
+// written with dynamicpb instead of code generated by protoc. Both must be
+// changed together, which TestGRPCFile checks.
+func grpcFile() *descriptorpb.FileDescriptorProto {
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	boolean := descriptorpb.FieldDescriptorProto_TYPE_BOOL
+	message := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	timestamp := ".google.protobuf.Timestamp"
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("sendlater.proto"),
+		Package:    proto.String("sendlater.v1"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			grpcMessage("Schedule",
+				grpcField("id", 1, str, ""),
+				grpcField("guild_id", 2, str, ""),
+				grpcField("channel_id", 3, str, ""),
+				grpcField("author_id", 4, str, ""),
+				grpcField("content", 5, str, ""),
+				grpcField("send_at", 6, message, timestamp),
+				grpcField("awaiting_approval", 7, boolean, ""),
+			),
+			grpcMessage("CreateRequest",
+				grpcField("channel_id", 1, str, ""),
+				grpcField("author_id", 2, str, ""),
+				grpcField("content", 3, str, ""),
+				grpcField("send_at", 4, message, timestamp),
+				grpcField("tts", 5, boolean, ""),
+				grpcField("silent", 6, boolean, ""),
+			),
+			grpcMessage("ListRequest",
+				grpcField("guild_id", 1, str, ""),
+				grpcField("author_id", 2, str, ""),
+			),
+			grpcMessage("ListResponse", grpcRepeated(grpcField("schedules", 1, message, ".sendlater.v1.Schedule"))),
+			grpcMessage("CancelRequest", grpcField("id", 1, str, "")),
+			grpcMessage("CancelResponse"),
+			grpcMessage("WatchRequest", grpcField("guild_id", 1, str, "")),
+			grpcMessage("Event",
+				grpcField("action", 1, str, ""),
+				grpcField("user_id", 2, str, ""),
+				grpcField("schedule", 3, message, ".sendlater.v1.Schedule"),
+				grpcField("time", 4, message, timestamp),
+			),
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Scheduler"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				grpcMethod("Create", "CreateRequest", "Schedule", false),
+				grpcMethod("List", "ListRequest", "ListResponse", false),
+				grpcMethod("Cancel", "CancelRequest", "CancelResponse", false),
+				grpcMethod("Watch", "WatchRequest", "Event", true),
+			},
+		}},
+	}
+}
+
+func grpcMessage(name string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields}
+}
+
+func grpcField(name string, number int32, kind descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   kind.Enum(),
+	}
+	if typeName != "" {
+		field.TypeName = proto.String(typeName)
+	}
+	return field
+}
+
+func grpcRepeated(field *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+	field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return field
+}
+
+func grpcMethod(name string, input string, output string, streaming bool) *descriptorpb.MethodDescriptorProto {
+	return &descriptorpb.MethodDescriptorProto{
+		Name:            proto.String(name),
+		InputType:       proto.String(".sendlater.v1." + input),
+		OutputType:      proto.String(".sendlater.v1." + output),
+		ServerStreaming: proto.Bool(streaming),
+	}
+}
+
+// grpcScheduler serves the gRPC service with the messages of grpcFile.
+type grpcScheduler struct {
+	session *discordgo.Session
+	file    protoreflect.FileDescriptor
+}
+
+// newMessage returns an empty message of the given name of grpcFile.
+func (g *grpcScheduler) newMessage(name protoreflect.Name) *dynamicpb.Message {
+	return dynamicpb.NewMessage(g.file.Messages().ByName(name))
+}
+
+// serveGRPC serves the gRPC service on addr in the background, unless addr is
+// empty. The calls must carry one of tokens as a bearer token, like the HTTP
+// API.
+func serveGRPC(s *discordgo.Session, addr string, tokens []string) {
+	if addr == "" {
+		return
+	}
+	if len(tokens) == 0 {
+		logger.Error("The gRPC service is not served without SENDLATER_API_TOKENS", "address", addr)
+		return
+	}
+	file, err := protodesc.NewFile(grpcFile(), protoregistry.GlobalFiles)
+	if err != nil {
+		logger.Error("Error describing gRPC service", "error", err)
+		return
+	}
+	g := &grpcScheduler{session: s, file: file}
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if !grpcAuthorized(ctx, tokens) {
+				logger.Warn("gRPC call refused", "method", info.FullMethod)
+				return nil, status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if !grpcAuthorized(stream.Context(), tokens) {
+				logger.Warn("gRPC call refused", "method", info.FullMethod)
+				return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+			}
+			return handler(srv, stream)
+		}),
+	)
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: grpcService,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			g.unary("Create", "CreateRequest", g.create),
+			g.unary("List", "ListRequest", g.list),
+			g.unary("Cancel", "CancelRequest", g.cancel),
+		},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Watch",
+			Handler:       g.watch,
+			ServerStreams: true,
+		}},
+		Metadata: "sendlater.proto",
+	}, g)
+
+	go func() {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			logger.Error("Error listening for gRPC", "error", err, "address", addr)
+			return
+		}
+		logger.Info("Serving gRPC", "address", addr)
+		err = server.Serve(listener)
+		logger.Error("gRPC stopped", "error", err, "address", addr)
+	}()
+}
+
+// grpcAuthorized reports whether the call carries one of tokens.
+func grpcAuthorized(ctx context.Context, tokens []string) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, header := range md.Get("authorization") {
+		if validAPIToken(header, tokens) {
+			return true
+		}
+	}
+	return false
+}
+
+// unary describes a unary method reading a request message of the given name.
+func (g *grpcScheduler) unary(name string, input protoreflect.Name, fn func(ctx context.Context, req *dynamicpb.Message) (proto.Message, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := g.newMessage(input)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return fn(ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + grpcService + "/" + name}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return fn(ctx, req.(*dynamicpb.Message))
+			})
+		},
+	}
+}
+
+func (g *grpcScheduler) create(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+	sch, code, err := createAPISchedule(g.session, apiCreateRequest{
+		ChannelID: grpcString(req, "channel_id"),
+		AuthorID:  grpcString(req, "author_id"),
+		Content:   grpcString(req, "content"),
+		SendAt:    grpcTime(req, "send_at"),
+		TTS:       grpcBool(req, "tts"),
+		Silent:    grpcBool(req, "silent"),
+	})
+	if err != nil {
+		return nil, status.Error(grpcCode(code), err.Error())
+	}
+	return g.schedule(sch), nil
+}
+
+func (g *grpcScheduler) list(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+	list, err := listAPISchedules(grpcString(req, "guild_id"), grpcString(req, "author_id"))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := g.newMessage("ListResponse")
+	field := resp.Descriptor().Fields().ByName("schedules")
+	items := resp.Mutable(field).List()
+	for _, sch := range list {
+		items.Append(protoreflect.ValueOfMessage(g.schedule(sch)))
+	}
+	return resp, nil
+}
+
+func (g *grpcScheduler) cancel(ctx context.Context, req *dynamicpb.Message) (proto.Message, error) {
+	err := cancelAPISchedule(g.session, grpcString(req, "id"))
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return g.newMessage("CancelResponse"), nil
+}
+
+// watch streams the events of the schedules until the client goes away.
+func (g *grpcScheduler) watch(srv any, stream grpc.ServerStream) error {
+	req := g.newMessage("WatchRequest")
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	guildID := grpcString(req, "guild_id")
+	events, stop := subscribe()
+	defer stop()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			// the reminders of the members are not part of the guild
+			if guildID != "" && (event.Schedule.GuildID != guildID || !event.Schedule.visibleTo("")) {
+				continue
+			}
+			msg := g.newMessage("Event")
+			fields := msg.Descriptor().Fields()
+			msg.Set(fields.ByName("action"), protoreflect.ValueOfString(event.Action))
+			msg.Set(fields.ByName("user_id"), protoreflect.ValueOfString(event.UserID))
+			msg.Set(fields.ByName("schedule"), protoreflect.ValueOfMessage(g.schedule(event.Schedule)))
+			msg.Set(fields.ByName("time"), protoreflect.ValueOfMessage(timestamppb.New(event.Time).ProtoReflect()))
+			if err := stream.SendMsg(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// schedule returns the Schedule message of sch.
+func (g *grpcScheduler) schedule(sch *Schedule) *dynamicpb.Message {
+	msg := g.newMessage("Schedule")
+	fields := msg.Descriptor().Fields()
+	msg.Set(fields.ByName("id"), protoreflect.ValueOfString(sch.ID))
+	msg.Set(fields.ByName("guild_id"), protoreflect.ValueOfString(sch.GuildID))
+	msg.Set(fields.ByName("channel_id"), protoreflect.ValueOfString(sch.ChannelID))
+	msg.Set(fields.ByName("author_id"), protoreflect.ValueOfString(sch.AuthorID))
+	msg.Set(fields.ByName("content"), protoreflect.ValueOfString(sch.Content))
+	msg.Set(fields.ByName("send_at"), protoreflect.ValueOfMessage(timestamppb.New(sch.SendAt).ProtoReflect()))
+	msg.Set(fields.ByName("awaiting_approval"), protoreflect.ValueOfBool(sch.AwaitingApproval))
+	return msg
+}
+
+func grpcString(msg *dynamicpb.Message, name protoreflect.Name) string {
+	return msg.Get(msg.Descriptor().Fields().ByName(name)).String()
+}
+
+func grpcBool(msg *dynamicpb.Message, name protoreflect.Name) bool {
+	return msg.Get(msg.Descriptor().Fields().ByName(name)).Bool()
+}
+
+// grpcTime reads a google.protobuf.Timestamp field, the zero time when it is
+// not set.
+func grpcTime(msg *dynamicpb.Message, name protoreflect.Name) time.Time {
+	field := msg.Descriptor().Fields().ByName(name)
+	if !msg.Has(field) {
+		return time.Time{}
+	}
+	ts := msg.Get(field).Message()
+	fields := ts.Descriptor().Fields()
+	return time.Unix(ts.Get(fields.ByName("seconds")).Int(), ts.Get(fields.ByName("nanos")).Int())
+}
+
+// grpcCode returns the gRPC code of an HTTP status of the API.
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,121 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	protoComment = regexp.MustCompile(`//[^\n]*`)
+	protoBlock   = regexp.MustCompile(`(message|service)\s+(\w+)\s*\{([^}]*)\}`)
+	protoField   = regexp.MustCompile(`^(repeated\s+)?([\w.]+)\s+(\w+)\s*=\s*(\d+)$`)
+	protoMethod  = regexp.MustCompile(`^rpc\s+(\w+)\s*\(\s*(\w+)\s*\)\s*returns\s*\(\s*(stream\s+)?(\w+)\s*\)$`)
+	protoHeader  = regexp.MustCompile(`(syntax|package|import)\s*=?\s*"?([\w./]+)"?\s*;`)
+)
+
+// parseProtoFile reads the subset of the protobuf language used by
+// proto/sendlater.proto into the descriptor protoc would generate.
+func parseProtoFile(t *testing.T, source string) *descriptorpb.FileDescriptorProto {
+	source = protoComment.ReplaceAllString(source, "")
+	file := &descriptorpb.FileDescriptorProto{Name: proto.String("sendlater.proto")}
+	for _, match := range protoHeader.FindAllStringSubmatch(source, -1) {
+		switch match[1] {
+		case "syntax":
+			file.Syntax = proto.String(match[2])
+		case "package":
+			file.Package = proto.String(match[2])
+		case "import":
+			file.Dependency = append(file.Dependency, match[2])
+		}
+	}
+	for _, block := range protoBlock.FindAllStringSubmatch(source, -1) {
+		statements := []string{}
+		for _, statement := range strings.Split(block[3], ";") {
+			if statement = strings.Join(strings.Fields(statement), " "); statement != "" {
+				statements = append(statements, statement)
+			}
+		}
+		if block[1] == "service" {
+			service := &descriptorpb.ServiceDescriptorProto{Name: proto.String(block[2])}
+			for _, statement := range statements {
+				match := protoMethod.FindStringSubmatch(statement)
+				if match == nil {
+					t.Fatalf("cannot parse the method %q of %s", statement, block[2])
+				}
+				service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{
+					Name:            proto.String(match[1]),
+					InputType:       proto.String("." + file.GetPackage() + "." + match[2]),
+					OutputType:      proto.String("." + file.GetPackage() + "." + match[4]),
+					ServerStreaming: proto.Bool(match[3] != ""),
+				})
+			}
+			file.Service = append(file.Service, service)
+			continue
+		}
+		message := &descriptorpb.DescriptorProto{Name: proto.String(block[2])}
+		for _, statement := range statements {
+			match := protoField.FindStringSubmatch(statement)
+			if match == nil {
+				t.Fatalf("cannot parse the field %q of %s", statement, block[2])
+			}
+			number, _ := strconv.Atoi(match[4])
+			field := &descriptorpb.FieldDescriptorProto{
+				Name:   proto.String(match[3]),
+				Number: proto.Int32(int32(number)),
+				Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			}
+			if match[1] != "" {
+				field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			}
+			switch kind := match[2]; {
+			case kind == "string":
+				field.Type = descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+			case kind == "bool":
+				field.Type = descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum()
+			case strings.Contains(kind, "."):
+				field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+				field.TypeName = proto.String("." + kind)
+			default:
+				field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+				field.TypeName = proto.String("." + file.GetPackage() + "." + kind)
+			}
+			message.Field = append(message.Field, field)
+		}
+		file.MessageType = append(file.MessageType, message)
+	}
+	return file
+}
+
+// TestGRPCFile checks that grpcFile describes proto/sendlater.proto, the
+// file given to the clients.
+func TestGRPCFile(t *testing.T) {
+	source, err := os.ReadFile("proto/sendlater.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := parseProtoFile(t, string(source))
+	if got := grpcFile(); !proto.Equal(got, want) {
+		t.Errorf("grpcFile() differs from proto/sendlater.proto\ngot:\n%s\nwant:\n%s", prototext.Format(got), prototext.Format(want))
+	}
+}
@@ -0,0 +1,203 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"slices"
+	"strings"
+	"time"
+)
+
+// GuildConfig holds the settings of a guild. The zero value is the default
+// configuration.
+type GuildConfig struct {
+	// Timezone is the IANA name of the time zone used to read the times
+	// given by the members. Default: the time zone of the bot.
+	Timezone string `json:"timezone,omitempty"`
+	// AuditChannelID is the channel where the audit entries are posted.
+	AuditChannelID string `json:"audit_channel_id,omitempty"`
+	// AllowedRoleIDs restricts scheduling to the members having one of the
+	// roles. Empty allows everyone, unless RequiredPermission is set.
+	AllowedRoleIDs []string `json:"allowed_role_ids,omitempty"`
+	// RequiredPermission allows the members having this permission, one of
+	// gatePermissions, to schedule messages. With AllowedRoleIDs, the members
+	// having one of the roles or the permission are allowed.
+	RequiredPermission string `json:"required_permission,omitempty"`
+	// QuietHours is a "HH:MM-HH:MM" range during which nothing can be sent.
+	QuietHours string `json:"quiet_hours,omitempty"`
+	// MaxPending is how many messages a member can have pending at once.
+	// Default: SENDLATER_MAX_PENDING.
+	MaxPending int `json:"max_pending,omitempty"`
+	// RateLimit is how many scheduling commands a member can use per minute.
+	// Default: SENDLATER_RATE_LIMIT.
+	RateLimit int `json:"rate_limit,omitempty"`
+	// ApprovalChannelID is where the moderators review the schedules of the
+	// other members, when FlagApprovalMode is enabled. Default: no review.
+	ApprovalChannelID string `json:"approval_channel_id,omitempty"`
+	// OwnerID is the owner of the guild, who receives its archive when the
+	// bot is removed.
+	OwnerID string `json:"owner_id,omitempty"`
+}
+
+// GuildConfig returns the configuration of the guild.
+func (st *Store) GuildConfig(guildID string) GuildConfig {
+	config := GuildConfig{}
+	st.view(func(data *storeData) {
+		config = data.Guilds[guildID]
+	})
+	config.AllowedRoleIDs = slices.Clone(config.AllowedRoleIDs)
+	return config
+}
+
+// HasGuildConfig reports whether the guild was ever configured.
+func (st *Store) HasGuildConfig(guildID string) bool {
+	found := false
+	st.view(func(data *storeData) {
+		_, found = data.Guilds[guildID]
+	})
+	return found
+}
+
+// UpdateGuildConfig applies fn to the configuration of the guild and saves it.
+func (st *Store) UpdateGuildConfig(guildID string, fn func(config *GuildConfig)) error {
+	return st.update(func(data *storeData) {
+		if data.Guilds == nil {
+			data.Guilds = map[string]GuildConfig{}
+		}
+		config := data.Guilds[guildID]
+		fn(&config)
+		data.Guilds[guildID] = config
+	})
+}
+
+// location returns the time zone of the guild.
+func (c GuildConfig) location() *time.Location {
+	if c.Timezone == "" {
+		return settings().loc
+	}
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		logger.Error("Error loading guild time zone", "error", err, "timezone", c.Timezone)
+		return settings().loc
+	}
+	return location
+}
+
+// gatePermission is a permission the guilds can require to schedule messages.
+type gatePermission struct {
+	Name       string
+	Label      string
+	Permission int64
+}
+
+var gatePermissions = []gatePermission{
+	{"manage_messages", "Manage Messages", discordgo.PermissionManageMessages},
+	{"mention_everyone", "Mention Everyone", discordgo.PermissionMentionEveryone},
+	{"manage_events", "Manage Events", discordgo.PermissionManageEvents},
+	{"manage_server", "Manage Server", discordgo.PermissionManageServer},
+}
+
+// findGatePermission returns the gate permission of the given name, or nil.
+func findGatePermission(name string) *gatePermission {
+	for n := range gatePermissions {
+		if gatePermissions[n].Name == name {
+			return &gatePermissions[n]
+		}
+	}
+	return nil
+}
+
+// guildAllowed reports whether the bot serves the guild: it must be one of
+// the allowed guilds when there are some, and not one of the denied ones. The
+// DMs are always served.
+func guildAllowed(guildID string) bool {
+	if guildID == "" {
+		return true
+	}
+	current := settings()
+	if len(current.allowedGuilds) > 0 && !slices.Contains(current.allowedGuilds, guildID) {
+		return false
+	}
+	return !slices.Contains(current.deniedGuilds, guildID)
+}
+
+// canSchedule reports whether the member is allowed to schedule messages.
+func (c GuildConfig) canSchedule(member *discordgo.Member) bool {
+	permission := findGatePermission(c.RequiredPermission)
+	if len(c.AllowedRoleIDs) == 0 && permission == nil {
+		return true
+	}
+	if member == nil {
+		return false
+	}
+	if permission != nil && member.Permissions&(permission.Permission|discordgo.PermissionAdministrator) != 0 {
+		return true
+	}
+	for _, role := range member.Roles {
+		if slices.Contains(c.AllowedRoleIDs, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// quota returns how many messages each member can have pending.
+func (c GuildConfig) quota() int {
+	if c.MaxPending == 0 {
+		return settings().maxPending
+	}
+	return c.MaxPending
+}
+
+// quotaLeft returns how many more messages the user can schedule in the guild.
+func (c GuildConfig) quotaLeft(guildID string, userID string) int {
+	return max(c.quota()-schedules.pendingCount(guildID, userID), 0)
+}
+
+// inQuietHours reports whether t falls in the quiet hours of the guild. The
+// range may span midnight.
+func (c GuildConfig) inQuietHours(t time.Time) bool {
+	start, end, err := parseQuietHours(c.QuietHours)
+	if err != nil {
+		return false
+	}
+	t = t.In(c.location())
+	minutes := t.Hour()*60 + t.Minute()
+	if start <= end {
+		return minutes >= start && minutes < end
+	}
+	return minutes >= start || minutes < end
+}
+
+// parseQuietHours returns the bounds of a "HH:MM-HH:MM" range in minutes
+// since midnight.
+func parseQuietHours(quietHours string) (int, int, error) {
+	from, to, found := strings.Cut(quietHours, "-")
+	if !found {
+		return 0, 0, errors.New("quiet hours must be HH:MM-HH:MM")
+	}
+	start, err := time.Parse("15:04", from)
+	if err != nil {
+		return 0, 0, errors.New("quiet hours must be HH:MM-HH:MM")
+	}
+	end, err := time.Parse("15:04", to)
+	if err != nil {
+		return 0, 0, errors.New("quiet hours must be HH:MM-HH:MM")
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
@@ -0,0 +1,101 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strings"
+)
+
+// Custom IDs of the buttons of the heads-up DM, followed by the ID of the
+// schedule, like "headsup_cancel:<id>".
+const (
+	headsUpPrefix  = "headsup_"
+	headsUpCancel  = headsUpPrefix + "cancel:"
+	headsUpSendNow = headsUpPrefix + "send:"
+)
+
+// sendHeadsUp tells the author in DM that the schedule is about to be sent,
+// with buttons to cancel it or send it right away.
+func sendHeadsUp(s *discordgo.Session, sch *Schedule) {
+	channel, err := s.UserChannelCreate(sch.AuthorID)
+	if err == nil {
+		_, err = s.ChannelMessageSendComplex(channel.ID, &discordgo.MessageSend{
+			Content:         "Heads-up: your message `" + sch.ID + "` for " + sch.target() + " is about to be sent.",
+			Embeds:          []*discordgo.MessageEmbed{scheduledEmbed("Message about to be sent", sch)},
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Cancel",
+						Style:    discordgo.DangerButton,
+						CustomID: headsUpCancel + sch.ID,
+					},
+					discordgo.Button{
+						Label:    "Send now",
+						Style:    discordgo.PrimaryButton,
+						CustomID: headsUpSendNow + sch.ID,
+					},
+				}},
+			},
+		})
+	}
+	if err != nil {
+		logger.Error("Error sending heads-up", "error", err, "id", sch.ID, "author", sch.AuthorID)
+		return
+	}
+	logger.Info("Heads-up sent", "id", sch.ID, "author", sch.AuthorID)
+}
+
+// handleHeadsUpComponent cancels or sends right away the schedule of the
+// heads-up, and replaces the buttons with what was done.
+func handleHeadsUpComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	customID := i.MessageComponentData().CustomID
+	user := interactionUser(i)
+
+	id := ""
+	sendNow := false
+	if after, found := strings.CutPrefix(customID, headsUpSendNow); found {
+		id = after
+		sendNow = true
+	} else {
+		id = strings.TrimPrefix(customID, headsUpCancel)
+	}
+
+	outcome := ""
+	sch := schedules.get(id)
+	if sch == nil || sch.AuthorID != user.ID || !schedules.remove(sch) {
+		outcome = "This message is not pending anymore."
+	} else if sendNow {
+		sendQueues.run(sch.ChannelID, func() { dispatch(s, sch) })
+		outcome = "Sending it now."
+	} else {
+		audit(s, AuditCancelled, user.ID, sch)
+		outcome = "Message cancelled!"
+	}
+	logger.Info("Heads-up answered", "id", id, "user", user.ID, "sendNow", sendNow)
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    truncate(i.Message.Content+"\n"+outcome, maxMessageLength),
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		logger.Error("Error responding to interaction", "error", err)
+	}
+}
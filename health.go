@@ -0,0 +1,124 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// heartbeatTimeout is how long the gateway can go without acknowledging a
+// heartbeat before the bot is reported dead. discordgo reconnects on its own
+// after a few missed heartbeats, so this leaves it time to do so.
+const heartbeatTimeout = 5 * time.Minute
+
+// serveHealth serves /healthz and /readyz on addr in the background, unless
+// addr is empty. /healthz fails when the gateway connection is dead, so that
+// the bot gets restarted, and /readyz also fails while the session is not
+// ready or the store cannot be written.
+func serveHealth(s *discordgo.Session, addr string) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, checkGateway(s, time.Now()))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		err := checkGateway(s, time.Now())
+		if err == nil {
+			err = checkReady(s)
+		}
+		writeHealth(w, err)
+	})
+	go func() {
+		logger.Info("Serving health endpoints", "address", addr)
+		err := http.ListenAndServe(addr, mux)
+		logger.Error("Health endpoints stopped", "error", err, "address", addr)
+	}()
+}
+
+// writeHealth answers ok, or the reason of the failure with a 503 status.
+func writeHealth(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// checkGateway returns an error if the gateway didn't acknowledge a
+// heartbeat for heartbeatTimeout.
+func checkGateway(s *discordgo.Session, now time.Time) error {
+	s.RLock()
+	last := s.LastHeartbeatAck
+	s.RUnlock()
+	if last.IsZero() {
+		return errors.New("no heartbeat acknowledged by the gateway yet")
+	}
+	if late := now.Sub(last); late > heartbeatTimeout {
+		return errors.New("no heartbeat acknowledged by the gateway for " + late.Round(time.Second).String())
+	}
+	return nil
+}
+
+// checkReady returns an error if the session is not ready to handle the
+// interactions or if the last write of the store failed.
+func checkReady(s *discordgo.Session) error {
+	s.RLock()
+	ready := s.DataReady
+	s.RUnlock()
+	if !ready {
+		return errors.New("the Discord session is not ready")
+	}
+	if err := store.lastError(); err != nil {
+		return errors.New("the store cannot be written: " + err.Error())
+	}
+	return nil
+}
+
+// runHealthcheck queries the /healthz endpoint of the bot running on addr and
+// returns the exit code of the check, for the HEALTHCHECK of images without
+// curl or wget.
+func runHealthcheck(addr string) int {
+	if addr == "" {
+		logger.Error("No health address, set SENDLATER_HEALTH_ADDR")
+		return 1
+	}
+	// an address like :8080 listens on every interface, including localhost
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://" + addr + "/healthz")
+	if err != nil {
+		logger.Error("Health check failed", "error", err)
+		return 1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		logger.Error("Health check failed", "status", resp.Status, "reason", strings.TrimSpace(string(body)))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+)
+
+func handleHelp(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	config := store.GuildConfig(i.GuildID)
+	respondEphemeralEmbed(s, i, helpEmbed(config))
+}
+
+// helpEmbed documents the commands with the settings of the guild.
+func helpEmbed(config GuildConfig) *discordgo.MessageEmbed {
+	limits := []string{
+		"- Messages: 2000 characters, longer text attachments are uploaded as a file",
+		"- Pending messages per member: " + strconv.Itoa(config.quota()),
+		"- Scheduling commands per member and minute: " + strconv.Itoa(config.rateLimit()),
+	}
+	bounds := settings()
+	if bounds.minDelay != 0 {
+		limits = append(limits, "- At least "+formatDelay(bounds.minDelay)+" in advance")
+	}
+	if bounds.maxHorizon != 0 {
+		limits = append(limits, "- At most "+formatDelay(bounds.maxHorizon)+" in advance")
+	}
+	if config.QuietHours != "" {
+		limits = append(limits, "- No messages during the quiet hours: "+config.QuietHours)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       "How to use /sendlater",
+		Description: "Schedule a message now, and the bot sends it at the time you chose.",
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:  "Scheduling a message",
+				Value: "`/sendlater schedule time:18:30 message:Hello!` sends `Hello!` in this channel today at 18:30.\nAdd `date:` for another day, `channel:` for another channel, or an `attachment:` for a text on several lines, an embed (JSON) or a file. `/sendlater compose` opens a form for messages on several lines, and `/remindme` reminds you of a note in DM.",
+			},
+			{
+				Name:  "Time and date",
+				Value: "- `time` is `HH:MM` on 24 hours, like `09:05` or `18:30`, suggestions are shown as you type\n- `date` is `dd/mm/yyyy`, like `31/12/2025` for December 31st, today by default\n- They are read in the time zone of the server: " + config.location().String() + "\n- `/sendlater check` shows when a time would be sent, without scheduling",
+			},
+			{
+				Name:  "Useful options",
+				Value: "- `preview`: check how the message looks before scheduling it\n- `mentions`: who is pinged, only the users by default\n- `silent`, `spoiler`, `tts`, `pin`, `delete_after`: how the message is sent\n- `dm`: send it to yourself as a reminder",
+			},
+			{
+				Name:  "Managing your messages",
+				Value: "- `/sendlater agenda`: the messages of the next 7 days, with their IDs\n- `/sendlater update`: change the message or time of a pending message\n- `/sendlater cancel`: cancel a pending message\n- `/sendlater edit`, `delete`, `react`, `poll`: schedule edits, deletions, reactions and polls",
+			},
+			{
+				Name:  "Limits",
+				Value: strings.Join(limits, "\n"),
+			},
+		},
+	}
+}
@@ -0,0 +1,383 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icalTimeFormat is the UTC date-time format of RFC 5545.
+const icalTimeFormat = "20060102T150405Z"
+
+func handleExportICal(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" {
+		respond(s, i, "The export is only available in a server")
+		return
+	}
+	list := schedules.shared(i.GuildID, interactionUser(i).ID)
+	logger.Info("Exporting schedules as iCalendar", "guild", i.GuildID, "count", len(list))
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "Here are the messages scheduled in this server",
+			Flags:   replyFlags(),
+			Files: []*discordgo.File{
+				{
+					Name:        "sendlater.ics",
+					ContentType: "text/calendar",
+					Reader:      strings.NewReader(encodeICal(list, time.Now())),
+				},
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error responding to interaction", "error", err)
+	}
+}
+
+// encodeICal writes the schedules as an iCalendar file with one event each.
+func encodeICal(list []*Schedule, now time.Time) string {
+	var b strings.Builder
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:-//send-later-discord-bot//EN")
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	for _, sch := range list {
+		writeICalLine(&b, "BEGIN:VEVENT")
+		writeICalLine(&b, "UID:"+sch.ID+"@send-later-discord-bot")
+		writeICalLine(&b, "DTSTAMP:"+now.UTC().Format(icalTimeFormat))
+		writeICalLine(&b, "DTSTART:"+sch.SendAt.UTC().Format(icalTimeFormat))
+		writeICalLine(&b, "DURATION:PT15M")
+		writeICalLine(&b, "SUMMARY:"+escapeICalText(icalSummary(sch)))
+		writeICalLine(&b, "DESCRIPTION:"+escapeICalText(sch.preview()))
+		writeICalLine(&b, "END:VEVENT")
+	}
+	writeICalLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// icalSummary describes where the schedule is sent.
+func icalSummary(sch *Schedule) string {
+	if sch.DM {
+		return "Message in DM"
+	}
+	return "Message in #" + sch.ChannelName
+}
+
+// writeICalLine writes a content line, folded at 75 octets as required by the
+// RFC, without splitting UTF-8 sequences.
+func writeICalLine(b *strings.Builder, line string) {
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escapeICalText escapes a TEXT property value.
+func escapeICalText(text string) string {
+	return icalEscaper.Replace(text)
+}
+
+// Limits of an import, so that a calendar with endless recurrences does not
+// flood the scheduler.
+const (
+	icalImportHorizon = 90 * 24 * time.Hour
+	icalImportMax     = 100
+)
+
+// icalEvent is the part of a VEVENT that can be scheduled.
+type icalEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	RRule       string
+}
+
+// content returns the message to send for the event.
+func (e icalEvent) content() string {
+	if e.Summary != "" && e.Description != "" {
+		return "**" + e.Summary + "**\n" + e.Description
+	}
+	return e.Summary + e.Description
+}
+
+// occurrences returns the start times of the event between from and until.
+// Recurrences are only expanded when recurring is set and the rule uses FREQ,
+// INTERVAL, COUNT and UNTIL, other rule parts are ignored.
+func (e icalEvent) occurrences(from time.Time, until time.Time, recurring bool) []time.Time {
+	times := []time.Time{}
+	if !recurring || e.RRule == "" {
+		if !e.Start.Before(from) && e.Start.Before(until) {
+			times = append(times, e.Start)
+		}
+		return times
+	}
+
+	freq := ""
+	interval := 1
+	count := 0
+	end := until
+	for _, part := range strings.Split(e.RRule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "FREQ":
+			freq = value
+		case "INTERVAL":
+			if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				interval = n
+			}
+		case "COUNT":
+			if n, err := strconv.Atoi(value); err == nil {
+				count = n
+			}
+		case "UNTIL":
+			if t, err := parseICalTime("", value); err == nil && t.Before(end) {
+				end = t.Add(time.Second)
+			}
+		}
+	}
+
+	t := e.Start
+	for n := 0; t.Before(end) && len(times) < icalImportMax; n++ {
+		if count > 0 && n >= count {
+			break
+		}
+		if !t.Before(from) {
+			times = append(times, t)
+		}
+		switch freq {
+		case "DAILY":
+			t = e.Start.AddDate(0, 0, (n+1)*interval)
+		case "WEEKLY":
+			t = e.Start.AddDate(0, 0, 7*(n+1)*interval)
+		case "MONTHLY":
+			t = e.Start.AddDate(0, (n+1)*interval, 0)
+		case "YEARLY":
+			t = e.Start.AddDate((n+1)*interval, 0, 0)
+		default:
+			return times
+		}
+	}
+	return times
+}
+
+// decodeICal returns the events of an iCalendar file. Events without a start
+// or without a summary and a description are reported as errors, the other
+// events are still returned.
+func decodeICal(content string) ([]icalEvent, []error) {
+	// unfold the content lines
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\n ", "")
+	content = strings.ReplaceAll(content, "\n\t", "")
+
+	events := []icalEvent{}
+	errs := []error{}
+	var event *icalEvent
+	for n, line := range strings.Split(content, "\n") {
+		nameAndParams, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		name, params, _ := strings.Cut(nameAndParams, ";")
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if value == "VEVENT" {
+				event = &icalEvent{}
+			}
+		case "END":
+			if value != "VEVENT" || event == nil {
+				continue
+			}
+			if event.Start.IsZero() {
+				errs = append(errs, errors.New("event ending on line "+strconv.Itoa(n+1)+" has no valid DTSTART"))
+			} else if event.content() == "" {
+				errs = append(errs, errors.New("event ending on line "+strconv.Itoa(n+1)+" has no SUMMARY nor DESCRIPTION"))
+			} else {
+				events = append(events, *event)
+			}
+			event = nil
+		case "SUMMARY":
+			if event != nil {
+				event.Summary = unescapeICalText(value)
+			}
+		case "DESCRIPTION":
+			if event != nil {
+				event.Description = unescapeICalText(value)
+			}
+		case "UID":
+			if event != nil {
+				event.UID = value
+			}
+		case "LOCATION":
+			if event != nil {
+				event.Location = unescapeICalText(value)
+			}
+		case "RRULE":
+			if event != nil {
+				event.RRule = strings.ToUpper(value)
+			}
+		case "DTSTART":
+			if event == nil {
+				continue
+			}
+			start, err := parseICalTime(params, value)
+			if err != nil {
+				errs = append(errs, errors.New("line "+strconv.Itoa(n+1)+": "+err.Error()))
+				continue
+			}
+			event.Start = start
+		}
+	}
+	return events, errs
+}
+
+// parseICalTime parses a DATE or DATE-TIME value. Times without a time zone
+// are in the local time zone of the bot, and dates start at midnight.
+func parseICalTime(params string, value string) (time.Time, error) {
+	location := settings().loc
+	for _, param := range strings.Split(params, ";") {
+		key, tzid, _ := strings.Cut(param, "=")
+		if strings.ToUpper(key) != "TZID" {
+			continue
+		}
+		var err error
+		location, err = time.LoadLocation(strings.Trim(tzid, `"`))
+		if err != nil {
+			return time.Time{}, errors.New("unknown time zone " + tzid)
+		}
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(icalTimeFormat, value)
+	}
+	if len(value) == len("20060102") {
+		return time.ParseInLocation("20060102", value, location)
+	}
+	return time.ParseInLocation("20060102T150405", value, location)
+}
+
+var icalUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// unescapeICalText reverts escapeICalText.
+func unescapeICalText(text string) string {
+	return icalUnescaper.Replace(text)
+}
+
+func handleImportICal(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config := store.GuildConfig(i.GuildID)
+
+	attachmentUrl := ""
+	recurring := false
+	var channel *discordgo.Channel
+	for _, option := range options {
+		if option.Name == "file" {
+			attachmentUrl = i.ApplicationCommandData().Resolved.Attachments[option.Value.(string)].URL
+		} else if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		} else if option.Name == "recurrences" {
+			recurring = option.BoolValue()
+		}
+	}
+
+	// downloading the calendar can take longer than the time allowed to respond
+	deferResponse(s, i, replyFlags())
+
+	// if the channel wasn't set by the user, we get the current channel
+	if channel == nil {
+		var err error
+		channel, err = s.Channel(i.ChannelID)
+		if err != nil {
+			logger.Error("Error importing calendar: ", "error", err)
+			respondError(s, i, "Error importing calendar", discordError("cannot get this channel", err))
+			return
+		}
+	}
+
+	err := checkChannel(channel, false)
+	if err != nil {
+		respondError(s, i, "Error importing calendar", err)
+		return
+	}
+
+	content, err := downloadAttachment(interactionContext(i), attachmentUrl)
+	if err != nil {
+		respondError(s, i, "Error importing calendar", err)
+		return
+	}
+	events, errs := decodeICal(content)
+
+	// we only schedule the events to come, up to the import limits
+	now := time.Now()
+	author := interactionUser(i)
+	limit := min(icalImportMax, config.quotaLeft(i.GuildID, author.ID))
+	until := now.Add(icalImportHorizon)
+	if maxHorizon := settings().maxHorizon; maxHorizon != 0 && maxHorizon < icalImportHorizon {
+		until = now.Add(maxHorizon)
+	}
+	count := 0
+events:
+	for _, event := range events {
+		err := checkCanPost(s, author.ID, channel, event.content(), nil)
+		if err != nil {
+			errs = append(errs, errors.New(event.Summary+": "+err.Error()))
+			continue
+		}
+		for _, start := range event.occurrences(now, until, recurring) {
+			if count == limit {
+				errs = append(errs, errors.New("only the first "+strconv.Itoa(limit)+" messages were imported, the limit of the import or of your pending messages"))
+				break events
+			}
+			if config.inQuietHours(start) {
+				errs = append(errs, errors.New(event.Summary+" at "+start.Format("02/01/2006 15:04")+" is during the quiet hours of the server"))
+				continue
+			}
+			if err := checkSendBounds(start, now); err != nil {
+				errs = append(errs, errors.New(event.Summary+" at "+start.Format("02/01/2006 15:04")+": "+err.Error()))
+				continue
+			}
+			startSchedule(s, &Schedule{
+				GuildID:     channel.GuildID,
+				ChannelID:   channel.ID,
+				ChannelName: channel.Name,
+				AuthorID:    author.ID,
+				Content:     event.content(),
+				SendAt:      start,
+			})
+			count++
+		}
+	}
+	logger.Info("Calendar imported", "count", count, "errors", len(errs), "channel", channel.Name)
+
+	reply := "Imported " + strconv.Itoa(count) + " messages"
+	for _, err := range errs {
+		reply += "\n- " + err.Error()
+	}
+	respond(s, i, truncate(reply, 2000))
+}
@@ -0,0 +1,267 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importMax is the maximum number of rows of an import.
+const importMax = 100
+
+// importRow is a message to schedule from an import file.
+type importRow struct {
+	// Line is where the row is in the file, to report its errors.
+	Line    string `json:"-"`
+	Content string `json:"content"`
+	// Date is dd/mm/yyyy, today by default, and Time is HH:MM, both in the
+	// time zone of the guild.
+	Date string `json:"date"`
+	Time string `json:"time"`
+	// Channel is a mention, an ID or a name, the default channel of the
+	// import when empty.
+	Channel string `json:"channel"`
+}
+
+// decodeImport reads the rows of a JSON array of objects, or of a CSV file
+// whose header names the columns content, date, time and channel.
+func decodeImport(name string, data string) ([]importRow, error) {
+	// the spreadsheets often start their CSV exports with a byte order mark
+	data = strings.TrimPrefix(data, "\ufeff")
+	if strings.HasSuffix(strings.ToLower(name), ".json") || strings.HasPrefix(strings.TrimSpace(data), "[") {
+		rows := []importRow{}
+		err := json.Unmarshal([]byte(data), &rows)
+		if err != nil {
+			return nil, errors.New("the JSON file must be an array of objects with content, date, time and channel: " + err.Error())
+		}
+		for n := range rows {
+			rows[n].Line = "item " + strconv.Itoa(n+1)
+		}
+		return rows, nil
+	}
+
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("the CSV file must start with a header naming its columns: " + err.Error())
+	}
+	columns := map[string]int{}
+	for n, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = n
+	}
+	if _, found := columns["content"]; !found {
+		return nil, errors.New("the CSV file has no content column")
+	}
+	if _, found := columns["time"]; !found {
+		return nil, errors.New("the CSV file has no time column")
+	}
+	field := func(record []string, column string) string {
+		n, found := columns[column]
+		if !found || n >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[n])
+	}
+	rows := []importRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			return nil, errors.New("the CSV file is invalid: " + err.Error())
+		}
+		rows = append(rows, importRow{
+			Line:    "line " + strconv.Itoa(line),
+			Content: field(record, "content"),
+			Date:    field(record, "date"),
+			Time:    field(record, "time"),
+			Channel: field(record, "channel"),
+		})
+	}
+	return rows, nil
+}
+
+// importChannel returns the channel of the guild given by mention, ID or
+// name.
+func importChannel(s *discordgo.Session, guildID string, ref string) (*discordgo.Channel, error) {
+	if ids, err := parseLink(ref); err == nil {
+		channel, err := s.Channel(ids[len(ids)-1])
+		if err != nil {
+			return nil, errors.New("unknown channel " + ref)
+		}
+		if channel.GuildID != guildID {
+			return nil, errors.New("the channel " + ref + " is not in this server")
+		}
+		return channel, nil
+	}
+	guild, err := s.State.Guild(guildID)
+	if err != nil {
+		return nil, errors.New("Error getting the channels of the server: " + err.Error())
+	}
+	name := strings.TrimPrefix(ref, "#")
+	for _, channel := range guild.Channels {
+		if channel.Name == name {
+			return channel, nil
+		}
+	}
+	return nil, errors.New("no channel named #" + name + " in this server")
+}
+
+// validateImport returns the schedules of the rows, and the errors of the
+// invalid ones. Nothing should be scheduled when there are errors.
+func validateImport(s *discordgo.Session, rows []importRow, guildID string, authorID string, defaultChannel *discordgo.Channel, config GuildConfig) ([]*Schedule, []error) {
+	list := []*Schedule{}
+	errs := []error{}
+	now := time.Now()
+	channels := map[string]*discordgo.Channel{}
+	for _, row := range rows {
+		rowErr := func(err error) {
+			errs = append(errs, errors.New(row.Line+": "+err.Error()))
+		}
+		if strings.TrimSpace(row.Content) == "" {
+			rowErr(errors.New("the content is empty"))
+			continue
+		}
+		if len([]rune(row.Content)) > maxMessageLength {
+			rowErr(errors.New("the content is longer than " + strconv.Itoa(maxMessageLength) + " characters"))
+			continue
+		}
+
+		channel := defaultChannel
+		if row.Channel != "" {
+			channel = channels[row.Channel]
+			if channel == nil {
+				var err error
+				channel, err = importChannel(s, guildID, row.Channel)
+				if err != nil {
+					rowErr(err)
+					continue
+				}
+				channels[row.Channel] = channel
+			}
+		}
+		err := checkChannel(channel, false)
+		if err == nil {
+			err = checkCanPost(s, authorID, channel, row.Content, nil)
+		}
+		if err != nil {
+			rowErr(err)
+			continue
+		}
+
+		date := row.Date
+		if date == "" {
+			date = now.In(config.location()).Format("02/01/2006")
+		}
+		sendAt, err := parseSendTime(date, row.Time, config)
+		if err != nil {
+			rowErr(err)
+			continue
+		}
+		if !sendAt.After(now) {
+			rowErr(errors.New(date + " " + row.Time + " is in the past"))
+			continue
+		}
+		list = append(list, &Schedule{
+			GuildID:     channel.GuildID,
+			ChannelID:   channel.ID,
+			ChannelName: channel.Name,
+			AuthorID:    authorID,
+			Content:     row.Content,
+			SendAt:      sendAt,
+		})
+	}
+	return list, errs
+}
+
+func handleImport(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config := store.GuildConfig(i.GuildID)
+
+	var attachment *discordgo.MessageAttachment
+	var channel *discordgo.Channel
+	for _, option := range options {
+		if option.Name == "file" {
+			attachment = i.ApplicationCommandData().Resolved.Attachments[option.Value.(string)]
+		} else if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		}
+	}
+
+	// downloading and checking the file can take longer than the time
+	// allowed to respond
+	deferResponse(s, i, replyFlags())
+
+	// if the channel wasn't set by the user, we get the current channel
+	if channel == nil {
+		var err error
+		channel, err = s.Channel(i.ChannelID)
+		if err != nil {
+			logger.Error("Error importing messages: ", "error", err)
+			respondError(s, i, "Error importing messages", discordError("cannot get this channel", err))
+			return
+		}
+	}
+
+	content, err := downloadAttachment(interactionContext(i), attachment.URL)
+	if err != nil {
+		respondError(s, i, "Error importing messages", err)
+		return
+	}
+	rows, err := decodeImport(attachment.Filename, content)
+	if err != nil {
+		respondError(s, i, "Error importing messages", err)
+		return
+	}
+	author := interactionUser(i)
+	limit := min(importMax, config.quotaLeft(i.GuildID, author.ID))
+	if len(rows) == 0 {
+		respond(s, i, "The file has no messages to import")
+		return
+	}
+	if len(rows) > limit {
+		respond(s, i, "The file has "+strconv.Itoa(len(rows))+" messages, but you can only schedule "+strconv.Itoa(limit)+" more, the limit of the import or of your pending messages. Nothing was imported.")
+		return
+	}
+
+	// nothing is scheduled unless every row is valid, so that the file can be
+	// fixed and imported again without duplicates
+	list, errs := validateImport(s, rows, i.GuildID, author.ID, channel, config)
+	if len(errs) > 0 {
+		logger.Info("Import refused", "rows", len(rows), "errors", len(errs), "user", author.ID)
+		reply := "Nothing was imported, fix these rows first:"
+		for _, err := range errs {
+			reply += "\n- " + err.Error()
+		}
+		respond(s, i, truncate(reply, maxMessageLength))
+		return
+	}
+	for _, sch := range list {
+		startSchedule(s, sch)
+	}
+	logger.Info("Messages imported", "count", len(list), "user", author.ID)
+	respond(s, i, "Imported "+strconv.Itoa(len(list))+" messages, see them with /sendlater mine")
+}
@@ -0,0 +1,51 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseLink returns the IDs of a Discord link like
+// https://discord.com/channels/<guild>/<channel>/<message>, or of a plain
+// ID or <#channel> mention, which are returned as the only element.
+func parseLink(link string) ([]string, error) {
+	link = strings.TrimSpace(link)
+	link = strings.TrimSuffix(strings.TrimPrefix(link, "<#"), ">")
+	if isSnowflake(link) {
+		return []string{link}, nil
+	}
+
+	_, path, found := strings.Cut(link, "/channels/")
+	if !found {
+		return nil, &userError{Code: codeInvalidLink, Message: "not a Discord link nor an ID: " + link, Hint: "Copy the link from the menu of the channel or the message."}
+	}
+	ids := strings.Split(strings.Trim(path, "/"), "/")
+	for _, id := range ids {
+		// the guild is @me in the links to DMs
+		if !isSnowflake(id) && id != "@me" {
+			return nil, &userError{Code: codeInvalidLink, Message: "not a Discord link: " + link, Hint: "Copy the link from the menu of the channel or the message."}
+		}
+	}
+	return ids, nil
+}
+
+// isSnowflake reports whether id looks like a Discord ID.
+func isSnowflake(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil && id != ""
+}
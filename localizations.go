@@ -0,0 +1,577 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// localization is the name and the description of the command, a subcommand
+// or an option in a language.
+type localization struct {
+	Name        string
+	Description string
+}
+
+// localizations translate the command, keyed by "" for the command itself,
+// by subcommand, or by subcommand.option. The other commands are keyed by
+// their name and by command.option. The commands keep their name so that it
+// is the same for everyone.
+var localizations = map[string]map[discordgo.Locale]localization{
+	"": {
+		discordgo.French: {"", "Programme des messages à envoyer plus tard"},
+		discordgo.German: {"", "Plant Nachrichten, die später gesendet werden"},
+	},
+
+	"schedule": {
+		discordgo.French: {"programmer", "Programme un message (une ligne) ou une pièce jointe (plusieurs lignes) à envoyer plus tard"},
+		discordgo.German: {"planen", "Plant eine Nachricht (eine Zeile) oder einen Anhang (mehrere Zeilen) zum späteren Senden"},
+	},
+	"schedule.time": {
+		discordgo.French: {"heure", "L'heure d'envoi du message (HH:MM)"},
+		discordgo.German: {"uhrzeit", "Die Uhrzeit, zu der die Nachricht gesendet wird (HH:MM)"},
+	},
+	"schedule.message": {
+		discordgo.French: {"message", "Le message à envoyer (une ligne)"},
+		discordgo.German: {"nachricht", "Die zu sendende Nachricht (eine Zeile)"},
+	},
+	"schedule.attachment": {
+		discordgo.French: {"pièce_jointe", "Le message à envoyer (plusieurs lignes en texte, un embed en JSON, ou un fichier)"},
+		discordgo.German: {"anhang", "Die zu sendende Nachricht (mehrere Zeilen als Text, ein Embed als JSON, oder eine Datei)"},
+	},
+	"schedule.date": {
+		discordgo.French: {"date", "[Facultatif] La date d'envoi du message (jj/mm/aaaa). Par défaut : aujourd'hui"},
+		discordgo.German: {"datum", "[Optional] Das Datum, an dem die Nachricht gesendet wird (TT/MM/JJJJ). Standard: heute"},
+	},
+	"schedule.channel": {
+		discordgo.French: {"salon", "[Facultatif] Le salon où envoyer le message. Par défaut : le salon actuel"},
+		discordgo.German: {"kanal", "[Optional] Der Kanal, in den die Nachricht gesendet wird. Standard: aktueller Kanal"},
+	},
+	"schedule.tts": {
+		discordgo.French: {"tts", "[Facultatif] Lire le message à voix haute par synthèse vocale. Par défaut : non"},
+		discordgo.German: {"tts", "[Optional] Die Nachricht per Sprachausgabe vorlesen. Standard: nein"},
+	},
+	"schedule.silent": {
+		discordgo.French: {"silencieux", "[Facultatif] Envoyer sans notifications push ni bureau. Par défaut : non"},
+		discordgo.German: {"lautlos", "[Optional] Ohne Push- und Desktop-Benachrichtigungen senden. Standard: nein"},
+	},
+	"schedule.spoiler": {
+		discordgo.French: {"spoiler", "[Facultatif] Cacher le message et ses fichiers derrière un spoiler. Par défaut : non"},
+		discordgo.German: {"spoiler", "[Optional] Die Nachricht und ihre Dateien hinter einem Spoiler verbergen. Standard: nein"},
+	},
+	"schedule.format": {
+		discordgo.French: {"format", "[Facultatif] Le rendu d'une pièce jointe texte. Par défaut : texte brut"},
+		discordgo.German: {"format", "[Optional] Wie ein Textanhang dargestellt wird. Standard: reiner Text"},
+	},
+	"schedule.language": {
+		discordgo.French: {"langage", "[Facultatif] Le langage du bloc de code, comme go ou json"},
+		discordgo.German: {"sprache", "[Optional] Die Sprache des Codeblocks, wie go oder json"},
+	},
+	"schedule.dm": {
+		discordgo.French: {"mp", "[Facultatif] Vous envoyer le message en MP au lieu d'un salon. Par défaut : non"},
+		discordgo.German: {"dn", "[Optional] Dir die Nachricht als DN statt in einen Kanal senden. Standard: nein"},
+	},
+	"schedule.thread": {
+		discordgo.French: {"fil", "[Facultatif] Lien ou ID d'un fil où envoyer le message, les fils archivés sont rouverts"},
+		discordgo.German: {"thread", "[Optional] Link oder ID eines Threads für die Nachricht, archivierte werden wieder geöffnet"},
+	},
+	"schedule.post_title": {
+		discordgo.French: {"titre_post", "[Facultatif] Titre du post des salons forum. Par défaut : première ligne du message"},
+		discordgo.German: {"beitragstitel", "[Optional] Titel des Beitrags in Forenkanälen. Standard: erste Zeile der Nachricht"},
+	},
+	"schedule.post_tags": {
+		discordgo.French: {"tags_post", "[Facultatif] Tags du post séparés par des virgules, pour les salons forum"},
+		discordgo.German: {"beitragstags", "[Optional] Durch Kommas getrennte Tags des Beitrags, für Forenkanäle"},
+	},
+	"schedule.more_channels": {
+		discordgo.French: {"autres_salons", "[Facultatif] D'autres salons recevant le même message, comme #events #news"},
+		discordgo.German: {"weitere_kanäle", "[Optional] Weitere Kanäle, die dieselbe Nachricht erhalten, wie #events #news"},
+	},
+	"schedule.crosspost": {
+		discordgo.French: {"publier", "[Facultatif] Publier le message aux serveurs suivant le salon d'annonces. Par défaut : non"},
+		discordgo.German: {"veröffentlichen", "[Optional] Die Nachricht an die Server veröffentlichen, die dem Ankündigungskanal folgen"},
+	},
+	"schedule.reply_to": {
+		discordgo.French: {"répondre_à", "[Facultatif] Lien ou ID d'un message auquel répondre"},
+		discordgo.German: {"antwort_auf", "[Optional] Link oder ID einer Nachricht, auf die geantwortet wird"},
+	},
+	"schedule.mentions": {
+		discordgo.French: {"mentions", "[Facultatif] Qui les mentions du message notifient. Par défaut : les utilisateurs"},
+		discordgo.German: {"erwähnungen", "[Optional] Wen die Erwähnungen der Nachricht anpingen. Standard: Benutzer"},
+	},
+	"schedule.sender": {
+		discordgo.French: {"expéditeur", "[Facultatif] Au nom de qui le message est envoyé. Par défaut : le bot"},
+		discordgo.German: {"absender", "[Optional] In wessen Namen die Nachricht gesendet wird. Standard: der Bot"},
+	},
+	"schedule.pin": {
+		discordgo.French: {"épingler", "[Facultatif] Épingler le message une fois envoyé. Par défaut : non épinglé"},
+		discordgo.German: {"anheften", "[Optional] Die Nachricht nach dem Senden anheften. Standard: nicht angeheftet"},
+	},
+	"schedule.delete_after": {
+		discordgo.French: {"supprimer_après", "[Facultatif] Supprimer le message ce délai après son envoi, comme 30m, 2h ou 1d"},
+		discordgo.German: {"löschen_nach", "[Optional] Die Nachricht so lange nach dem Senden löschen, wie 30m, 2h oder 1d"},
+	},
+	"schedule.discussion": {
+		discordgo.French: {"discussion", "[Facultatif] Ouvrir un fil de ce nom sur le message une fois envoyé"},
+		discordgo.German: {"diskussion", "[Optional] Nach dem Senden einen Thread mit diesem Namen zur Nachricht starten"},
+	},
+	"schedule.discussion_archive": {
+		discordgo.French: {"archivage_discussion", "[Facultatif] Archiver le fil de discussion après cette inactivité. Par défaut : 1 jour"},
+		discordgo.German: {"diskussion_archivieren", "[Optional] Den Diskussionsthread nach dieser Inaktivität archivieren. Standard: 1 Tag"},
+	},
+	"schedule.notify_before": {
+		discordgo.French: {"prévenir_avant", "[Facultatif] Vous prévenir en MP ce délai avant l'envoi, pour l'annuler ou l'envoyer. Ex : 10m, 1h"},
+		discordgo.German: {"vorher_benachrichtigen", "[Optional] Dich so lange vor dem Senden per DM benachrichtigen, wie 10m oder 1h"},
+	},
+	"schedule.preview": {
+		discordgo.French: {"aperçu", "[Facultatif] Montrer le rendu du message et demander confirmation avant. Par défaut : non"},
+		discordgo.German: {"vorschau", "[Optional] Die Nachricht anzeigen und vor dem Planen bestätigen lassen. Standard: nein"},
+	},
+
+	"compose": {
+		discordgo.French: {"rédiger", "Ouvre un éditeur pour écrire un message sur plusieurs lignes, puis le programme"},
+		discordgo.German: {"verfassen", "Öffnet einen Editor für eine mehrzeilige Nachricht und plant sie dann"},
+	},
+	"compose.channel": {
+		discordgo.French: {"salon", "[Facultatif] Le salon où envoyer le message. Par défaut : le salon actuel"},
+		discordgo.German: {"kanal", "[Optional] Der Kanal, in den die Nachricht gesendet wird. Standard: aktueller Kanal"},
+	},
+	"compose.repeat": {
+		discordgo.French: {"répéter", "[Facultatif] Renvoyer le message à chaque occurrence, à gérer avec /reminders"},
+		discordgo.German: {"wiederholen", "[Optional] Die Nachricht bei jedem Vorkommen erneut senden, verwaltet mit /reminders"},
+	},
+
+	"edit": {
+		discordgo.French: {"modifier", "Programme la modification d'un message envoyé par le bot"},
+		discordgo.German: {"bearbeiten", "Plant die Bearbeitung einer vom Bot gesendeten Nachricht"},
+	},
+	"edit.link": {
+		discordgo.French: {"lien", "Le lien ou l'ID du message à modifier"},
+		discordgo.German: {"link", "Der Link oder die ID der zu bearbeitenden Nachricht"},
+	},
+	"edit.message": {
+		discordgo.French: {"message", "Le nouveau contenu du message"},
+		discordgo.German: {"nachricht", "Der neue Inhalt der Nachricht"},
+	},
+	"edit.time": {
+		discordgo.French: {"heure", "L'heure de modification du message (HH:MM)"},
+		discordgo.German: {"uhrzeit", "Die Uhrzeit, zu der die Nachricht bearbeitet wird (HH:MM)"},
+	},
+	"edit.date": {
+		discordgo.French: {"date", "[Facultatif] La date de modification du message (jj/mm/aaaa). Par défaut : aujourd'hui"},
+		discordgo.German: {"datum", "[Optional] Das Datum, an dem die Nachricht bearbeitet wird (TT/MM/JJJJ). Standard: heute"},
+	},
+
+	"delete": {
+		discordgo.French: {"supprimer", "Programme la suppression d'un message"},
+		discordgo.German: {"löschen", "Plant das Löschen einer Nachricht"},
+	},
+	"delete.link": {
+		discordgo.French: {"lien", "Le lien ou l'ID du message à supprimer"},
+		discordgo.German: {"link", "Der Link oder die ID der zu löschenden Nachricht"},
+	},
+	"delete.time": {
+		discordgo.French: {"heure", "L'heure de suppression du message (HH:MM)"},
+		discordgo.German: {"uhrzeit", "Die Uhrzeit, zu der die Nachricht gelöscht wird (HH:MM)"},
+	},
+	"delete.date": {
+		discordgo.French: {"date", "[Facultatif] La date de suppression du message (jj/mm/aaaa). Par défaut : aujourd'hui"},
+		discordgo.German: {"datum", "[Optional] Das Datum, an dem die Nachricht gelöscht wird (TT/MM/JJJJ). Standard: heute"},
+	},
+
+	"react": {
+		discordgo.French: {"réagir", "Programme des réactions du bot sur un message"},
+		discordgo.German: {"reagieren", "Plant Reaktionen des Bots auf eine Nachricht"},
+	},
+	"react.link": {
+		discordgo.French: {"lien", "Le lien ou l'ID du message auquel réagir"},
+		discordgo.German: {"link", "Der Link oder die ID der Nachricht, auf die reagiert wird"},
+	},
+	"react.emojis": {
+		discordgo.French: {"emojis", "Les emojis des réactions, séparés par des espaces"},
+		discordgo.German: {"emojis", "Die Emojis der Reaktionen, durch Leerzeichen getrennt"},
+	},
+	"react.time": {
+		discordgo.French: {"heure", "L'heure d'ajout des réactions (HH:MM)"},
+		discordgo.German: {"uhrzeit", "Die Uhrzeit, zu der die Reaktionen hinzugefügt werden (HH:MM)"},
+	},
+	"react.date": {
+		discordgo.French: {"date", "[Facultatif] La date d'ajout des réactions (jj/mm/aaaa). Par défaut : aujourd'hui"},
+		discordgo.German: {"datum", "[Optional] Das Datum, an dem die Reaktionen hinzugefügt werden (TT/MM/JJJJ). Standard: heute"},
+	},
+
+	"update": {
+		discordgo.French: {"mettre_à_jour", "Change le contenu ou l'heure d'un message programmé"},
+		discordgo.German: {"aktualisieren", "Ändert den Inhalt oder die Uhrzeit einer geplanten Nachricht"},
+	},
+	"update.id": {
+		discordgo.French: {"id", "L'ID du message programmé, comme affiché par l'agenda"},
+		discordgo.German: {"id", "Die ID der geplanten Nachricht, wie in der Agenda angezeigt"},
+	},
+	"update.message": {
+		discordgo.French: {"message", "[Facultatif] Le nouveau message. Par défaut : inchangé"},
+		discordgo.German: {"nachricht", "[Optional] Die neue Nachricht. Standard: unverändert"},
+	},
+	"update.time": {
+		discordgo.French: {"heure", "[Facultatif] La nouvelle heure (HH:MM). Par défaut : inchangée"},
+		discordgo.German: {"uhrzeit", "[Optional] Die neue Uhrzeit (HH:MM). Standard: unverändert"},
+	},
+	"update.date": {
+		discordgo.French: {"date", "[Facultatif] La nouvelle date (jj/mm/aaaa). Par défaut : inchangée"},
+		discordgo.German: {"datum", "[Optional] Das neue Datum (TT/MM/JJJJ). Standard: unverändert"},
+	},
+
+	"poll": {
+		discordgo.French: {"sondage", "Programme un sondage Discord"},
+		discordgo.German: {"umfrage", "Plant eine Discord-Umfrage"},
+	},
+	"poll.question": {
+		discordgo.French: {"question", "La question du sondage"},
+		discordgo.German: {"frage", "Die Frage der Umfrage"},
+	},
+	"poll.answers": {
+		discordgo.French: {"réponses", "Les réponses, séparées par |, comme Oui | Non | Peut-être"},
+		discordgo.German: {"antworten", "Die Antworten, getrennt durch |, wie Ja | Nein | Vielleicht"},
+	},
+	"poll.time": {
+		discordgo.French: {"heure", "L'heure de création du sondage (HH:MM)"},
+		discordgo.German: {"uhrzeit", "Die Uhrzeit, zu der die Umfrage erstellt wird (HH:MM)"},
+	},
+	"poll.date": {
+		discordgo.French: {"date", "[Facultatif] La date de création du sondage (jj/mm/aaaa). Par défaut : aujourd'hui"},
+		discordgo.German: {"datum", "[Optional] Das Datum, an dem die Umfrage erstellt wird (TT/MM/JJJJ). Standard: heute"},
+	},
+	"poll.channel": {
+		discordgo.French: {"salon", "[Facultatif] Le salon où créer le sondage. Par défaut : le salon actuel"},
+		discordgo.German: {"kanal", "[Optional] Der Kanal, in dem die Umfrage erstellt wird. Standard: aktueller Kanal"},
+	},
+	"poll.duration": {
+		discordgo.French: {"durée", "[Facultatif] Combien d'heures le sondage est ouvert. Par défaut : 24"},
+		discordgo.German: {"dauer", "[Optional] Wie viele Stunden die Umfrage offen ist. Standard: 24"},
+	},
+	"poll.multi_select": {
+		discordgo.French: {"choix_multiple", "[Facultatif] Autoriser plusieurs réponses. Par défaut : non"},
+		discordgo.German: {"mehrfachauswahl", "[Optional] Mehrere Antworten erlauben. Standard: nein"},
+	},
+
+	"cancel": {
+		discordgo.French: {"annuler", "Annule un message programmé"},
+		discordgo.German: {"abbrechen", "Bricht eine geplante Nachricht ab"},
+	},
+	"cancel.id": {
+		discordgo.French: {"id", "L'ID du message programmé, comme affiché par l'agenda"},
+		discordgo.German: {"id", "Die ID der geplanten Nachricht, wie in der Agenda angezeigt"},
+	},
+
+	"countdown": {
+		discordgo.French: {"compte_à_rebours", "Publie un compte à rebours, remplacé par le message à son envoi"},
+		discordgo.German: {"countdown", "Veröffentlicht einen Countdown, der beim Senden durch die Nachricht ersetzt wird"},
+	},
+	"countdown.message": {
+		discordgo.French: {"message", "Le message à envoyer"},
+		discordgo.German: {"nachricht", "Die zu sendende Nachricht"},
+	},
+	"countdown.time": {
+		discordgo.French: {"heure", "L'heure d'envoi du message (HH:MM)"},
+		discordgo.German: {"uhrzeit", "Die Uhrzeit, zu der die Nachricht gesendet wird (HH:MM)"},
+	},
+	"countdown.date": {
+		discordgo.French: {"date", "[Facultatif] La date d'envoi du message (jj/mm/aaaa). Par défaut : aujourd'hui"},
+		discordgo.German: {"datum", "[Optional] Das Datum, an dem die Nachricht gesendet wird (TT/MM/JJJJ). Standard: heute"},
+	},
+	"countdown.channel": {
+		discordgo.French: {"salon", "[Facultatif] Le salon où envoyer le message. Par défaut : le salon actuel"},
+		discordgo.German: {"kanal", "[Optional] Der Kanal, in den die Nachricht gesendet wird. Standard: aktueller Kanal"},
+	},
+	"countdown.label": {
+		discordgo.French: {"libellé", "[Facultatif] Ce qu'annonce le compte à rebours. Par défaut : Annonce"},
+		discordgo.German: {"bezeichnung", "[Optional] Was der Countdown ankündigt. Standard: Ankündigung"},
+	},
+
+	"mine": {
+		discordgo.French: {"les_miens", "Liste les messages que vous avez programmés dans tous les serveurs"},
+		discordgo.German: {"meine", "Listet die Nachrichten auf, die du in allen Servern geplant hast"},
+	},
+
+	"campaign": {
+		discordgo.French: {"campagne", "Programme une suite de messages, chacun un certain temps après le début"},
+		discordgo.German: {"kampagne", "Plant eine Folge von Nachrichten, jede eine gewisse Zeit nach dem Start"},
+	},
+	"campaign.channel": {
+		discordgo.French: {"salon", "[Facultatif] Le salon où envoyer les messages. Par défaut : le salon actuel"},
+		discordgo.German: {"kanal", "[Optional] Der Kanal, in den die Nachrichten gesendet werden. Standard: aktueller Kanal"},
+	},
+	"campaigns": {
+		discordgo.French: {"campagnes", "Liste, met en pause, reprend ou annule les suites de messages du serveur"},
+		discordgo.German: {"kampagnen", "Listet, pausiert, setzt fort oder bricht die Nachrichtenfolgen des Servers ab"},
+	},
+	"campaigns.action": {
+		discordgo.French: {"action", "Ce qu'il faut faire"},
+		discordgo.German: {"aktion", "Was zu tun ist"},
+	},
+	"campaigns.id": {
+		discordgo.French: {"id", "[Facultatif] L'ID de la suite, sauf pour la liste"},
+		discordgo.German: {"id", "[Optional] Die ID der Folge, außer für die Liste"},
+	},
+
+	"check": {
+		discordgo.French: {"vérifier", "Montre quand un message serait envoyé, sans rien programmer"},
+		discordgo.German: {"prüfen", "Zeigt, wann eine Nachricht gesendet würde, ohne etwas zu planen"},
+	},
+	"check.time": {
+		discordgo.French: {"heure", "L'heure à vérifier (HH:MM)"},
+		discordgo.German: {"uhrzeit", "Die zu prüfende Uhrzeit (HH:MM)"},
+	},
+	"check.date": {
+		discordgo.French: {"date", "[Facultatif] La date à vérifier (jj/mm/aaaa). Par défaut : aujourd'hui"},
+		discordgo.German: {"datum", "[Optional] Das zu prüfende Datum (TT/MM/JJJJ). Standard: heute"},
+	},
+
+	"help": {
+		discordgo.French: {"aide", "Explique comment programmer des messages, avec les formats, options et limites"},
+		discordgo.German: {"hilfe", "Erklärt, wie Nachrichten geplant werden, mit Formaten, Optionen und Grenzen"},
+	},
+	"agenda": {
+		discordgo.French: {"agenda", "Affiche les messages programmés sur ce serveur pour les 7 prochains jours"},
+		discordgo.German: {"agenda", "Zeigt die auf diesem Server geplanten Nachrichten der nächsten 7 Tage"},
+	},
+	"export_ics": {
+		discordgo.French: {"exporter_ics", "Exporte les messages programmés sur ce serveur en fichier iCalendar (.ics)"},
+		discordgo.German: {"ics_exportieren", "Exportiert die auf diesem Server geplanten Nachrichten als iCalendar-Datei (.ics)"},
+	},
+
+	"import_ics": {
+		discordgo.French: {"importer_ics", "Programme les événements d'un fichier iCalendar (.ics), chacun envoyé à son début"},
+		discordgo.German: {"ics_importieren", "Plant die Termine einer iCalendar-Datei (.ics), jeder wird zu seinem Beginn gesendet"},
+	},
+	"import_ics.file": {
+		discordgo.French: {"fichier", "Le fichier iCalendar"},
+		discordgo.German: {"datei", "Die iCalendar-Datei"},
+	},
+	"import_ics.channel": {
+		discordgo.French: {"salon", "[Facultatif] Le salon où envoyer les messages. Par défaut : le salon actuel"},
+		discordgo.German: {"kanal", "[Optional] Der Kanal, in den die Nachrichten gesendet werden. Standard: aktueller Kanal"},
+	},
+	"import_ics.recurrences": {
+		discordgo.French: {"récurrences", "[Facultatif] Programmer les récurrences des 90 prochains jours. Par défaut : non"},
+		discordgo.German: {"wiederholungen", "[Optional] Die Wiederholungen der nächsten 90 Tage planen. Standard: nein"},
+	},
+
+	"import": {
+		discordgo.French: {"importer", "Programme les messages d'un fichier CSV ou JSON, avec contenu, date, heure et salon"},
+		discordgo.German: {"importieren", "Plant die Nachrichten einer CSV- oder JSON-Datei mit Inhalt, Datum, Uhrzeit und Kanal"},
+	},
+	"import.file": {
+		discordgo.French: {"fichier", "Le fichier CSV ou JSON"},
+		discordgo.German: {"datei", "Die CSV- oder JSON-Datei"},
+	},
+	"import.channel": {
+		discordgo.French: {"salon", "[Facultatif] Le salon des messages qui n'en ont pas. Par défaut : le salon actuel"},
+		discordgo.German: {"kanal", "[Optional] Der Kanal der Nachrichten ohne Kanal. Standard: aktueller Kanal"},
+	},
+
+	"backup": {
+		discordgo.French: {"sauvegarde", "[Admins] Exporte en JSON les messages programmés sur ce serveur, ou les restaure"},
+		discordgo.German: {"sicherung", "[Admins] Exportiert die geplanten Nachrichten dieses Servers als JSON oder stellt sie wieder her"},
+	},
+	"backup.action": {
+		discordgo.French: {"action", "Exporter ou restaurer les messages"},
+		discordgo.German: {"aktion", "Die Nachrichten exportieren oder wiederherstellen"},
+	},
+	"backup.file": {
+		discordgo.French: {"fichier", "[Facultatif] La sauvegarde à restaurer"},
+		discordgo.German: {"datei", "[Optional] Die wiederherzustellende Sicherung"},
+	},
+
+	"stats": {
+		discordgo.French: {"statistiques", "[Admins] Montre les messages en attente, envoyés et échoués de ce serveur"},
+		discordgo.German: {"statistik", "[Admins] Zeigt die ausstehenden, gesendeten und fehlgeschlagenen Nachrichten"},
+	},
+
+	"feed": {
+		discordgo.French: {"flux", "[Admins] Publie les nouveautés d'un flux RSS ou Atom dans un résumé quotidien"},
+		discordgo.German: {"feed", "[Admins] Postet die neuen Einträge eines RSS- oder Atom-Feeds täglich gesammelt"},
+	},
+	"feed.action": {
+		discordgo.French: {"action", "Que faire du flux"},
+		discordgo.German: {"aktion", "Was mit dem Feed geschehen soll"},
+	},
+	"feed.url": {
+		discordgo.French: {"url", "[Facultatif] L'URL du flux, pour l'ajouter ou le retirer"},
+		discordgo.German: {"url", "[Optional] Die URL des Feeds, um ihn hinzuzufügen oder zu entfernen"},
+	},
+	"feed.channel": {
+		discordgo.French: {"salon", "[Facultatif] Le salon du résumé. Par défaut : le salon actuel"},
+		discordgo.German: {"kanal", "[Optional] Der Kanal der Zusammenfassung. Standard: aktueller Kanal"},
+	},
+	"feed.digest_time": {
+		discordgo.French: {"heure_du_résumé", "[Facultatif] L'heure du résumé quotidien (HH:MM). Par défaut : 09:00"},
+		discordgo.German: {"uhrzeit", "[Optional] Die Uhrzeit der täglichen Zusammenfassung (HH:MM). Standard: 09:00"},
+	},
+
+	"audit": {
+		discordgo.French: {"audit", "[Admins] Exporte qui a programmé, annulé et envoyé quoi sur ce serveur"},
+		discordgo.German: {"audit", "[Admins] Exportiert, wer auf diesem Server was geplant, abgebrochen und gesendet hat"},
+	},
+	"audit.format": {
+		discordgo.French: {"format", "Le format de l'export"},
+		discordgo.German: {"format", "Das Format des Exports"},
+	},
+	"audit.from": {
+		discordgo.French: {"du", "[Facultatif] Le premier jour de l'export (jj/mm/aaaa). Par défaut : le premier enregistré"},
+		discordgo.German: {"von", "[Optional] Der erste Tag des Exports (TT/MM/JJJJ). Standard: erster erfasster Tag"},
+	},
+	"audit.to": {
+		discordgo.French: {"au", "[Facultatif] Le dernier jour de l'export (jj/mm/aaaa). Par défaut : aujourd'hui"},
+		discordgo.German: {"bis", "[Optional] Der letzte Tag des Exports (TT/MM/JJJJ). Standard: heute"},
+	},
+
+	"setup": {
+		discordgo.French: {"configurer", "[Admins] Configure le fuseau horaire, le salon d'audit, les rôles autorisés, etc."},
+		discordgo.German: {"einrichten", "[Admins] Richtet Zeitzone, Audit-Kanal, erlaubte Rollen usw. ein"},
+	},
+
+	"flag": {
+		discordgo.French: {"fonctionnalité", "[Propriétaires du bot] Active ou désactive une fonctionnalité expérimentale"},
+		discordgo.German: {"funktion", "[Bot-Besitzer] Aktiviert oder deaktiviert eine experimentelle Funktion"},
+	},
+	"flag.name": {
+		discordgo.French: {"nom", "La fonctionnalité"},
+		discordgo.German: {"name", "Die Funktion"},
+	},
+	"flag.enabled": {
+		discordgo.French: {"activée", "Si la fonctionnalité est activée"},
+		discordgo.German: {"aktiviert", "Ob die Funktion aktiviert ist"},
+	},
+	"flag.guild": {
+		discordgo.French: {"serveur", "[Facultatif] L'ID du serveur. Par défaut : le serveur actuel"},
+		discordgo.German: {"server", "[Optional] Die ID des Servers. Standard: aktueller Server"},
+	},
+
+	"broadcast": {
+		discordgo.French: {"diffuser", "[Propriétaires du bot] Programme un message dans le salon du même nom de chaque serveur"},
+		discordgo.German: {"rundsenden", "[Bot-Besitzer] Plant eine Nachricht im gleichnamigen Kanal jedes Servers"},
+	},
+	"broadcast.message": {
+		discordgo.French: {"message", "Le message à diffuser"},
+		discordgo.German: {"nachricht", "Die zu sendende Nachricht"},
+	},
+	"broadcast.time": {
+		discordgo.French: {"heure", "L'heure d'envoi du message (HH:MM)"},
+		discordgo.German: {"uhrzeit", "Die Uhrzeit, zu der die Nachricht gesendet wird (HH:MM)"},
+	},
+	"broadcast.channel_name": {
+		discordgo.French: {"nom_salon", "Le nom du salon dans chaque serveur, comme annonces"},
+		discordgo.German: {"kanalname", "Der Name des Kanals in jedem Server, wie ankündigungen"},
+	},
+	"broadcast.date": {
+		discordgo.French: {"date", "[Facultatif] La date d'envoi du message (jj/mm/aaaa). Par défaut : aujourd'hui"},
+		discordgo.German: {"datum", "[Optional] Das Datum, an dem die Nachricht gesendet wird (TT/MM/JJJJ). Standard: heute"},
+	},
+
+	"remindme": {
+		discordgo.French: {"", "Programme un rappel pour vous-même"},
+		discordgo.German: {"", "Plant eine Erinnerung für dich selbst"},
+	},
+	"remindme.note": {
+		discordgo.French: {"note", "Ce dont il faut vous rappeler"},
+		discordgo.German: {"notiz", "Woran du erinnert werden willst"},
+	},
+	"remindme.time": {
+		discordgo.French: {"heure", "L'heure du rappel (HH:MM)"},
+		discordgo.German: {"uhrzeit", "Die Uhrzeit der Erinnerung (HH:MM)"},
+	},
+	"remindme.date": {
+		discordgo.French: {"date", "[Facultatif] La date du rappel (jj/mm/aaaa). Par défaut : aujourd'hui"},
+		discordgo.German: {"datum", "[Optional] Das Datum der Erinnerung (TT/MM/JJJJ). Standard: heute"},
+	},
+	"remindme.here": {
+		discordgo.French: {"ici", "[Facultatif] Vous mentionner dans ce salon plutôt qu'en message privé"},
+		discordgo.German: {"hier", "[Optional] Dich in diesem Kanal erwähnen statt per Direktnachricht"},
+	},
+	"remindme.repeat": {
+		discordgo.French: {"répéter", "[Facultatif] Vous le rappeler à chaque occurrence, à gérer avec /reminders"},
+		discordgo.German: {"wiederholen", "[Optional] Dich bei jedem Vorkommen erinnern, verwaltet mit /reminders"},
+	},
+
+	"reminders": {
+		discordgo.French: {"", "Gère vos rappels récurrents"},
+		discordgo.German: {"", "Verwaltet deine wiederkehrenden Erinnerungen"},
+	},
+	"reminders.action": {
+		discordgo.French: {"action", "Ce qu'il faut faire"},
+		discordgo.German: {"aktion", "Was zu tun ist"},
+	},
+	"reminders.id": {
+		discordgo.French: {"id", "[Facultatif] L'ID du rappel, sauf pour la liste"},
+		discordgo.German: {"id", "[Optional] Die ID der Erinnerung, außer für die Liste"},
+	},
+
+	"failed": {
+		discordgo.French: {"", "[Admins] Liste, renvoie ou supprime les messages qui n'ont pas pu être envoyés"},
+		discordgo.German: {"", "[Admins] Listet, sendet erneut oder verwirft die nicht zugestellten Nachrichten"},
+	},
+	"failed.action": {
+		discordgo.French: {"action", "Ce qu'il faut faire"},
+		discordgo.German: {"aktion", "Was zu tun ist"},
+	},
+	"failed.id": {
+		discordgo.French: {"id", "[Facultatif] L'ID du message, sauf pour la liste"},
+		discordgo.German: {"id", "[Optional] Die ID der Nachricht, außer für die Liste"},
+	},
+}
+
+// localizeCommand sets the translations of the command, its subcommands and
+// their options. What isn't translated is shown in English.
+func localizeCommand(command *discordgo.ApplicationCommand) {
+	// the context menus have no description
+	if command.Type == discordgo.MessageApplicationCommand {
+		return
+	}
+	key := ""
+	if command.Name != "sendlater" {
+		key = command.Name
+	}
+	descriptions := map[discordgo.Locale]string{}
+	for locale, l := range localizations[key] {
+		descriptions[locale] = l.Description
+	}
+	command.DescriptionLocalizations = &descriptions
+	for _, subcommand := range command.Options {
+		// the options of the other commands are keyed by command.option
+		if subcommand.Type != discordgo.ApplicationCommandOptionSubCommand {
+			localizeOption(subcommand, command.Name+"."+subcommand.Name)
+			continue
+		}
+		localizeOption(subcommand, subcommand.Name)
+		for _, option := range subcommand.Options {
+			localizeOption(option, subcommand.Name+"."+option.Name)
+		}
+	}
+}
+
+// localizeOption sets the translations of the subcommand or option.
+func localizeOption(option *discordgo.ApplicationCommandOption, key string) {
+	if len(localizations[key]) == 0 {
+		return
+	}
+	option.NameLocalizations = map[discordgo.Locale]string{}
+	option.DescriptionLocalizations = map[discordgo.Locale]string{}
+	for locale, l := range localizations[key] {
+		option.NameLocalizations[locale] = l.Name
+		option.DescriptionLocalizations[locale] = l.Description
+	}
+}
@@ -0,0 +1,209 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// logHandler passes the records to the handler of the current logging
+// settings, so that a reload replaces it while the logger is in use.
+var logHandler = newSwappableHandler(slog.NewJSONHandler(os.Stdout, nil))
+
+var logger = slog.New(logHandler)
+
+// swappableHandler is a slog.Handler whose destination can be replaced. The
+// loggers derived with With or WithGroup keep the handler of the moment they
+// were derived.
+type swappableHandler struct {
+	current atomic.Pointer[slog.Handler]
+}
+
+func newSwappableHandler(handler slog.Handler) *swappableHandler {
+	h := &swappableHandler{}
+	h.set(handler)
+	return h
+}
+
+// set makes handler receive the next records.
+func (h *swappableHandler) set(handler slog.Handler) {
+	h.current.Store(&handler)
+}
+
+func (h *swappableHandler) handler() slog.Handler {
+	return *h.current.Load()
+}
+
+func (h *swappableHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler().Enabled(ctx, level)
+}
+
+func (h *swappableHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler().Handle(ctx, record)
+}
+
+func (h *swappableHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.handler().WithAttrs(attrs)
+}
+
+func (h *swappableHandler) WithGroup(name string) slog.Handler {
+	return h.handler().WithGroup(name)
+}
+
+// setupLogging configures the logger from the environment: the level with
+// SENDLATER_LOG_LEVEL (debug, info, warn or error), the format with
+// SENDLATER_LOG_FORMAT (json or text) and the destination with
+// SENDLATER_LOG_FILE, rotated when it reaches SENDLATER_LOG_MAX_SIZE
+// megabytes and keeping SENDLATER_LOG_MAX_FILES old files. The logs go to the
+// standard output in JSON at the info level by default.
+func setupLogging() error {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(envOr("SENDLATER_LOG_LEVEL", "info")))
+	if err != nil {
+		return errors.New("Error reading log level: " + err.Error())
+	}
+
+	var out io.Writer = os.Stdout
+	var file *rotatingFile
+	if path := os.Getenv("SENDLATER_LOG_FILE"); path != "" {
+		maxSize, err := strconv.Atoi(envOr("SENDLATER_LOG_MAX_SIZE", "10"))
+		if err != nil || maxSize <= 0 {
+			return errors.New("Error reading log max size: not a positive number of megabytes")
+		}
+		maxFiles, err := strconv.Atoi(envOr("SENDLATER_LOG_MAX_FILES", "5"))
+		if err != nil || maxFiles < 0 {
+			return errors.New("Error reading log max files: not a number")
+		}
+		file, err = openRotatingFile(path, int64(maxSize)<<20, maxFiles)
+		if err != nil {
+			return errors.New("Error opening log file: " + err.Error())
+		}
+		out = file
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	switch format := strings.ToLower(envOr("SENDLATER_LOG_FORMAT", "json")); format {
+	case "json":
+		logHandler.set(slog.NewJSONHandler(out, options))
+	case "text":
+		logHandler.set(slog.NewTextHandler(out, options))
+	default:
+		if file != nil {
+			file.Close()
+		}
+		return errors.New("unknown log format " + format + ", expected json or text")
+	}
+	// some messages are logged with the default logger
+	slog.SetDefault(logger)
+	// the file of the previous configuration is closed when it is reloaded
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = file
+	return nil
+}
+
+// logFile is the file the logs are written to, if any.
+var logFile *rotatingFile
+
+// rotatingFile is a log file renamed to path.1 once it reaches maxSize, the
+// older files being shifted up to path.maxFiles.
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// openRotatingFile opens the log file at path, appending to it.
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Write writes a log record, rotating the file first if the record would
+// make it too big.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		err := r.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file, the records written afterwards are lost.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// rotate shifts the old files, renames the current one to path.1 and opens a
+// new one.
+func (r *rotatingFile) rotate() error {
+	err := r.file.Close()
+	if err != nil {
+		return err
+	}
+	if r.maxFiles == 0 {
+		err = os.Remove(r.path)
+	} else {
+		_ = os.Remove(r.path + "." + strconv.Itoa(r.maxFiles))
+		for n := r.maxFiles - 1; n >= 1; n-- {
+			_ = os.Rename(r.path+"."+strconv.Itoa(n), r.path+"."+strconv.Itoa(n+1))
+		}
+		err = os.Rename(r.path, r.path+".1")
+	}
+	if err != nil {
+		return err
+	}
+	return r.open()
+}
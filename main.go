@@ -16,286 +16,1876 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"flag"
 	"github.com/bwmarrin/discordgo"
-	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 var (
-	Token  = os.Getenv("DISCORD_TOKEN")
-	logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	loc    *time.Location
+	Token          string
+	SecondaryToken string
+	StorePath      string
+	// AttachmentDir holds the files of the pending schedules, so that they
+	// are not kept in memory until sent. It is next to the store by default,
+	// as the files must last as long as the schedules.
+	AttachmentDir string
+	// CommandPermission is the permission members need to see the command
+	// until the admins change it in the server settings, see commandPermissions.
+	CommandPermission string
+	// DMCommands makes the command available in the DMs with the bot.
+	DMCommands bool
+	// HealthAddr is the address of the health endpoints, like :8080, they
+	// are not served when it is empty.
+	HealthAddr string
+	// Pprof serves the profiles of the Go runtime on PprofAddr.
+	Pprof     bool
+	PprofAddr string
+	// APIAddr is the address of the HTTP API, like :8081, served to the
+	// clients having one of APITokens. It is not served when it is empty.
+	APIAddr   string
+	APITokens []string
+	// EventWebhooks receive the events of the schedules listed in
+	// EventWebhookActions, all of them by default, each one signed with its
+	// secret of EventWebhookSecrets, or with EventWebhookSecret otherwise.
+	EventWebhooks       []string
+	EventWebhookSecret  string
+	EventWebhookSecrets []string
+	EventWebhookActions []string
+	// DashboardAddr is the address of the web dashboard, like :8082, reached
+	// by the members at DashboardURL. They log in with the OAuth2 client of
+	// the bot, OAuthClientID being the ID of the bot by default.
+	DashboardAddr     string
+	DashboardURL      string
+	OAuthClientID     string
+	OAuthClientSecret string
+	// GRPCAddr is the address of the gRPC service, like :9090, authenticated
+	// with APITokens too. It is not served when it is empty.
+	GRPCAddr string
+	// CalendarURL is an iCalendar feed, like the secret address of a Google
+	// Calendar, whose events are announced in CalendarChannel CalendarLead
+	// before they start, with CalendarTemplate, see watchCalendar.
+	CalendarURL      string
+	CalendarChannel  string
+	CalendarLead     string
+	CalendarInterval string
+	CalendarTemplate string
+	// Presence shows the number of pending messages in the status of the bot.
+	Presence bool
+	// KeepCommands leaves the commands registered when the bot stops.
+	KeepCommands bool
+	// CommandGuilds are the guilds where the commands are registered instead
+	// of globally, see commandScopes.
+	CommandGuilds []string
+	// Environment is where the bot runs, like production or dev, and
+	// CommandSuffix is added to the names of its commands, "-dev" by default
+	// outside of production, so that a staging bot can share a guild with the
+	// production one.
+	Environment   string
+	CommandSuffix string
+	store         *Store
 )
 
+// readSettings reads the settings of the bot from the environment, once the
+// configuration file and the flags are applied to it, see loadConfig. They
+// are only read at startup, the settings that a reload changes being the
+// runtimeSettings.
+func readSettings() {
+	Token = os.Getenv("DISCORD_TOKEN")
+	SecondaryToken = os.Getenv("DISCORD_TOKEN_SECONDARY")
+	StorePath = envOr("SENDLATER_STORE", "sendlater.json")
+	AttachmentDir = envOr("SENDLATER_ATTACHMENT_DIR", filepath.Join(filepath.Dir(StorePath), "attachments"))
+	CommandPermission = envOr("SENDLATER_COMMAND_PERMISSION", "send_messages")
+	DMCommands = envOr("SENDLATER_DM_COMMANDS", "true") == "true"
+	HealthAddr = os.Getenv("SENDLATER_HEALTH_ADDR")
+	Pprof = envOr("SENDLATER_PPROF", "false") == "true"
+	PprofAddr = envOr("SENDLATER_PPROF_ADDR", "localhost:6060")
+	Presence = envOr("SENDLATER_PRESENCE", "true") == "true"
+	APIAddr = os.Getenv("SENDLATER_API_ADDR")
+	APITokens = envList("SENDLATER_API_TOKENS")
+	GRPCAddr = os.Getenv("SENDLATER_GRPC_ADDR")
+	DashboardAddr = os.Getenv("SENDLATER_DASHBOARD_ADDR")
+	DashboardURL = os.Getenv("SENDLATER_DASHBOARD_URL")
+	OAuthClientID = os.Getenv("SENDLATER_OAUTH_CLIENT_ID")
+	OAuthClientSecret = os.Getenv("SENDLATER_OAUTH_CLIENT_SECRET")
+	EventWebhooks = envList("SENDLATER_EVENT_WEBHOOKS")
+	EventWebhookSecret = os.Getenv("SENDLATER_EVENT_WEBHOOK_SECRET")
+	EventWebhookSecrets = envList("SENDLATER_EVENT_WEBHOOK_SECRETS")
+	EventWebhookActions = envList("SENDLATER_EVENT_WEBHOOK_ACTIONS")
+	CalendarURL = os.Getenv("SENDLATER_CALENDAR_URL")
+	CalendarChannel = os.Getenv("SENDLATER_CALENDAR_CHANNEL")
+	CalendarLead = envOr("SENDLATER_CALENDAR_LEAD", "1h")
+	CalendarInterval = envOr("SENDLATER_CALENDAR_INTERVAL", "15m")
+	CalendarTemplate = envOr("SENDLATER_CALENDAR_TEMPLATE", defaultCalendarTemplate)
+	KeepCommands = envOr("SENDLATER_KEEP_COMMANDS", "false") == "true"
+	CommandGuilds = envList("SENDLATER_COMMAND_GUILDS")
+	Environment = envOr("SENDLATER_ENVIRONMENT", "production")
+	CommandSuffix = ""
+	if Environment != "production" {
+		CommandSuffix = "-" + Environment
+	}
+	CommandSuffix = envOr("SENDLATER_COMMAND_SUFFIX", CommandSuffix)
+}
+
 func main() {
-	// Create a new Discord session using the provided bot token.
-	dg, err := discordgo.New("Bot " + Token)
+	// The image of the bot runs "send-later-discord-bot healthcheck" to check
+	// the bot running in the container
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		os.Exit(runHealthcheck(envOr("SENDLATER_HEALTH_ADDR", ":8080")))
+	}
+	// and installs or controls the Windows service with its subcommands
+	if len(os.Args) > 1 {
+		if handled, code := serviceCommand(os.Args[1], os.Args[2:]); handled {
+			os.Exit(code)
+		}
+	}
+	chdirService()
+
+	// Read the configuration file, the environment and the flags
+	err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		logger.Error("Error loading configuration", "error", err)
+		os.Exit(2)
+	}
+
+	// Set up where the logs go, so that everything else is logged there
+	err = setupLogging()
+	if err != nil {
+		logger.Error("Error setting up logging", "error", err)
+		os.Exit(1)
+	}
+
+	// Load the persisted state of the bot
+	store, err = openStore(StorePath)
+	if err != nil {
+		logger.Error("Error opening store", "error", err, "path", StorePath)
+		os.Exit(1)
+	}
+
+	// Open a websocket connection to Discord with the primary token, falling
+	// back to the secondary one if the gateway session cannot be established.
+	dg, err := openSession(Token, SecondaryToken)
+	if err != nil {
+		logger.Error("Error opening Discord session,", "error", err)
+		os.Exit(1)
+	}
+
+	// Set up where the operational problems are reported
+	setupAlerters(dg)
+	// and where the traces are exported
+	setupTracing()
+	// and where the errors are reported
+	err = setupErrorReporters()
 	if err != nil {
-		logger.Error("Error creating Discord session,", "error", err)
+		logger.Error("Error setting up error reporting", "error", err)
 		os.Exit(1)
 	}
+	// and how the orchestrator checks the bot
+	serveHealth(dg, HealthAddr)
+	// and how the operators profile it
+	servePprof(Pprof, PprofAddr)
+	// and how the other services schedule messages
+	serveAPI(dg, APIAddr, APITokens)
+	serveGRPC(dg, GRPCAddr, APITokens)
+	// and where the members manage them on a calendar
+	serveDashboard(dg, DashboardAddr, DashboardURL, OAuthClientID, OAuthClientSecret)
+	// and how the other systems follow the messages
+	postEvents(EventWebhooks, eventWebhookSecrets(EventWebhooks, EventWebhookSecrets, EventWebhookSecret), EventWebhookActions)
+	// and schedule again the messages pending before the restart
+	restorePending(dg)
+	// and finish the deliveries interrupted by a crash
+	reconcileOutbox(dg)
+	// and delete the messages sent before when their time comes
+	restoreDeletions(dg)
+	// and delete the files of the messages sent before
+	watchAttachments()
+	// and which calendar is announced
+	watchCalendar(dg, CalendarURL, CalendarChannel, CalendarLead, CalendarInterval, CalendarTemplate)
+	// and which feeds are posted
+	watchFeeds(dg)
+	// and how the members see the queue
+	showPresence(dg, Presence)
+	// and reload the configuration on SIGHUP
+	watchReload(os.Args[1:])
 
-	// Get the local time zone
-	loc, err = time.LoadLocation("Local")
+	// Register the command and the other commands
+	command, err := sendlaterCommandDefinition("sendlater")
 	if err != nil {
-		logger.Error("Error loading local time zone", "error", err)
+		logger.Error("Error registering command,", "error", err, "command", "sendlater")
 		os.Exit(1)
 	}
+	definitions := append([]*discordgo.ApplicationCommand{command}, extraCommandDefinitions()...)
+	for _, definition := range definitions {
+		definition.Name = suffixedName(definition.Name, definition.Type)
+	}
+	commands := registerCommands(dg, definitions)
+
+	// watch for interruption, or for the orchestrator stopping the container,
+	// and gracefully shut down
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	runAsService(stop)
+	logger.Info("Press Ctrl+C to exit")
+	received := <-stop
+	logger.Info("Stopping", "signal", received.String())
+	// the changes of the last second are not written yet
+	err = store.SavePending()
+	if err != nil {
+		logger.Error("Error saving pending messages", "error", err)
+	}
+
+	// the commands are kept during a rolling restart, so that they don't
+	// disappear until the next instance registers them again
+	if !KeepCommands {
+		logger.Info("Removing commands...")
+		for _, cmd := range commands {
+			err = dg.ApplicationCommandDelete(dg.State.User.ID, cmd.GuildID, cmd.ID)
+			if err != nil {
+				logger.Error("Cannot delete command", "error", err, "command", cmd.Name, "guild", cmd.GuildID)
+			}
+		}
+	}
+
+	err = dg.Close()
+	if err != nil {
+		logger.Error("Error closing Discord session", "error", err)
+	}
+	logger.Info("Gracefully shutting down.")
+}
+
+// envOr returns the value of the environment variable key, or def if it is unset.
+func envOr(key string, def string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return def
+}
+
+// envInt returns the number in the environment variable key, or def if it is
+// unset or not a number.
+func envInt(key string, def int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// envList returns the comma separated values of the environment variable key,
+// without the empty ones.
+func envList(key string) []string {
+	values := []string{}
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// openSession tries each non-empty token in order and returns the first
+// session whose gateway connection could be established.
+func openSession(tokens ...string) (*discordgo.Session, error) {
+	err := errors.New("no bot token configured")
+	for n, token := range tokens {
+		if token == "" {
+			continue
+		}
+		dg, newErr := discordgo.New("Bot " + token)
+		if newErr != nil {
+			logger.Error("Error creating Discord session,", "error", newErr, "token", n)
+			err = newErr
+			continue
+		}
+
+		// Add a handler for the "ready" event to confirm the bot is online.
+		dg.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
+			logger.Info("Bot is up!")
+		})
+
+		// Add a handler for the command interaction
+		dg.AddHandler(interactionCreate)
+
+		// Add handlers to welcome the bot in new guilds and clean up after it
+		// is removed from one
+		dg.AddHandler(guildCreate)
+		dg.AddHandler(guildDelete)
+
+		err = dg.Open()
+		if err != nil {
+			logger.Error("Error opening Discord session, trying next token", "error", err, "token", n)
+			continue
+		}
+		if n > 0 {
+			logger.Warn("Primary session unavailable, running on secondary token", "token", n)
+		}
+		return dg, nil
+	}
+	return nil, err
+}
+
+// schedulingCommands are the subcommands restricted by the guild
+// configuration.
+var schedulingCommands = []string{"schedule", "compose", "import_ics", "import", "edit", "delete", "react", "poll", "countdown", "campaign", "update"}
+
+func interactionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// the handling of the interaction is traced, its handlers adding spans
+	// through interactionContext
+	ctx, sp := startSpan(context.Background(), "interaction "+i.Type.String(), spanKindServer, "interaction.id", i.ID, "guild.id", i.GuildID, "user.id", interactionUser(i).ID)
+	interactionTraces.Store(i.ID, ctx)
+	defer func() {
+		interactionTraces.Delete(i.ID)
+		sp.end(nil)
+	}()
+	// a malformed interaction must not crash the bot, the member is told
+	// something went wrong instead
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			capturePanic(recovered, map[string]string{"interaction.id": i.ID, "interaction.type": i.Type.String(), "guild.id": i.GuildID, "user.id": interactionUser(i).ID})
+			if i.Type != discordgo.InteractionApplicationCommandAutocomplete {
+				respondEphemeral(s, i, "Something went wrong, please try again later")
+			}
+		}
+	}()
+
+	if !guildAllowed(i.GuildID) {
+		logger.Warn("Interaction refused in unlisted guild", "guild", i.GuildID, "user", interactionUser(i).ID)
+		respond(s, i, "This bot is not available in this server")
+		return
+	}
+	if i.Type == discordgo.InteractionMessageComponent {
+		if strings.HasPrefix(i.MessageComponentData().CustomID, "setup_") {
+			handleSetupComponent(s, i)
+		} else if strings.HasPrefix(i.MessageComponentData().CustomID, approvalPrefix) {
+			handleApprovalComponent(s, i)
+		} else if strings.HasPrefix(i.MessageComponentData().CustomID, previewPrefix) {
+			handlePreviewComponent(s, i)
+		} else if strings.HasPrefix(i.MessageComponentData().CustomID, snoozePrefix) {
+			handleSnoozeComponent(s, i)
+		} else if strings.HasPrefix(i.MessageComponentData().CustomID, headsUpPrefix) {
+			handleHeadsUpComponent(s, i)
+		}
+		return
+	}
+	if i.Type == discordgo.InteractionModalSubmit {
+		if strings.HasPrefix(i.ModalSubmitData().CustomID, composePrefix) && allowScheduling(s, i, true, false) {
+			handleComposeSubmit(s, i)
+		} else if strings.HasPrefix(i.ModalSubmitData().CustomID, repostPrefix) && allowScheduling(s, i, true, false) {
+			handleRepostSubmit(s, i)
+		} else if strings.HasPrefix(i.ModalSubmitData().CustomID, remindMessagePrefix) && allowScheduling(s, i, true, false) {
+			handleRemindMessageSubmit(s, i)
+		} else if strings.HasPrefix(i.ModalSubmitData().CustomID, campaignPrefix) && allowScheduling(s, i, true, false) {
+			handleCampaignSubmit(s, i)
+		}
+		return
+	}
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		handleAutocomplete(s, i)
+		return
+	}
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	data := i.ApplicationCommandData()
+	data.Name = unsuffixedName(data.Name, data.CommandType)
+	if data.CommandType == discordgo.MessageApplicationCommand {
+		if data.Name == repostCommand && allowScheduling(s, i, true, true) {
+			handleRepost(s, i)
+		} else if data.Name == remindMessageCommand && allowScheduling(s, i, true, true) {
+			handleRemindMessage(s, i)
+		}
+		return
+	}
+	if data.Name == remindmeCommand {
+		if allowScheduling(s, i, true, true) {
+			handleRemindme(s, i, data.Options)
+		}
+		return
+	}
+	if data.Name == remindersCommand {
+		handleReminders(s, i, data.Options)
+		return
+	}
+	if data.Name == failedCommand {
+		handleFailed(s, i, data.Options)
+		return
+	}
+	if data.Name != "sendlater" || len(data.Options) == 0 {
+		return
+	}
+
+	// every feature of the bot lives in a subcommand of /sendlater
+	subcommand := data.Options[0]
+	// updates don't add pending messages
+	if slices.Contains(schedulingCommands, subcommand.Name) && !allowScheduling(s, i, subcommand.Name != "update", true) {
+		return
+	}
+	switch subcommand.Name {
+	case "schedule":
+		handleSchedule(s, i, subcommand.Options)
+	case "agenda":
+		handleAgenda(s, i)
+	case "compose":
+		handleCompose(s, i, subcommand.Options)
+	case "cancel":
+		handleCancel(s, i, subcommand.Options)
+	case "audit":
+		handleAudit(s, i, subcommand.Options)
+	case "export_ics":
+		handleExportICal(s, i)
+	case "import_ics":
+		handleImportICal(s, i, subcommand.Options)
+	case "setup":
+		handleSetup(s, i)
+	case "flag":
+		handleFlag(s, i, subcommand.Options)
+	case "broadcast":
+		handleBroadcast(s, i, subcommand.Options)
+	case "edit":
+		handleEdit(s, i, subcommand.Options)
+	case "delete":
+		handleDelete(s, i, subcommand.Options)
+	case "react":
+		handleReact(s, i, subcommand.Options)
+	case "update":
+		handleUpdate(s, i, subcommand.Options)
+	case "poll":
+		handlePoll(s, i, subcommand.Options)
+	case "help":
+		handleHelp(s, i)
+	case "check":
+		handleCheck(s, i, subcommand.Options)
+	case "countdown":
+		handleCountdown(s, i, subcommand.Options)
+	case "mine":
+		handleMine(s, i)
+	case "campaign":
+		handleCampaign(s, i, subcommand.Options)
+	case "campaigns":
+		handleCampaigns(s, i, subcommand.Options)
+	case "stats":
+		handleStats(s, i)
+	case "feed":
+		handleFeed(s, i, subcommand.Options)
+	case "import":
+		handleImport(s, i, subcommand.Options)
+	case "backup":
+		handleBackup(s, i, subcommand.Options)
+	}
+}
+
+// allowScheduling reports whether the member may schedule in the guild, and
+// tells them why not otherwise: the guild may restrict scheduling to some
+// roles or to a permission, and limit how many messages each member can have
+// pending, when checkQuota is set. The commands using the rate limit count
+// towards it.
+func allowScheduling(s *discordgo.Session, i *discordgo.InteractionCreate, checkQuota bool, useRateLimit bool) bool {
+	config := store.GuildConfig(i.GuildID)
+	user := interactionUser(i)
+	if !config.canSchedule(i.Member) {
+		logger.Debug("Scheduling refused, missing role or permission", "guild", i.GuildID, "user", user.ID)
+		respond(s, i, "You are not allowed to schedule messages in this server")
+		return false
+	}
+	if checkQuota && config.quotaLeft(i.GuildID, user.ID) == 0 {
+		logger.Debug("Scheduling refused, quota reached", "guild", i.GuildID, "user", user.ID, "quota", config.quota())
+		respond(s, i, "You have too many pending messages in this server, cancel some or wait for them to be sent")
+		return false
+	}
+	if useRateLimit {
+		if wait := scheduleCooldowns.use(i.GuildID, user.ID, config.rateLimit(), time.Now()); wait > 0 {
+			logger.Debug("Scheduling refused, rate limited", "guild", i.GuildID, "user", user.ID, "wait", wait)
+			respond(s, i, cooldownMessage(wait))
+			return false
+		}
+	}
+	return true
+}
+
+// deferred holds the IDs of the interactions whose response was deferred, it
+// is then given by editing the deferred response.
+var deferred sync.Map
+
+// deferResponse acknowledges the interaction before a processing that may
+// last longer than the 3 seconds allowed to respond, like downloading an
+// attachment. The response is given later with respond and the like, and is
+// shown with the flags given here.
+func deferResponse(s *discordgo.Session, i *discordgo.InteractionCreate, flags discordgo.MessageFlags) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: flags,
+		},
+	})
+	if err != nil {
+		logger.Error("Error deferring interaction response", "error", err)
+		return
+	}
+	deferred.Store(i.ID, true)
+}
 
-	// Add a handler for the "ready" event to confirm the bot is online.
-	dg.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
-		logger.Info("Bot is up!")
+// sendResponse responds to the interaction with data, or completes its
+// deferred response.
+func sendResponse(s *discordgo.Session, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData) {
+	if _, found := deferred.LoadAndDelete(i.ID); found {
+		_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content:         &data.Content,
+			Embeds:          &data.Embeds,
+			Components:      &data.Components,
+			Files:           data.Files,
+			AllowedMentions: data.AllowedMentions,
+		})
+		if err != nil {
+			logger.Error("Error editing deferred interaction response", "error", err)
+		}
+		return
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
 	})
+	if err != nil {
+		logger.Error("Error responding to interaction", "error", err)
+	}
+}
 
-	// Add a handler for the command interaction
-	dg.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		if i.Type == discordgo.InteractionApplicationCommand {
-			if i.ApplicationCommandData().Name == "sendlater" {
-				options := i.ApplicationCommandData().Options
-				message := ""
-				sendTime := ""
-				attachment := ""
-				date := ""
-				var channel *discordgo.Channel
-
-				// we get the options set by the user
-				for _, option := range options {
-					if option.Name == "message" {
-						message = option.StringValue()
-					} else if option.Name == "time" {
-						sendTime = option.StringValue()
-					} else if option.Name == "date" {
-						date = option.StringValue()
-					} else if option.Name == "channel" {
-						channel = option.ChannelValue(s)
-					} else if option.Name == "attachment" {
-						// we get the attachment url and then we download it
-						attachmentID := option.Value.(string)
-						if attachmentID == "" {
-							continue
-						}
-						attachmentUrl := i.ApplicationCommandData().Resolved.Attachments[attachmentID].URL
-						resp, err := http.Get(attachmentUrl)
-						if err != nil {
-							slog.Error("Could not get attachment", "error", err, "url", attachmentUrl)
-							s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-								Type: discordgo.InteractionResponseChannelMessageWithSource,
-								Data: &discordgo.InteractionResponseData{
-									Content: "Could not get attachment: " + err.Error(),
-								},
-							})
-							return
-						}
-						if strings.Contains(resp.Header.Get("Content-type"), "plain/text") {
-							slog.Error("Attachment is not text", "content-type", resp.Header.Get("Content-type"), "url", attachmentUrl)
-							s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-								Type: discordgo.InteractionResponseChannelMessageWithSource,
-								Data: &discordgo.InteractionResponseData{
-									Content: "Could not get attachment, attachment is not text but " + resp.Header.Get("Content-type"),
-								},
-							})
-							return
-						}
-						attachmentBytes, err := io.ReadAll(resp.Body)
-						if err != nil {
-							slog.Error("Could not get attachment", "error", err, "url", attachmentUrl)
-							s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-								Type: discordgo.InteractionResponseChannelMessageWithSource,
-								Data: &discordgo.InteractionResponseData{
-									Content: "Could not get attachment: " + err.Error(),
-								},
-							})
-							return
-						}
-						attachment = string(attachmentBytes)
-					}
-				}
+// replyFlags are the flags of the replies to the commands, they are only
+// seen by the member who used them unless publicReplies is set, so that a
+// surprise isn't spoiled.
+func replyFlags() discordgo.MessageFlags {
+	if settings().publicReplies {
+		return 0
+	}
+	return discordgo.MessageFlagsEphemeral
+}
 
-				// if the channel wasn't set by the user, we get the current channel
-				if channel == nil {
-					channel, err = s.Channel(i.ChannelID)
-					if err != nil {
-						logger.Error("Error scheduling message: ", "error", err)
-						s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-							Type: discordgo.InteractionResponseChannelMessageWithSource,
-							Data: &discordgo.InteractionResponseData{
-								Content: "Error scheduling message: " + err.Error(),
-							},
-						})
-						return
-					}
-				}
+// respond replies to the interaction with a plain message.
+func respond(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	sendResponse(s, i, &discordgo.InteractionResponseData{
+		Content: content,
+		Flags:   replyFlags(),
+	})
+}
 
-				// if the date wasn't set by the user, we get the current date
-				if date == "" {
-					date = time.Now().Format("02/01/2006")
-				}
+// respondEphemeral replies to the interaction with a message only the user
+// can see.
+func respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	sendResponse(s, i, &discordgo.InteractionResponseData{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+}
 
-				// we check that at least message or attachment is set but not both
-				if message == "" && attachment == "" {
-					logger.Error("Error scheduling message: ", "error", "message and attachment cannot be empty")
-					s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-						Type: discordgo.InteractionResponseChannelMessageWithSource,
-						Data: &discordgo.InteractionResponseData{
-							Content: "Error scheduling message: message and attachment cannot be empty",
-						},
-					})
+// respondEmbed replies to the interaction with an embed.
+func respondEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed) {
+	sendResponse(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+		Flags:  replyFlags(),
+	})
+}
+
+// respondEphemeralEmbed replies to the interaction with an embed only the
+// user can see.
+func respondEphemeralEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed) {
+	sendResponse(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+		Flags:  discordgo.MessageFlagsEphemeral,
+	})
+}
+
+// truncate shortens text to at most max runes, marking the cut with an ellipsis.
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// hasPermission reports whether the member who triggered the interaction has
+// the permission in the channel, administrators have every permission.
+func hasPermission(i *discordgo.InteractionCreate, permission int64) bool {
+	if i.Member == nil {
+		return false
+	}
+	return i.Member.Permissions&(permission|discordgo.PermissionAdministrator) != 0
+}
+
+// interactionUser returns the user who triggered the interaction, whether it
+// happened in a guild or in a DM.
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+func handleSchedule(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	message := ""
+	sendTime := ""
+	attachment := ""
+	date := ""
+	files := []ScheduledFile{}
+	embeds := []*discordgo.MessageEmbed{}
+	var allowedMentions *discordgo.MessageAllowedMentions
+	tts := false
+	silent := false
+	spoiler := false
+	format := formatPlain
+	language := ""
+	dm := false
+	thread := ""
+	postTitle := ""
+	postTags := ""
+	moreChannels := ""
+	crosspost := false
+	replyTo := ""
+	mentions := ""
+	sender := senderBot
+	pin := ""
+	deleteAfter := ""
+	notifyBefore := ""
+	discussion := ""
+	discussionArchive := 24 * 60
+	preview := false
+	var channel *discordgo.Channel
+
+	// the guild may restrict scheduling to some roles
+	config := store.GuildConfig(i.GuildID)
+
+	// downloading an attachment can take longer than the time allowed to
+	// respond, the replies of previews and anonymous messages are hidden
+	flags := replyFlags()
+	hasAttachment := false
+	for _, option := range options {
+		if option.Name == "attachment" && option.Value.(string) != "" {
+			hasAttachment = true
+		} else if (option.Name == "preview" && option.BoolValue()) || (option.Name == "sender" && option.StringValue() == senderAnonymous) {
+			flags = discordgo.MessageFlagsEphemeral
+		}
+	}
+	if hasAttachment {
+		deferResponse(s, i, flags)
+	}
+
+	// we get the options set by the user
+	for _, option := range options {
+		if option.Name == "message" {
+			message = option.StringValue()
+		} else if option.Name == "time" {
+			sendTime = option.StringValue()
+		} else if option.Name == "date" {
+			date = option.StringValue()
+		} else if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		} else if option.Name == "tts" {
+			tts = option.BoolValue()
+		} else if option.Name == "silent" {
+			silent = option.BoolValue()
+		} else if option.Name == "spoiler" {
+			spoiler = option.BoolValue()
+		} else if option.Name == "dm" {
+			dm = option.BoolValue()
+		} else if option.Name == "thread" {
+			thread = option.StringValue()
+		} else if option.Name == "post_title" {
+			postTitle = option.StringValue()
+		} else if option.Name == "post_tags" {
+			postTags = option.StringValue()
+		} else if option.Name == "discussion" {
+			discussion = strings.TrimSpace(option.StringValue())
+		} else if option.Name == "discussion_archive" {
+			discussionArchive = int(option.IntValue())
+		} else if option.Name == "delete_after" {
+			deleteAfter = option.StringValue()
+		} else if option.Name == "notify_before" {
+			notifyBefore = option.StringValue()
+		} else if option.Name == "pin" {
+			pin = option.StringValue()
+		} else if option.Name == "preview" {
+			preview = option.BoolValue()
+		} else if option.Name == "sender" {
+			sender = option.StringValue()
+		} else if option.Name == "reply_to" {
+			replyTo = option.StringValue()
+		} else if option.Name == "mentions" {
+			mentions = option.StringValue()
+		} else if option.Name == "crosspost" {
+			crosspost = option.BoolValue()
+		} else if option.Name == "more_channels" {
+			moreChannels = option.StringValue()
+		} else if option.Name == "format" {
+			format = option.StringValue()
+		} else if option.Name == "language" {
+			language = option.StringValue()
+		} else if option.Name == "attachment" {
+			// we get the attachment url and then we download it
+			attachmentID := option.Value.(string)
+			if attachmentID == "" {
+				continue
+			}
+			resolved := i.ApplicationCommandData().Resolved.Attachments[attachmentID]
+			err := checkAttachmentSize(resolved.Filename, resolved.Size)
+			if err != nil {
+				respondError(s, i, "Error scheduling message", err)
+				return
+			}
+
+			// JSON is sent as a webhook message or an embed, text as the
+			// message, and anything else is uploaded as a file
+			if strings.HasPrefix(resolved.ContentType, "application/json") {
+				data, _, err := downloadFile(interactionContext(i), resolved.URL)
+				if err != nil {
+					respondError(s, i, "Error scheduling message", discordError("cannot download "+resolved.Filename, err))
 					return
 				}
-
-				if message != "" && attachment != "" {
-					logger.Error("Error scheduling message: ", "error", "message and attachment cannot be both set")
-					s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-						Type: discordgo.InteractionResponseChannelMessageWithSource,
-						Data: &discordgo.InteractionResponseData{
-							Content: "Error scheduling message: message and attachment cannot be both set",
-						},
-					})
+				payload, payloadEmbeds, err := parseMessage(data)
+				if err != nil {
+					respondError(s, i, "Error scheduling message", &userError{Code: codeInvalidMessage, Message: resolved.Filename + ": " + err.Error(), Hint: "The file is a webhook message, as exported by Discohook."})
 					return
 				}
-
-				// we schedule the message
-				err := scheduleMessage(s, message, attachment, sendTime, date, channel)
+				attachment = payload.Content
+				embeds = payloadEmbeds
+				allowedMentions = payload.AllowedMentions
+				continue
+			}
+			if strings.HasPrefix(resolved.ContentType, "text/") {
+				attachment, err = downloadAttachment(interactionContext(i), resolved.URL)
 				if err != nil {
-					logger.Error("Error scheduling message: ", "error", err)
-					s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-						Type: discordgo.InteractionResponseChannelMessageWithSource,
-						Data: &discordgo.InteractionResponseData{
-							Content: "Error scheduling message: " + err.Error(),
-						},
-					})
+					respondError(s, i, "Error scheduling message", discordError("cannot download "+resolved.Filename, err))
 					return
 				}
-				logger.Info("Message scheduled\n", "message", message+attachment, "date", date, "sendTime", sendTime, "channel", channel.Name)
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: "Message scheduled!",
-					},
-				})
+				continue
+			}
+			// the other files wait on the disk rather than in memory
+			file, err := storeAttachment(interactionContext(i), resolved.URL, resolved.Filename, resolved.ContentType)
+			if err != nil {
+				respondError(s, i, "Error scheduling message", discordError("cannot download "+resolved.Filename, err))
 				return
 			}
+			files = append(files, file)
 		}
-	})
+	}
+
+	// if the channel wasn't set by the user, we get the current channel
+	if channel == nil {
+		var err error
+		channel, err = s.Channel(i.ChannelID)
+		if err != nil {
+			logger.Error("Error scheduling message: ", "error", err)
+			respondError(s, i, "Error scheduling message", discordError("cannot get this channel", err))
+			return
+		}
+	}
 
-	// Open a websocket connection to Discord and begin listening.
-	err = dg.Open()
+	// archived threads cannot be picked in the channel option, so they are
+	// given by link or ID
+	if thread != "" {
+		var err error
+		channel, err = threadChannel(s, thread)
+		if err != nil {
+			logger.Error("Error scheduling message: ", "error", err)
+			respondError(s, i, "Error scheduling message", err)
+			return
+		}
+	}
+
+	// a reply is sent in the channel of the message it replies to
+	replyToID := ""
+	if replyTo != "" {
+		if dm {
+			respondError(s, i, "Error scheduling message", &userError{Code: codeInvalidOption, Message: "a message sent in DM cannot reply to a message"})
+			return
+		}
+		var err error
+		var replied *discordgo.Message
+		replied, channel, err = linkedMessage(s, channel, replyTo)
+		if err != nil {
+			logger.Error("Error scheduling message: ", "error", err)
+			respondError(s, i, "Error scheduling message", err)
+			return
+		}
+		if channel.GuildID != i.GuildID {
+			respondError(s, i, "Error scheduling message", &userError{Code: codeInvalidOption, Message: "the message to reply to is not in this server"})
+			return
+		}
+		replyToID = replied.ID
+	}
+
+	// the DMs are sent wherever the command comes from
+	if !dm {
+		err := checkChannel(channel, true)
+		if err != nil {
+			respondError(s, i, "Error scheduling message", err)
+			return
+		}
+	}
+
+	// if the date wasn't set by the user, we get the current date
+	if date == "" {
+		date = time.Now().In(config.location()).Format("02/01/2006")
+	}
+
+	// we check that at least message or attachment is set but not both, a
+	// file or an embed can come with a message though
+	if message == "" && attachment == "" && len(files) == 0 && len(embeds) == 0 {
+		logger.Error("Error scheduling message: ", "error", "message and attachment cannot be empty")
+		respondError(s, i, "Error scheduling message", &userError{Code: codeInvalidMessage, Message: "message and attachment cannot be empty", Hint: "Write the message, or attach a file or an embed."})
+		return
+	}
+
+	if message != "" && attachment != "" {
+		logger.Error("Error scheduling message: ", "error", "message and attachment cannot be both set")
+		respondError(s, i, "Error scheduling message", &userError{Code: codeInvalidMessage, Message: "message and attachment cannot be both set", Hint: "A text file is the message, choose one of them."})
+		return
+	}
+
+	// who is pinged is chosen explicitly, a JSON message may set it otherwise
+	if mentions != "" || allowedMentions == nil {
+		allowedMentions = mentionLevel(mentions)
+	}
+
+	// the members can only schedule what they could post themselves
+	if !dm {
+		err := checkCanPost(s, interactionUser(i).ID, channel, message+attachment, allowedMentions)
+		if err != nil {
+			respondError(s, i, "Error scheduling message", err)
+			return
+		}
+	}
+
+	// the same message can be sent in several channels at once
+	extraChannelIDs, err := extraChannels(s, channel.GuildID, moreChannels, interactionUser(i).ID, message+attachment, allowedMentions)
 	if err != nil {
-		logger.Error("Error opening Discord session,", "error", err)
-		os.Exit(1)
+		respondError(s, i, "Error scheduling message", err)
+		return
+	}
+
+	// in a forum channel, the message starts a new post
+	var forumTagIDs []string
+	if channel.Type == discordgo.ChannelTypeGuildForum && !dm {
+		if postTitle == "" {
+			postTitle, _, _ = strings.Cut(strings.TrimSpace(message+attachment), "\n")
+		}
+		if postTitle == "" {
+			respondError(s, i, "Error scheduling message", &userError{Code: codeInvalidOption, Message: "a post title is needed in a forum channel", Hint: "Set the title option."})
+			return
+		}
+		postTitle = truncate(postTitle, 100)
+		forumTagIDs, err = forumTags(channel, postTags)
+		if err != nil {
+			respondError(s, i, "Error scheduling message", err)
+			return
+		}
+	} else {
+		postTitle = ""
+	}
+
+	// only the messages of announcement channels can be published to the
+	// servers following them
+	if crosspost && (channel.Type != discordgo.ChannelTypeGuildNews || dm) {
+		respondError(s, i, "Error scheduling message", &userError{Code: codeInvalidOption, Message: "only the messages of an announcement channel can be published"})
+		return
+	}
+
+	// the first message of a forum post is pinned by design
+	if pin != "" && postTitle != "" {
+		respondError(s, i, "Error scheduling message", &userError{Code: codeInvalidOption, Message: "a forum post cannot be pinned", Hint: "Its first message is pinned by design."})
+		return
+	}
+
+	// threads cannot be started in threads, forums or DMs
+	if discussion != "" && (dm || channel.IsThread() || channel.Type == discordgo.ChannelTypeGuildForum) {
+		respondError(s, i, "Error scheduling message", &userError{Code: codeInvalidOption, Message: "a discussion thread can only be started in a text or announcement channel"})
+		return
+	}
+	discussion = truncate(discussion, 100)
+
+	// sending as the author goes through a webhook of the channel, which
+	// cannot reply nor post in DM or in a forum
+	senderName := ""
+	senderAvatarURL := ""
+	if sender == senderMe {
+		if !store.FlagEnabled(i.GuildID, FlagWebhookDelivery) {
+			respondError(s, i, "Error scheduling message", &userError{Code: codeNotAllowed, Message: "sending as yourself is not enabled in this server", Hint: "An admin can enable the webhook_delivery feature flag."})
+			return
+		}
+		if dm || replyToID != "" || channel.Type == discordgo.ChannelTypeGuildForum {
+			respondError(s, i, "Error scheduling message", &userError{Code: codeInvalidOption, Message: "a message sent as yourself cannot be sent in DM, in a forum or as a reply"})
+			return
+		}
+		permissions, err := s.UserChannelPermissions(s.State.User.ID, channel.ID)
+		if err == nil && permissions&discordgo.PermissionManageWebhooks == 0 {
+			respondError(s, i, "Error scheduling message", &userError{Code: codeMissingAccess, Message: "I need the Manage Webhooks permission to send as yourself", Hint: "Ask an admin to give it to me in this channel."})
+			return
+		}
+		senderName, senderAvatarURL = authorIdentity(i.Member, i.GuildID)
+	}
+
+	// the message can be deleted some time after being sent
+	var deleteDelay time.Duration
+	if deleteAfter != "" {
+		var err error
+		deleteDelay, err = parseDelay(deleteAfter)
+		if err != nil {
+			respondError(s, i, "Error scheduling message", err)
+			return
+		}
+	}
+
+	// and the author can be told before it is sent
+	var headsUpDelay time.Duration
+	if notifyBefore != "" {
+		var err error
+		headsUpDelay, err = parseDelay(notifyBefore)
+		if err != nil {
+			respondError(s, i, "Error scheduling message", err)
+			return
+		}
 	}
 
-	// Register the command
-	cmd, err := registerCommand(dg, "sendlater")
+	// a text attachment can be rendered as code
+	if attachment != "" && format == formatCode {
+		attachment = codeBlock(attachment, language)
+	}
+
+	// we schedule the message
+	fixedTime, err := parseSendTime(date, sendTime, config)
 	if err != nil {
-		logger.Error("Error registering command,", "error", err, "command", cmd.Name)
-		os.Exit(1)
+		logger.Error("Error scheduling message: ", "error", err)
+		respondError(s, i, "Error scheduling message", err)
+		return
+	}
+	sch := &Schedule{
+		GuildID:           channel.GuildID,
+		ChannelID:         channel.ID,
+		ChannelName:       channel.Name,
+		AuthorID:          interactionUser(i).ID,
+		Content:           message + attachment,
+		Files:             files,
+		Embeds:            embeds,
+		AllowedMentions:   allowedMentions,
+		TTS:               tts,
+		Silent:            silent,
+		Spoiler:           spoiler,
+		DM:                dm,
+		Thread:            channel.IsThread(),
+		ForumTitle:        postTitle,
+		ForumTagIDs:       forumTagIDs,
+		ExtraChannelIDs:   extraChannelIDs,
+		Crosspost:         crosspost,
+		ReplyToID:         replyToID,
+		Sender:            sender,
+		SenderName:        senderName,
+		SenderAvatarURL:   senderAvatarURL,
+		Pin:               pin,
+		DeleteAfter:       deleteDelay,
+		NotifyBefore:      headsUpDelay,
+		Discussion:        discussion,
+		DiscussionArchive: discussionArchive,
+		SendAt:            fixedTime,
+	}
+	// the member can check how the message looks before it is scheduled
+	if preview {
+		respondPreview(s, i, sch)
+		return
+	}
+	startSchedule(s, sch)
+	logger.Info("Message scheduled\n", "message", message+attachment, "files", len(files), "date", date, "sendTime", sendTime, "channel", channel.Name)
+	// the command of an anonymous message must not be seen by the members
+	if sender == senderAnonymous {
+		embed := scheduledEmbed("Message scheduled anonymously", sch)
+		embed.Description = "Only the moderators can see in the audit trail that you scheduled it."
+		respondEphemeralEmbed(s, i, embed)
+		return
 	}
+	respondEmbed(s, i, scheduledEmbed("Message scheduled", sch))
+}
 
-	// watch for interruption and gracefully shut down
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
-	logger.Info("Press Ctrl+C to exit")
-	<-stop
-	logger.Info("Removing commands...")
+// Rendering modes of the text attachments.
+const (
+	formatPlain = "plain"
+	formatCode  = "code"
+)
+
+// codeBlock fences text as a code block with an optional language hint. The
+// fences inside the text are broken with a zero width space so they don't
+// close the block early.
+func codeBlock(text string, language string) string {
+	text = strings.ReplaceAll(text, "```", "`\u200b``")
+	return "```" + language + "\n" + strings.TrimRight(text, "\n") + "\n```"
+}
 
-	err = dg.ApplicationCommandDelete(dg.State.User.ID, "", cmd.ID)
+// downloadFile returns the content and the content type of the attachment at
+// url, which must not be larger than the maxAttachmentSize setting.
+func downloadFile(ctx context.Context, attachmentUrl string) (data []byte, contentType string, err error) {
+	_, sp := startSpan(ctx, "download attachment", spanKindClient)
+	defer func() {
+		sp.setAttribute("size", strconv.Itoa(len(data)))
+		sp.end(err)
+	}()
+	var buf bytes.Buffer
+	contentType, _, err = fetchAttachment(ctx, attachmentUrl, &buf)
 	if err != nil {
-		logger.Error("Cannot delete command", "error", err, "command", cmd.Name)
+		return nil, "", err
 	}
+	return buf.Bytes(), contentType, nil
+}
 
-	logger.Info("Gracefully shutting down.")
+// downloadAttachment returns the content of the text attachment at url.
+func downloadAttachment(ctx context.Context, attachmentUrl string) (string, error) {
+	data, contentType, err := downloadFile(ctx, attachmentUrl)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(contentType, "plain/text") {
+		slog.Error("Attachment is not text", "content-type", contentType, "url", attachmentUrl)
+		return "", errors.New("Could not get attachment, attachment is not text but " + contentType)
+	}
+	return string(data), nil
 }
 
-func registerCommand(s *discordgo.Session, commandName string) (*discordgo.ApplicationCommand, error) {
+// commandPermissions are the values of CommandPermission, nil lets everyone
+// use the command.
+var commandPermissions = map[string]*int64{
+	"everyone":        nil,
+	"send_messages":   ptr(int64(discordgo.PermissionSendMessages)),
+	"manage_messages": ptr(int64(discordgo.PermissionManageMessages)),
+	"manage_events":   ptr(int64(discordgo.PermissionManageEvents)),
+	"manage_server":   ptr(int64(discordgo.PermissionManageServer)),
+	"administrator":   ptr(int64(discordgo.PermissionAdministrator)),
+}
+
+// ptr returns a pointer to v, for the optional fields of the Discord API.
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// sendlaterCommandDefinition returns the /sendlater command under the given
+// name.
+func sendlaterCommandDefinition(commandName string) (*discordgo.ApplicationCommand, error) {
 	// Create a new command
+	permission, found := commandPermissions[CommandPermission]
+	if !found {
+		return nil, errors.New("unknown command permission " + CommandPermission)
+	}
 	command := &discordgo.ApplicationCommand{
-		Name:        commandName,
-		Description: "Schedules a message (one line) or an attachment (several lines) to be sent at a later time. If time is set in the past, the message will be sent after one minute",
+		Name:                     commandName,
+		Description:              "Schedules messages to be sent at a later time",
+		DefaultMemberPermissions: permission,
+		DMPermission:             &DMCommands,
 		Options: []*discordgo.ApplicationCommandOption{
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "time",
-				Description: "The time to send the message (HH:MM)",
-				Required:    true,
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "schedule",
+				Description: "Schedules a message (one line) or an attachment (several lines) to be sent later",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "time",
+						Description:  "The time to send the message (HH:MM)",
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "message",
+						Description: "The message to send (one line)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionAttachment,
+						Name:        "attachment",
+						Description: "The message to send (several lines if text, an embed if JSON, or a file to upload)",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "date",
+						Description: "[Optionnal] The date to send the message (dd/mm/yyyy). Default: today",
+						Required:    false,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "[Optionnal] Channel to send the message. Default: current channel",
+						Required:     false,
+						ChannelTypes: channelTypes(true),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "tts",
+						Description: "[Optionnal] Read the message aloud with text-to-speech. Default: false",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "silent",
+						Description: "[Optionnal] Send without push and desktop notifications. Default: false",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "spoiler",
+						Description: "[Optionnal] Hide the message and its files behind a spoiler. Default: false",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "format",
+						Description: "[Optionnal] How a text attachment is rendered. Default: plain",
+						Required:    false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Plain text", Value: formatPlain},
+							{Name: "Code block", Value: formatCode},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "language",
+						Description: "[Optionnal] Language hint of the code block, like go or json",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "dm",
+						Description: "[Optionnal] Send the message to you in DM instead of a channel. Default: false",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "thread",
+						Description: "[Optionnal] Link or ID of a thread to send the message in, archived threads are reopened",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "post_title",
+						Description: "[Optionnal] Title of the post, for forum channels. Default: first line of the message",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "post_tags",
+						Description: "[Optionnal] Comma separated tags of the post, for forum channels",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "more_channels",
+						Description: "[Optionnal] Other channels receiving the same message, like #events #news",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "crosspost",
+						Description: "[Optionnal] Publish the message to the servers following the announcement channel. Default: false",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "reply_to",
+						Description: "[Optionnal] Link or ID of a message to reply to",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "mentions",
+						Description: "[Optionnal] Who the mentions of the message ping. Default: users",
+						Required:    false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Nobody", Value: mentionsNone},
+							{Name: "Users", Value: mentionsUsers},
+							{Name: "Users and roles", Value: mentionsRoles},
+							{Name: "Everyone, including @everyone and @here", Value: mentionsEveryone},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "sender",
+						Description: "[Optionnal] Who the message is sent as. Default: bot",
+						Required:    false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Bot", Value: senderBot},
+							{Name: "Me", Value: senderMe},
+							{Name: "Anonymous", Value: senderAnonymous},
+							{Name: "Bot, mentioning me", Value: senderMention},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "pin",
+						Description: "[Optionnal] Pin the message once sent. Default: not pinned",
+						Required:    false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Pin", Value: pinAdd},
+							{Name: "Pin and unpin the previous messages of the bot", Value: pinReplace},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "delete_after",
+						Description: "[Optionnal] Delete the message this long after sending it, like 30m, 2h or 1d",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "discussion",
+						Description: "[Optionnal] Start a thread with this name on the message once sent",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "discussion_archive",
+						Description: "[Optionnal] Archive the discussion thread after this inactivity. Default: 1 day",
+						Required:    false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "1 hour", Value: 60},
+							{Name: "1 day", Value: 24 * 60},
+							{Name: "3 days", Value: 3 * 24 * 60},
+							{Name: "1 week", Value: 7 * 24 * 60},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "preview",
+						Description: "[Optionnal] Show how the message will look and ask for confirmation before scheduling. Default: false",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "notify_before",
+						Description: "[Optionnal] DM you this long before sending, with buttons to cancel or send now, like 10m or 1h",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "compose",
+				Description: "Opens an editor to write a message on several lines, then schedules it",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "[Optionnal] Channel to send the message. Default: current channel",
+						Required:     false,
+						ChannelTypes: channelTypes(false),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "repeat",
+						Description: "[Optionnal] Send the message again at every occurrence, manage it with /reminders",
+						Required:    false,
+						Choices:     repeatChoices(),
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "edit",
+				Description: "Schedules an edit of a message sent by the bot",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "link",
+						Description: "The link or ID of the message to edit",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "message",
+						Description: "The new content of the message",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "time",
+						Description:  "The time to edit the message (HH:MM)",
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "date",
+						Description: "[Optionnal] The date to edit the message (dd/mm/yyyy). Default: today",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "delete",
+				Description: "Schedules the deletion of a message",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "link",
+						Description: "The link or ID of the message to delete",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "time",
+						Description:  "The time to delete the message (HH:MM)",
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "date",
+						Description: "[Optionnal] The date to delete the message (dd/mm/yyyy). Default: today",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "react",
+				Description: "Schedules reactions of the bot on a message",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "link",
+						Description: "The link or ID of the message to react to",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "emojis",
+						Description: "The emojis to react with, separated by spaces",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "time",
+						Description:  "The time to add the reactions (HH:MM)",
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "date",
+						Description: "[Optionnal] The date to add the reactions (dd/mm/yyyy). Default: today",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "update",
+				Description: "Changes the content or the time of a scheduled message",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "id",
+						Description: "The ID of the scheduled message, as shown by the agenda",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "message",
+						Description: "[Optionnal] The new message. Default: unchanged",
+						Required:    false,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "time",
+						Description:  "[Optionnal] The new time (HH:MM). Default: unchanged",
+						Required:     false,
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "date",
+						Description: "[Optionnal] The new date (dd/mm/yyyy). Default: unchanged",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "poll",
+				Description: "Schedules a Discord poll",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "question",
+						Description: "The question of the poll",
+						Required:    true,
+						MaxLength:   maxPollQuestion,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "answers",
+						Description: "The answers, separated by |, like Yes | No | Maybe",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "time",
+						Description:  "The time to create the poll (HH:MM)",
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "date",
+						Description: "[Optionnal] The date to create the poll (dd/mm/yyyy). Default: today",
+						Required:    false,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "[Optionnal] Channel to create the poll. Default: current channel",
+						Required:     false,
+						ChannelTypes: channelTypes(false),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "duration",
+						Description: "[Optionnal] How many hours the poll is open. Default: 24",
+						Required:    false,
+						MinValue:    &minPollDuration,
+						MaxValue:    maxPollDuration,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "multi_select",
+						Description: "[Optionnal] Allow several answers. Default: false",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "countdown",
+				Description: "Posts a countdown now, replaced by the message when it is sent",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "message",
+						Description: "The message to send",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "time",
+						Description:  "The time to send the message (HH:MM)",
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "date",
+						Description: "[Optionnal] The date to send the message (dd/mm/yyyy). Default: today",
+						Required:    false,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "[Optionnal] Channel to send the message. Default: current channel",
+						Required:     false,
+						ChannelTypes: channelTypes(false),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "label",
+						Description: "[Optionnal] What the countdown announces. Default: Announcement",
+						Required:    false,
+						MaxLength:   100,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "campaign",
+				Description: "Schedules a sequence of messages, each one some time after the start",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "[Optionnal] Channel to send the messages. Default: current channel",
+						Required:     false,
+						ChannelTypes: channelTypes(false),
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "campaigns",
+				Description: "Lists, pauses, resumes or cancels the sequences of messages of the server",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "action",
+						Description: "What to do",
+						Required:    true,
+						Choices:     manageChoices(),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "id",
+						Description: "[Optionnal] The ID of the sequence, except for the list",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "cancel",
+				Description: "Cancels a scheduled message",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "id",
+						Description: "The ID of the scheduled message, as shown by the agenda",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "check",
+				Description: "Shows when a message would be sent, without scheduling anything",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "time",
+						Description:  "The time to check (HH:MM)",
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "date",
+						Description: "[Optionnal] The date to check (dd/mm/yyyy). Default: today",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "help",
+				Description: "Explains how to schedule messages, with the formats, options and limits",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "mine",
+				Description: "Lists the messages you scheduled in every server",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "agenda",
+				Description: "Shows the messages scheduled in this server for the next 7 days",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "export_ics",
+				Description: "Exports the messages scheduled in this server as an iCalendar (.ics) file",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "import_ics",
+				Description: "Schedules the events of an iCalendar (.ics) file, each event is sent at its start",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionAttachment,
+						Name:        "file",
+						Description: "The iCalendar file",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "[Optionnal] Channel to send the messages. Default: current channel",
+						Required:     false,
+						ChannelTypes: channelTypes(false),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "recurrences",
+						Description: "[Optionnal] Schedule the recurrences of the events for the next 90 days. Default: false",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "import",
+				Description: "Schedules the messages of a CSV or JSON file, with their content, date, time and channel",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionAttachment,
+						Name:        "file",
+						Description: "The CSV or JSON file",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "[Optionnal] The channel of the messages without one. Default: current channel",
+						Required:     false,
+						ChannelTypes: channelTypes(false),
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "backup",
+				Description: "[Admins] Exports the messages scheduled in this server as JSON, or restores them",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "action",
+						Description: "Export or restore the messages",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Export", Value: backupExport},
+							{Name: "Restore", Value: backupRestore},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionAttachment,
+						Name:        "file",
+						Description: "[Optionnal] The backup to restore",
+						Required:    false,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "stats",
+				Description: "[Admins] Shows the pending, delivered and failed messages of this server",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "feed",
+				Description: "[Admins] Posts the new items of an RSS or Atom feed in a daily digest",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "action",
+						Description: "What to do with the feed",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Add", Value: feedAdd},
+							{Name: "Remove", Value: feedRemove},
+							{Name: "List", Value: feedList},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "url",
+						Description: "[Optionnal] The URL of the feed, to add or remove it",
+						Required:    false,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "[Optionnal] The channel of the digest. Default: current channel",
+						Required:     false,
+						ChannelTypes: channelTypes(false),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "digest_time",
+						Description: "[Optionnal] The time of the daily digest (HH:MM). Default: 09:00",
+						Required:    false,
+					},
+				},
 			},
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "message",
-				Description: "The message to send (one line)",
-				Required:    false,
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "audit",
+				Description: "[Admins] Exports who scheduled, cancelled and sent what in this server",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "format",
+						Description: "The format of the export",
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "CSV", Value: "csv"},
+							{Name: "JSON", Value: "json"},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "from",
+						Description: "[Optionnal] The first day of the export (dd/mm/yyyy). Default: first recorded day",
+						Required:    false,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "to",
+						Description: "[Optionnal] The last day of the export (dd/mm/yyyy). Default: today",
+						Required:    false,
+					},
+				},
 			},
 			{
-				Type:        discordgo.ApplicationCommandOptionAttachment,
-				Name:        "attachment",
-				Description: "The message to send (several lines)",
-				Required:    false,
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "setup",
+				Description: "[Admins] Configures the time zone, audit channel, allowed roles and quiet hours",
 			},
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "date",
-				Description: "[Optionnal] The date to send the message (dd/mm/yyyy). Default: today",
-				Required:    false,
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "flag",
+				Description: "[Bot owners] Enables or disables an experimental feature for a guild",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "name",
+						Description: "The feature flag",
+						Required:    true,
+						Choices:     flagChoices(),
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "enabled",
+						Description: "Whether the feature is enabled",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "guild",
+						Description: "[Optionnal] The guild ID. Default: current guild",
+						Required:    false,
+					},
+				},
 			},
 			{
-				Type:        discordgo.ApplicationCommandOptionChannel,
-				Name:        "channel",
-				Description: "[Optionnal] Channel to send the message. Default: current channel",
-				Required:    false,
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "broadcast",
+				Description: "[Bot owners] Schedules a message in the channel of the same name in every server",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "message",
+						Description: "The message to broadcast",
+						Required:    true,
+					},
+					{
+						Type:         discordgo.ApplicationCommandOptionString,
+						Name:         "time",
+						Description:  "The time to send the message (HH:MM)",
+						Required:     true,
+						Autocomplete: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "channel_name",
+						Description: "The name of the channel in every server, like announcements",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "date",
+						Description: "[Optionnal] The date to send the message (dd/mm/yyyy). Default: today",
+						Required:    false,
+					},
+				},
 			},
 		},
 	}
 
-	// Register the command
-	cmd, err := s.ApplicationCommandCreate(s.State.User.ID, "", command)
-	if err != nil {
-		logger.Error("Error creating command,", "error", err)
+	// the command is shown in the language of the members when translated
+	localizeCommand(command)
 
-	} else {
-		logger.Info("Command registered successfully!")
+	return command, nil
+}
+
+// suffixedName returns the name of the command in the environment of the
+// bot. The context menus show the environment in parentheses, like
+// "Schedule repost (dev)".
+func suffixedName(name string, commandType discordgo.ApplicationCommandType) string {
+	if CommandSuffix == "" {
+		return name
 	}
-	return cmd, nil
+	if commandType == discordgo.MessageApplicationCommand {
+		return name + " (" + strings.TrimLeft(CommandSuffix, "-_ ") + ")"
+	}
+	return name + CommandSuffix
 }
 
-func scheduleMessage(s *discordgo.Session, message string, attachment string, sendTime string, date string, channel *discordgo.Channel) error {
-	// Define the fixed time when the message should be sent.
-	toSend := ""
-	fixedTime, err := time.ParseInLocation("02/01/2006 15:04", date+" "+sendTime, loc)
-	if err != nil {
-		return errors.New("Error parsing fixed time: " + err.Error())
+// unsuffixedName reverts suffixedName, so that the commands are handled
+// under the same name in every environment.
+func unsuffixedName(name string, commandType discordgo.ApplicationCommandType) string {
+	if CommandSuffix == "" {
+		return name
 	}
-	logger.Info("Time parsed", "time", fixedTime)
-	if message != "" {
-		toSend = message
-	} else {
-		toSend = attachment
-	}
-	go func() {
-		// Use a ticker to periodically check the current time.
-		ticker := time.NewTicker(time.Minute)
-		//ticker := time.NewTicker(time.Second)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				now := time.Now()
-				if fixedTime.Before(now) {
-					// Send a message to the specified channel.
-					logger.Info("Sending message", "message", toSend, "channel", channel.Name)
-					_, err := s.ChannelMessageSend(channel.ID, toSend)
-					if err != nil {
-						logger.Error("Error sending message,", "error", err)
-					}
-					return
-				}
-			}
+	if commandType == discordgo.MessageApplicationCommand {
+		return strings.TrimSuffix(name, " ("+strings.TrimLeft(CommandSuffix, "-_ ")+")")
+	}
+	return strings.TrimSuffix(name, CommandSuffix)
+}
+
+// commandScopes are the guilds where the commands are registered, or the
+// empty guild ID to register them globally. Guild commands are updated at
+// once, so a development instance registers its commands in a test guild.
+func commandScopes() []string {
+	if len(CommandGuilds) > 0 {
+		return CommandGuilds
+	}
+	return []string{""}
+}
+
+// registerCommands registers the commands in every scope and returns the
+// registered commands. They replace the commands of the bot at once, which
+// is idempotent: Discord leaves the commands that didn't change untouched, so
+// a restart doesn't make them disappear or count against the daily limit of
+// command creations.
+func registerCommands(s *discordgo.Session, commands []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+	registered := []*discordgo.ApplicationCommand{}
+	for _, guildID := range commandScopes() {
+		cmds, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, guildID, commands)
+		if err != nil {
+			logger.Error("Error registering commands,", "error", err, "guild", guildID)
+			continue
 		}
-	}()
-	return nil
+		logger.Info("Commands registered successfully!", "count", len(cmds), "guild", guildID)
+		registered = append(registered, cmds...)
+	}
+	return registered
+}
+
+// extraCommandDefinitions returns the commands besides /sendlater, that is
+// /remindme, /reminders and the commands of the context menus of the messages.
+func extraCommandDefinitions() []*discordgo.ApplicationCommand {
+	permission := commandPermissions[CommandPermission]
+	commands := []*discordgo.ApplicationCommand{remindmeCommandDefinition(), remindersCommandDefinition(), failedCommandDefinition()}
+	for _, name := range []string{repostCommand, remindMessageCommand} {
+		commands = append(commands, &discordgo.ApplicationCommand{
+			Type: discordgo.MessageApplicationCommand,
+			Name: name,
+		})
+	}
+
+	for _, command := range commands {
+		command.DefaultMemberPermissions = permission
+		command.DMPermission = &DMCommands
+		// the failed messages are managed by the server admins
+		if command.Name == failedCommand {
+			manageServer := int64(discordgo.PermissionManageServer)
+			command.DefaultMemberPermissions = &manageServer
+			command.DMPermission = new(bool)
+		}
+		localizeCommand(command)
+	}
+	return commands
 }
@@ -0,0 +1,219 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// OutboxEntry is a delivery in progress. It is recorded before the message is
+// sent and removed once the delivery is over, so the entries left at startup
+// are the deliveries interrupted by a crash.
+type OutboxEntry struct {
+	Schedule  *Schedule `json:"schedule"`
+	StartedAt time.Time `json:"started_at"`
+	// Sent are the messages already sent, by target channel ID.
+	Sent map[string]string `json:"sent,omitempty"`
+}
+
+// BeginDelivery records that the schedule is being delivered. It leaves the
+// pending schedules in the same write, so that a crash never restores it
+// next to its entry in the outbox.
+func (st *Store) BeginDelivery(sch *Schedule, now time.Time) error {
+	return st.update(func(data *storeData) {
+		if data.Outbox == nil {
+			data.Outbox = map[string]OutboxEntry{}
+		}
+		data.Outbox[sch.ID] = OutboxEntry{Schedule: sch, StartedAt: now}
+		delete(data.Pending, sch.ID)
+	})
+}
+
+// RecordSent records that the message of the schedule was sent in the target
+// channel, before the steps that follow, so that it is not sent there again
+// after a crash.
+func (st *Store) RecordSent(id string, channelID string, messageID string) error {
+	return st.update(func(data *storeData) {
+		entry, found := data.Outbox[id]
+		if !found {
+			return
+		}
+		if entry.Sent == nil {
+			entry.Sent = map[string]string{}
+		}
+		entry.Sent[channelID] = messageID
+		data.Outbox[id] = entry
+	})
+}
+
+// EndDelivery records that the delivery of the schedule is over, whether it
+// succeeded or not. It is written with the next occurrence of the schedule
+// and the audit entries, a crash in the meantime only finishes the delivery
+// again.
+func (st *Store) EndDelivery(id string) {
+	st.updateLater(func(data *storeData) {
+		delete(data.Outbox, id)
+	})
+}
+
+// Outbox returns the deliveries in progress.
+func (st *Store) Outbox() []OutboxEntry {
+	entries := []OutboxEntry{}
+	st.view(func(data *storeData) {
+		for _, entry := range data.Outbox {
+			entries = append(entries, entry)
+		}
+	})
+	return entries
+}
+
+// beginDelivery records the delivery of the schedule in the outbox, and
+// returns the function recording its end. A store failure doesn't stop the
+// delivery, it only loses the protection against a crash.
+func beginDelivery(sch *Schedule) func() {
+	err := store.BeginDelivery(sch, time.Now())
+	if err != nil {
+		logger.Error("Error recording delivery", "error", err, "id", sch.ID)
+	}
+	return func() {
+		store.EndDelivery(sch.ID)
+	}
+}
+
+// recordSent records the message sent in the target channel in the outbox. A
+// store failure only loses the protection against a crash.
+func recordSent(sch *Schedule, channelID string, message *discordgo.Message) {
+	err := store.RecordSent(sch.ID, channelID, message.ID)
+	if err != nil {
+		logger.Error("Error recording sent message", "error", err, "id", sch.ID, "channel", channelID)
+	}
+}
+
+// reconcileOutbox finishes the deliveries interrupted by a crash: the target
+// channels where the message was recorded or is found are done, the schedule
+// is sent again within a minute to the others.
+func reconcileOutbox(s *discordgo.Session) {
+	for _, entry := range store.Outbox() {
+		sch := entry.Schedule
+		missing := []string{}
+		for _, channelID := range sch.targets() {
+			if _, sent := entry.Sent[channelID]; sent {
+				continue
+			}
+			delivered, err := findDelivered(s, sch, channelID, entry.StartedAt)
+			if err != nil {
+				logger.Warn("Could not check interrupted delivery, sending it again", "error", err, "id", sch.ID, "channel", channelID)
+			}
+			if !delivered {
+				missing = append(missing, channelID)
+			}
+		}
+		// a copy saved as pending before the delivery started is not sent
+		// next to the outbox entry
+		schedules.take(sch.ID)
+		// the next occurrence was not scheduled before the crash
+		if sch.Repeat != "" {
+			repeatSchedule(s, sch)
+		}
+		if len(missing) == 0 {
+			logger.Info("Interrupted delivery was sent", "id", sch.ID, "channel", sch.ChannelName)
+			audit(s, AuditSent, sch.AuthorID, sch)
+		} else {
+			again := sch.retarget(s, missing)
+			again.SendAt = time.Now()
+			again.Repeat = ""
+			schedules.readd(again)
+			watchSchedule(s, again)
+			logger.Warn("Interrupted delivery sent again", "id", again.ID, "channels", missing)
+		}
+		store.EndDelivery(sch.ID)
+	}
+}
+
+// findDelivered looks for the message of the schedule among the messages
+// posted in the target channel since the delivery started, for a crash
+// during the send itself. The messages of the bot or of its webhooks are
+// matched with deliveredAs, the actions, polls and forum posts are never
+// found.
+func findDelivered(s *discordgo.Session, sch *Schedule, channelID string, startedAt time.Time) (bool, error) {
+	if sch.Action != "" || sch.Poll != nil {
+		return false, nil
+	}
+	if sch.DM && channelID == sch.ChannelID {
+		channel, err := s.UserChannelCreate(sch.AuthorID)
+		if err != nil {
+			return false, err
+		}
+		channelID = channel.ID
+	}
+	if sch.ForumTitle != "" && !sch.DM && channelID == sch.ChannelID {
+		// the post is a thread of the forum, not a message in it
+		return false, nil
+	}
+	messages, err := s.ChannelMessages(channelID, 100, "", snowflakeAt(startedAt.Add(-time.Minute)), "")
+	if err != nil {
+		return false, err
+	}
+	for _, message := range messages {
+		fromBot := message.Author != nil && message.Author.ID == s.State.User.ID
+		if (fromBot || message.WebhookID != "") && deliveredAs(sch, message) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// deliveredAs reports whether the message looks like the one of the schedule:
+// the first line of its content, or when it has none, or it was uploaded as
+// a file for being too long, its first embed or file.
+func deliveredAs(sch *Schedule, message *discordgo.Message) bool {
+	content := strings.TrimSpace(sch.Content)
+	switch {
+	case utf8.RuneCountInString(content) > maxMessageLength:
+		return hasAttachment(message, "message.txt")
+	case content != "":
+		line, _, _ := strings.Cut(content, "\n")
+		return strings.Contains(message.Content, line)
+	case len(sch.Embeds) > 0:
+		return len(message.Embeds) > 0 && message.Embeds[0].Title == sch.Embeds[0].Title && message.Embeds[0].Description == sch.Embeds[0].Description
+	case len(sch.Files) > 0:
+		return hasAttachment(message, sch.Files[0].Name)
+	}
+	return false
+}
+
+// hasAttachment reports whether the message has a file with the name, hidden
+// behind a spoiler or not. Discord replaces the spaces of the names.
+func hasAttachment(message *discordgo.Message, name string) bool {
+	name = strings.ReplaceAll(name, " ", "_")
+	for _, attachment := range message.Attachments {
+		if attachment.Filename == name || attachment.Filename == "SPOILER_"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// snowflakeAt returns the smallest Discord ID of the given time, to list the
+// messages posted after it.
+func snowflakeAt(t time.Time) string {
+	const discordEpoch = 1420070400000
+	return strconv.FormatInt((t.UnixMilli()-discordEpoch)<<22, 10)
+}
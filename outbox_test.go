@@ -0,0 +1,125 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/bwmarrin/discordgo"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDiscord serves the messages of the channels at the endpoints of the
+// Discord API, the DM channel of every user being "dm".
+func fakeDiscord(t *testing.T, messages map[string][]*discordgo.Message) *discordgo.Session {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/users/@me/channels" {
+			json.NewEncoder(w).Encode(&discordgo.Channel{ID: "dm"})
+			return
+		}
+		channelID, _ := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/channels/"), "/messages")
+		json.NewEncoder(w).Encode(append([]*discordgo.Message{}, messages[channelID]...))
+	}))
+	channels, users := discordgo.EndpointChannels, discordgo.EndpointUsers
+	discordgo.EndpointChannels, discordgo.EndpointUsers = server.URL+"/channels/", server.URL+"/users/"
+	t.Cleanup(func() {
+		discordgo.EndpointChannels, discordgo.EndpointUsers = channels, users
+		server.Close()
+	})
+	s, err := discordgo.New("Bot token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.State.User = &discordgo.User{ID: "bot"}
+	return s
+}
+
+func TestFindDelivered(t *testing.T) {
+	bot, member := &discordgo.User{ID: "bot"}, &discordgo.User{ID: "member"}
+	s := fakeDiscord(t, map[string][]*discordgo.Message{
+		"news":    {{Author: member, Content: "Hello there"}, {Author: bot, Content: "||Weekly update||"}},
+		"uploads": {{Author: member, WebhookID: "hook", Attachments: []*discordgo.MessageAttachment{{Filename: "message.txt"}}}},
+		"dm":      {{Author: bot, Content: "Scheduled by <@member>\nYour reminder"}},
+	})
+	found := func(sch *Schedule, channelID string) bool {
+		delivered, err := findDelivered(s, sch, channelID, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return delivered
+	}
+	if !found(&Schedule{ChannelID: "news", Content: "Weekly update\nmore", Spoiler: true}, "news") {
+		t.Error("message of the bot not found")
+	}
+	if found(&Schedule{ChannelID: "news", Content: "Hello there"}, "news") {
+		t.Error("message of a member found")
+	}
+	if !found(&Schedule{ChannelID: "news", ExtraChannelIDs: []string{"uploads"}, Content: strings.Repeat("a", maxMessageLength+1)}, "uploads") {
+		t.Error("long message uploaded by a webhook not found")
+	}
+	if !found(&Schedule{ChannelID: "news", AuthorID: "member", DM: true, Content: "Your reminder"}, "news") {
+		t.Error("message sent in DM not found")
+	}
+	if found(&Schedule{ChannelID: "news", ForumTitle: "Update", Content: "Weekly update"}, "news") {
+		t.Error("forum post found among the messages")
+	}
+}
+
+// TestCrashDuringDelivery restarts the bot from the store left by a crash in
+// the middle of a send, the message being posted once whether it went out or
+// not.
+func TestCrashDuringDelivery(t *testing.T) {
+	saved, watching := store, dispatcher
+	defer func() { store, dispatcher = saved, watching }()
+	// the pending schedules are saved by hand, and nothing is dispatched
+	pendingFlush.Store(true)
+	defer pendingFlush.Store(false)
+	dispatcher = &scheduleDispatcher{byID: map[string]*scheduleWatch{}, wakeup: make(chan struct{}, 1), due: make(chan *scheduleWatch)}
+	dispatcher.start.Do(func() {})
+	for _, posted := range []bool{true, false} {
+		path := filepath.Join(t.TempDir(), "sendlater.json")
+		var err error
+		store, err = openStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sch := &Schedule{ID: "crash", AuthorID: "member", ChannelID: "news", Content: "Weekly update", SendAt: time.Now()}
+		schedules.add(sch)
+		store.SavePending()
+		schedules.remove(sch)
+		store.BeginDelivery(sch, time.Now())
+		messages := map[string][]*discordgo.Message{}
+		if posted {
+			messages["news"] = []*discordgo.Message{{Author: &discordgo.User{ID: "bot"}, Content: "Weekly update"}}
+		}
+		s := fakeDiscord(t, messages)
+		if store, err = openStore(path); err != nil {
+			t.Fatal(err)
+		}
+		restorePending(s)
+		reconcileOutbox(s)
+		copies := len(schedules.author("member"))
+		if posted && copies != 0 || !posted && copies != 1 {
+			t.Errorf("posted %v: %d copies sent again", posted, copies)
+		}
+		schedules.take("crash")
+		store.flush()
+	}
+}
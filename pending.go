@@ -0,0 +1,81 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"sync/atomic"
+)
+
+// SavePending writes the schedules pending right now to the store. They are
+// read under the lock of the store, so that a write never brings back a
+// schedule that another write recorded as being delivered.
+func (st *Store) SavePending() error {
+	return st.update(func(data *storeData) {
+		data.Pending = schedules.snapshot()
+	})
+}
+
+// Pending returns the pending schedules saved in the store.
+func (st *Store) Pending() []*Schedule {
+	list := []*Schedule{}
+	st.view(func(data *storeData) {
+		for _, sch := range data.Pending {
+			list = append(list, sch)
+		}
+	})
+	return list
+}
+
+// pendingFlush is set while a write of the pending schedules is planned.
+var pendingFlush atomic.Bool
+
+// pendingChanged plans a write of the pending schedules with the next write
+// of the store. They are read at the time of the write, so that it records
+// them along with the end of the deliveries that scheduled them.
+func pendingChanged() {
+	if store == nil || !pendingFlush.CompareAndSwap(false, true) {
+		return
+	}
+	store.updateLater(func(data *storeData) {
+		pendingFlush.Store(false)
+		data.Pending = schedules.snapshot()
+	})
+}
+
+// restorePending schedules again the schedules that were pending when the bot
+// stopped, those due in the meantime being sent at once. The ones whose
+// stored files were deleted in the meantime are dropped, and their authors
+// told why.
+func restorePending(s *discordgo.Session) {
+	restored := store.Pending()
+	for _, sch := range restored {
+		if name := sch.missingFile(); name != "" {
+			logger.Warn("Pending message dropped, its file is missing", "id", sch.ID, "file", name)
+			err := notifyAuthor(s, sch, "Your message `"+sch.ID+"` for "+sch.target()+" cannot be sent: its file "+name+" was lost while the bot restarted. Schedule it again with the file.")
+			if err != nil {
+				logger.Error("Error telling the author of a lost file", "error", err, "id", sch.ID, "author", sch.AuthorID)
+			}
+			pendingChanged()
+			continue
+		}
+		schedules.readd(sch)
+		watchSchedule(s, sch)
+	}
+	if len(restored) > 0 {
+		logger.Info("Pending messages restored", "count", len(restored))
+	}
+}
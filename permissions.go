@@ -0,0 +1,131 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"regexp"
+	"slices"
+)
+
+// roleMention matches the mentions of roles in a message.
+var roleMention = regexp.MustCompile(`<@&(\d+)>`)
+
+// Levels of the mentions option, from the safest. The users are pinged by
+// default, but not the roles nor everyone.
+const (
+	mentionsNone     = "none"
+	mentionsUsers    = "users"
+	mentionsRoles    = "roles"
+	mentionsEveryone = "everyone"
+)
+
+// mentionLevel returns who the message pings at the level.
+func mentionLevel(level string) *discordgo.MessageAllowedMentions {
+	mentions := &discordgo.MessageAllowedMentions{Parse: []discordgo.AllowedMentionType{}}
+	switch level {
+	case mentionsEveryone:
+		mentions.Parse = append(mentions.Parse, discordgo.AllowedMentionTypeEveryone)
+		fallthrough
+	case mentionsRoles:
+		mentions.Parse = append(mentions.Parse, discordgo.AllowedMentionTypeRoles)
+		fallthrough
+	case mentionsUsers, "":
+		mentions.Parse = append(mentions.Parse, discordgo.AllowedMentionTypeUsers)
+	}
+	return mentions
+}
+
+// checkCanPost returns an error when the user could not post the content in
+// the channel themselves, so that the bot cannot be used to post in channels
+// they are locked out of, or to ping everyone. Nil mentions ping the users
+// only.
+func checkCanPost(s *discordgo.Session, userID string, channel *discordgo.Channel, content string, mentions *discordgo.MessageAllowedMentions) error {
+	permissions, err := s.UserChannelPermissions(userID, channel.ID)
+	if err != nil {
+		return discordError("cannot get your permissions in #"+channel.Name, err)
+	}
+	send := int64(discordgo.PermissionSendMessages)
+	if channel.IsThread() {
+		send = discordgo.PermissionSendMessagesInThreads
+	}
+	if permissions&(discordgo.PermissionViewChannel|send) != discordgo.PermissionViewChannel|send {
+		return &userError{Code: codeNotAllowed, Message: "you cannot send messages in #" + channel.Name, Hint: "You can only schedule what you could post yourself."}
+	}
+	if permissions&discordgo.PermissionMentionEveryone != 0 {
+		return nil
+	}
+
+	if mentions == nil {
+		mentions = mentionLevel(mentionsUsers)
+	}
+	if slices.Contains(mentions.Parse, discordgo.AllowedMentionTypeEveryone) {
+		return &userError{Code: codeNotAllowed, Message: "you need the Mention Everyone permission in #" + channel.Name + " to ping everyone", Hint: "Choose another mentions option."}
+	}
+	// the roles that are not mentionable can only be pinged with the
+	// Mention Everyone permission
+	if !slices.Contains(mentions.Parse, discordgo.AllowedMentionTypeRoles) && len(mentions.Roles) == 0 {
+		return nil
+	}
+	for _, match := range roleMention.FindAllStringSubmatch(content, -1) {
+		role, err := s.State.Role(channel.GuildID, match[1])
+		if err != nil || !role.Mentionable {
+			return &userError{Code: codeNotAllowed, Message: "you cannot ping the role <@&" + match[1] + "> in #" + channel.Name, Hint: "Choose another mentions option, or ask an admin to make the role mentionable."}
+		}
+	}
+	return nil
+}
+
+// channelGoneError is returned by revalidate when a channel of the schedule
+// was deleted or the bot lost access to it.
+type channelGoneError struct {
+	reason string
+}
+
+func (e *channelGoneError) Error() string {
+	return e.reason
+}
+
+// revalidate checks again before sending that the channels of the schedule
+// still exist and that its author and the bot can still post there, as this
+// may have changed since it was scheduled.
+func revalidate(s *discordgo.Session, sch *Schedule) error {
+	if sch.DM {
+		return nil
+	}
+	for _, channelID := range append([]string{sch.ChannelID}, sch.ExtraChannelIDs...) {
+		channel, err := s.Channel(channelID)
+		if err != nil {
+			return &channelGoneError{"the channel <#" + channelID + "> cannot be reached anymore: " + err.Error()}
+		}
+		// the broadcasts of the bot owners go to servers they may not be in
+		if sch.Action == "" && !isOwner(sch.AuthorID) {
+			err = checkCanPost(s, sch.AuthorID, channel, sch.Content, sch.AllowedMentions)
+			if err != nil {
+				return errors.New("your permissions changed: " + err.Error())
+			}
+		}
+		permissions, err := s.UserChannelPermissions(s.State.User.ID, channel.ID)
+		if err == nil && permissions&discordgo.PermissionViewChannel == 0 {
+			err = errors.New("missing access")
+		}
+		if err != nil {
+			return &channelGoneError{"the bot cannot access #" + channel.Name + " anymore: " + err.Error()}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SchedulePoll is a native Discord poll. The polls are not supported by
+// discordgo yet, so they are sent with a raw request.
+type SchedulePoll struct {
+	Question string   `json:"question"`
+	Answers  []string `json:"answers"`
+	// Duration is how many hours the poll is open.
+	Duration    int  `json:"duration"`
+	MultiSelect bool `json:"multi_select,omitempty"`
+}
+
+// Limits of the Discord polls.
+const (
+	maxPollQuestion = 300
+	maxPollAnswer   = 55
+	maxPollAnswers  = 10
+	maxPollDuration = 32 * 24
+)
+
+// minPollDuration is the minimum of the duration option, which needs a pointer.
+var minPollDuration = 1.0
+
+// pollMedia is the text of a question or of an answer.
+type pollMedia struct {
+	Text string `json:"text"`
+}
+
+type pollAnswer struct {
+	PollMedia pollMedia `json:"poll_media"`
+}
+
+type pollRequest struct {
+	Question         pollMedia    `json:"question"`
+	Answers          []pollAnswer `json:"answers"`
+	Duration         int          `json:"duration"`
+	AllowMultiselect bool         `json:"allow_multiselect"`
+}
+
+// sendPoll creates the poll of the schedule in the channel.
+func sendPoll(s *discordgo.Session, sch *Schedule, channelID string) (*discordgo.Message, error) {
+	request := pollRequest{
+		Question:         pollMedia{Text: sch.Poll.Question},
+		Duration:         sch.Poll.Duration,
+		AllowMultiselect: sch.Poll.MultiSelect,
+	}
+	for _, answer := range sch.Poll.Answers {
+		request.Answers = append(request.Answers, pollAnswer{PollMedia: pollMedia{Text: answer}})
+	}
+	body := struct {
+		Poll  pollRequest            `json:"poll"`
+		Flags discordgo.MessageFlags `json:"flags,omitempty"`
+	}{Poll: request}
+	if sch.Silent {
+		body.Flags = discordgo.MessageFlagsSuppressNotifications
+	}
+
+	endpoint := discordgo.EndpointChannelMessages(channelID)
+	response, err := s.RequestWithBucketID("POST", endpoint, body, endpoint)
+	if err != nil {
+		return nil, errors.New("Error creating poll: " + err.Error())
+	}
+	message := &discordgo.Message{}
+	err = json.Unmarshal(response, message)
+	if err != nil {
+		return nil, errors.New("Error reading poll: " + err.Error())
+	}
+	return message, nil
+}
+
+// parsePoll checks the poll against the Discord limits. The answers are
+// separated by |.
+func parsePoll(question string, answers string, duration int, multiSelect bool) (*SchedulePoll, error) {
+	poll := &SchedulePoll{Question: strings.TrimSpace(question), Duration: duration, MultiSelect: multiSelect}
+	if poll.Question == "" || utf8.RuneCountInString(poll.Question) > maxPollQuestion {
+		return nil, errors.New("the question must have between 1 and 300 characters")
+	}
+	for _, answer := range strings.Split(answers, "|") {
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			continue
+		}
+		if utf8.RuneCountInString(answer) > maxPollAnswer {
+			return nil, errors.New("the answer " + answer + " is longer than 55 characters")
+		}
+		poll.Answers = append(poll.Answers, answer)
+	}
+	if len(poll.Answers) == 0 || len(poll.Answers) > maxPollAnswers {
+		return nil, errors.New("a poll has between 1 and 10 answers, separated by |")
+	}
+	if duration < 1 || duration > maxPollDuration {
+		return nil, errors.New("a poll lasts between 1 hour and " + strconv.Itoa(maxPollDuration) + " hours")
+	}
+	return poll, nil
+}
+
+func handlePoll(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config := store.GuildConfig(i.GuildID)
+
+	question := ""
+	answers := ""
+	sendTime := ""
+	date := ""
+	duration := 24
+	multiSelect := false
+	var channel *discordgo.Channel
+	for _, option := range options {
+		if option.Name == "question" {
+			question = option.StringValue()
+		} else if option.Name == "answers" {
+			answers = option.StringValue()
+		} else if option.Name == "time" {
+			sendTime = option.StringValue()
+		} else if option.Name == "date" {
+			date = option.StringValue()
+		} else if option.Name == "duration" {
+			duration = int(option.IntValue())
+		} else if option.Name == "multi_select" {
+			multiSelect = option.BoolValue()
+		} else if option.Name == "channel" {
+			channel = option.ChannelValue(s)
+		}
+	}
+
+	poll, err := parsePoll(question, answers, duration, multiSelect)
+	if err != nil {
+		respondError(s, i, "Error scheduling poll", err)
+		return
+	}
+	if channel == nil {
+		channel, err = s.Channel(i.ChannelID)
+		if err != nil {
+			logger.Error("Error scheduling poll: ", "error", err)
+			respondError(s, i, "Error scheduling poll", discordError("cannot get this channel", err))
+			return
+		}
+	}
+	err = checkChannel(channel, false)
+	if err == nil {
+		err = checkCanPost(s, interactionUser(i).ID, channel, poll.Question, nil)
+	}
+	if err != nil {
+		respondError(s, i, "Error scheduling poll", err)
+		return
+	}
+
+	if date == "" {
+		date = time.Now().In(config.location()).Format("02/01/2006")
+	}
+	fixedTime, err := parseSendTime(date, sendTime, config)
+	if err != nil {
+		logger.Error("Error scheduling poll: ", "error", err)
+		respondError(s, i, "Error scheduling poll", err)
+		return
+	}
+	sch := &Schedule{
+		GuildID:     channel.GuildID,
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		AuthorID:    interactionUser(i).ID,
+		Thread:      channel.IsThread(),
+		Poll:        poll,
+		SendAt:      fixedTime,
+	}
+	startSchedule(s, sch)
+	logger.Info("Poll scheduled", "question", poll.Question, "answers", len(poll.Answers), "channel", channel.Name, "date", date, "sendTime", sendTime)
+	respondEmbed(s, i, scheduledEmbed("Poll scheduled", sch))
+}
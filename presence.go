@@ -0,0 +1,95 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// presencePeriod is how often the presence of the bot is refreshed, Discord
+// rate limits the presence updates.
+const presencePeriod = time.Minute
+
+var (
+	presenceMu   sync.Mutex
+	lastPresence string
+)
+
+// showPresence keeps the status of the bot showing how many messages are
+// pending and when the next one is sent, so the members see at a glance that
+// the bot is alive.
+func showPresence(s *discordgo.Session, enabled bool) {
+	if !enabled {
+		return
+	}
+	// the presence is lost when the gateway session is resumed from scratch,
+	// it is set again on the next refresh
+	s.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
+		presenceMu.Lock()
+		lastPresence = ""
+		presenceMu.Unlock()
+	})
+	go func() {
+		defer recoverPanics(map[string]string{"task": "presence"})
+		ticker := time.NewTicker(presencePeriod)
+		defer ticker.Stop()
+		for {
+			updatePresence(s)
+			<-ticker.C
+		}
+	}()
+}
+
+// updatePresence sets the status of the bot if it changed since the last
+// update.
+func updatePresence(s *discordgo.Session) {
+	count, next := schedules.next()
+	status := presenceText(count, next, time.Now())
+	presenceMu.Lock()
+	defer presenceMu.Unlock()
+	if status == lastPresence {
+		return
+	}
+	err := s.UpdateCustomStatus(status)
+	if err != nil {
+		logger.Error("Error updating presence", "error", err)
+		return
+	}
+	lastPresence = status
+}
+
+// presenceText describes the queue, like "⏳ 12 scheduled, next in 2h".
+func presenceText(count int, next time.Time, now time.Time) string {
+	if count == 0 {
+		return "⏳ Nothing scheduled"
+	}
+	status := "⏳ " + strconv.Itoa(count) + " scheduled"
+	wait := next.Sub(now)
+	switch {
+	case wait < time.Minute:
+		status += ", next now"
+	case wait < time.Hour:
+		status += ", next in " + strconv.Itoa(int(wait/time.Minute)) + "m"
+	case wait < 24*time.Hour:
+		status += ", next in " + strconv.Itoa(int(wait/time.Hour)) + "h"
+	default:
+		status += ", next in " + strconv.Itoa(int(wait/(24*time.Hour))) + "d"
+	}
+	return status
+}
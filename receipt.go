@@ -0,0 +1,74 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strings"
+)
+
+// messageLink returns the link to jump to the message.
+func messageLink(message *discordgo.Message, guildID string) string {
+	if guildID == "" {
+		guildID = "@me"
+	}
+	return "https://discord.com/channels/" + guildID + "/" + message.ChannelID + "/" + message.ID
+}
+
+// sendReceipt tells the author in DM whether the schedule was delivered, with
+// links to the sent messages, as a failure would otherwise only be logged.
+func sendReceipt(s *discordgo.Session, sch *Schedule, sent []*discordgo.Message, deliveryErr error) {
+	// the message sent in DM is its own receipt
+	if sch.DM && deliveryErr == nil {
+		return
+	}
+
+	content := "Your message `" + sch.ID + "` for " + sch.target()
+	if sch.Action != "" {
+		content = "Your " + sch.Action + " `" + sch.ID + "` in " + sch.target()
+	}
+	if deliveryErr != nil {
+		content += " failed: " + deliveryErr.Error()
+	} else {
+		content += " is done."
+	}
+	links := []string{}
+	for _, message := range sent {
+		links = append(links, messageLink(message, sch.GuildID))
+	}
+	if len(links) > 0 {
+		content += "\n" + strings.Join(links, "\n")
+	}
+
+	err := notifyAuthor(s, sch, content)
+	if err != nil {
+		logger.Error("Error sending delivery receipt", "error", err, "id", sch.ID, "author", sch.AuthorID)
+	}
+}
+
+// notifyAuthor sends content to the author of the schedule in DM, without
+// pinging anyone.
+func notifyAuthor(s *discordgo.Session, sch *Schedule, content string) error {
+	channel, err := s.UserChannelCreate(sch.AuthorID)
+	if err != nil {
+		return err
+	}
+	_, err = s.ChannelMessageSendComplex(channel.ID, &discordgo.MessageSend{
+		Content:         truncate(content, maxMessageLength),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
@@ -0,0 +1,220 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"strconv"
+	"time"
+)
+
+// Recurrence rules of the schedules.
+const (
+	repeatDaily    = "daily"
+	repeatWeekdays = "weekdays"
+	repeatWeekly   = "weekly"
+	repeatMonthly  = "monthly"
+)
+
+// repeatChoices returns the choices of the recurrence options.
+func repeatChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, repeat := range []string{repeatDaily, repeatWeekdays, repeatWeekly, repeatMonthly} {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: repeat, Value: repeat})
+	}
+	return choices
+}
+
+// nextOccurrence returns the first occurrence of the rule after now, starting
+// from t. The days are counted in the location of t, so that the time of day
+// is kept across the changes of daylight saving time. The monthly occurrences
+// fall on day, or on the last day of the months shorter than it.
+func nextOccurrence(t time.Time, repeat string, day int, now time.Time) time.Time {
+	if day == 0 {
+		day = t.Day()
+	}
+	for !t.After(now) {
+		switch repeat {
+		case repeatWeekdays:
+			t = t.AddDate(0, 0, 1)
+			for t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+				t = t.AddDate(0, 0, 1)
+			}
+		case repeatWeekly:
+			t = t.AddDate(0, 0, 7)
+		case repeatMonthly:
+			// the next month is counted from its first day, AddDate would
+			// overflow the 31st into the month after it
+			year, month, _ := t.Date()
+			last := time.Date(year, month+2, 0, 0, 0, 0, 0, t.Location()).Day()
+			t = time.Date(year, month+1, min(day, last), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		default:
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+	return t
+}
+
+// repeatSchedule schedules the next occurrence of a recurring schedule under
+// the same ID, so that it can be managed as one.
+func repeatSchedule(s *discordgo.Session, sch *Schedule) {
+	next := *sch
+	sendAt := sch.SendAt.In(store.GuildConfig(sch.GuildID).location())
+	if sch.Repeat == repeatMonthly {
+		next.RepeatDay = sch.repeatDay(sendAt)
+	}
+	next.SendAt = nextOccurrence(sendAt, sch.Repeat, next.RepeatDay, time.Now())
+	schedules.readd(&next)
+	watchSchedule(s, &next)
+	logger.Info("Next occurrence scheduled", "id", next.ID, "repeat", next.Repeat, "time", next.SendAt)
+}
+
+// occurrences returns the send times of the schedule before end, in the
+// location of end, every occurrence of a recurring one.
+func (sch *Schedule) occurrences(end time.Time) []time.Time {
+	sendAt := sch.SendAt.In(end.Location())
+	day := sch.repeatDay(sendAt)
+	times := []time.Time{}
+	for sendAt.Before(end) {
+		times = append(times, sendAt)
+		if sch.Repeat == "" {
+			break
+		}
+		sendAt = nextOccurrence(sendAt, sch.Repeat, day, sendAt)
+	}
+	return times
+}
+
+// repeatDay returns the day of the month of the monthly occurrences of the
+// schedule sent at t: the day of the first occurrence, kept through the
+// shorter months, unless the send time was changed since.
+func (sch *Schedule) repeatDay(t time.Time) int {
+	lastDay := t.AddDate(0, 0, 1).Day() == 1
+	if sch.RepeatDay > t.Day() && lastDay {
+		return sch.RepeatDay
+	}
+	return t.Day()
+}
+
+// remindersCommand is the name of the command managing the recurring
+// reminders of its user.
+const remindersCommand = "reminders"
+
+// Ways of managing the recurring reminders and the campaigns.
+const (
+	manageList   = "list"
+	managePause  = "pause"
+	manageResume = "resume"
+	manageCancel = "cancel"
+)
+
+// manageChoices returns the ways of managing as choices for a command option.
+func manageChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, action := range []string{manageList, managePause, manageResume, manageCancel} {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: action, Value: action})
+	}
+	return choices
+}
+
+// remindersCommandDefinition returns the /reminders command.
+func remindersCommandDefinition() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        remindersCommand,
+		Description: "Manages your recurring reminders",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "action",
+				Description: "What to do",
+				Required:    true,
+				Choices:     manageChoices(),
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "id",
+				Description: "[Optionnal] The ID of the reminder, except for the list",
+				Required:    false,
+			},
+		},
+	}
+}
+
+// handleReminders lists, pauses, resumes or cancels the recurring reminders
+// of the user, in any server.
+func handleReminders(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	user := interactionUser(i)
+	action := manageList
+	id := ""
+	for _, option := range options {
+		if option.Name == "action" {
+			action = option.StringValue()
+		} else if option.Name == "id" {
+			id = option.StringValue()
+		}
+	}
+
+	if action == manageList {
+		content := ""
+		for _, sch := range schedules.author(user.ID) {
+			if sch.Repeat == "" {
+				continue
+			}
+			content += "- `" + sch.ID + "` " + sch.Repeat + ", next <t:" + strconv.FormatInt(sch.SendAt.Unix(), 10) + ":R>"
+			if sch.Paused {
+				content += " (paused)"
+			}
+			content += ": " + sch.preview() + "\n"
+		}
+		if content == "" {
+			content = "You have no recurring reminders."
+		}
+		respondEphemeral(s, i, truncate(content, maxMessageLength))
+		return
+	}
+
+	sch := schedules.get(id)
+	if sch == nil || sch.AuthorID != user.ID || sch.Repeat == "" {
+		respondError(s, i, "Error managing reminder", errors.New("you have no recurring reminder with ID "+id))
+		return
+	}
+	switch action {
+	case manageCancel:
+		if !schedules.remove(sch) {
+			respondError(s, i, "Error managing reminder", errors.New("it is being sent, try again"))
+			return
+		}
+		audit(s, AuditCancelled, user.ID, sch)
+		respondEphemeral(s, i, "Reminder cancelled!")
+	case managePause, manageResume:
+		// the schedule is replaced by a copy, which the dispatcher may be
+		// reading
+		changed := *sch
+		changed.Paused = action == managePause
+		if schedules.replace(&changed) == nil {
+			respondError(s, i, "Error managing reminder", errors.New("it is being sent, try again"))
+			return
+		}
+		watchSchedule(s, &changed)
+		if changed.Paused {
+			respondEphemeral(s, i, "Reminder paused, its occurrences are skipped until you resume it.")
+		} else {
+			respondEphemeral(s, i, "Reminder resumed!")
+		}
+	}
+	logger.Info("Reminder managed", "id", id, "action", action, "user", user.ID)
+}
@@ -0,0 +1,64 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	friday := time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)
+	if next := nextOccurrence(friday, repeatWeekdays, 0, friday); next.Weekday() != time.Monday || next.Hour() != 9 {
+		t.Errorf("weekdays after a Friday: got %v", next)
+	}
+	if next := nextOccurrence(friday, repeatDaily, 0, friday.AddDate(0, 0, 3)); !next.Equal(friday.AddDate(0, 0, 4)) {
+		t.Errorf("daily after a pause: got %v", next)
+	}
+
+	// a monthly schedule of the 31st falls on the last day of the shorter
+	// months, then on the 31st again
+	next := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+	for _, want := range []string{"2025-02-28", "2025-03-31", "2025-04-30", "2025-05-31"} {
+		next = nextOccurrence(next, repeatMonthly, 31, next)
+		if got := next.Format(time.DateOnly); got != want {
+			t.Fatalf("monthly on the 31st: got %s, want %s", got, want)
+		}
+	}
+
+	// the day is read from the clamped occurrence only if it was not edited
+	sch := &Schedule{Repeat: repeatMonthly, RepeatDay: 31}
+	if day := sch.repeatDay(time.Date(2025, 2, 28, 9, 0, 0, 0, time.UTC)); day != 31 {
+		t.Errorf("clamped occurrence: got day %d", day)
+	}
+	if day := sch.repeatDay(time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)); day != 15 {
+		t.Errorf("edited occurrence: got day %d", day)
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	friday := time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)
+	end := friday.AddDate(0, 0, 7)
+	if got := len((&Schedule{SendAt: friday, Repeat: repeatWeekdays}).occurrences(end)); got != 5 {
+		t.Errorf("weekdays over a week: got %d occurrences", got)
+	}
+	if got := len((&Schedule{SendAt: friday}).occurrences(end)); got != 1 {
+		t.Errorf("single schedule: got %d occurrences", got)
+	}
+	if got := len((&Schedule{SendAt: end}).occurrences(end)); got != 0 {
+		t.Errorf("schedule after the end: got %d occurrences", got)
+	}
+}
@@ -0,0 +1,87 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// watchReload reloads the configuration when the bot receives SIGHUP. The
+// pending schedules are left untouched.
+func watchReload(args []string) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		defer recoverPanics(map[string]string{"task": "reload"})
+		for range hangup {
+			logger.Info("Reloading configuration")
+			err := reloadConfig(args)
+			if err != nil {
+				logger.Error("Error reloading configuration", "error", err)
+				continue
+			}
+			logger.Info("Configuration reloaded")
+		}
+	}()
+}
+
+// reloadConfig reads the configuration file, the environment and the flags
+// again, and applies what can change without a restart: the runtimeSettings,
+// published at once, the logs, the alert thresholds and the configurations of
+// the servers in the store. The other settings, like the token, the addresses
+// and the command registration, are only read at startup. The previous
+// settings are kept if the new ones are invalid.
+func reloadConfig(args []string) error {
+	environ := os.Environ()
+	previousKeys := fileKeys
+
+	// the settings removed from the file fall back to their default
+	for _, key := range fileKeys {
+		os.Unsetenv(key)
+	}
+	fileKeys = nil
+	err := applyConfig(args)
+	if err == nil {
+		err = checkSettings()
+	}
+	var runtime *runtimeSettings
+	if err == nil {
+		runtime, err = readRuntimeSettings()
+	}
+	if err == nil {
+		err = setupLogging()
+	}
+	if err != nil {
+		os.Clearenv()
+		for _, variable := range environ {
+			key, value, _ := strings.Cut(variable, "=")
+			os.Setenv(key, value)
+		}
+		fileKeys = previousKeys
+		return err
+	}
+	currentSettings.Store(runtime)
+
+	token, secondaryToken := os.Getenv("DISCORD_TOKEN"), os.Getenv("DISCORD_TOKEN_SECONDARY")
+	if token != "" && token != Token || secondaryToken != "" && secondaryToken != SecondaryToken {
+		logger.Warn("The token changed, restart the bot to use it")
+	}
+	readFailureThresholds()
+	return store.reloadGuilds()
+}
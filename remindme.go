@@ -0,0 +1,248 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"time"
+)
+
+// remindmeCommand is the name of the command scheduling a reminder for
+// oneself.
+const remindmeCommand = "remindme"
+
+// remindMessageCommand is the name of the context menu command of the
+// messages scheduling a reminder of them.
+const remindMessageCommand = "Remind me about this"
+
+// remindMessagePrefix starts the custom ID of the modal of remindMessageCommand,
+// followed by the link of the message.
+const remindMessagePrefix = "remind:"
+
+// remindmeCommandDefinition returns the /remindme command.
+func remindmeCommandDefinition() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        remindmeCommand,
+		Description: "Schedules a reminder for yourself",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "note",
+				Description: "What to remind you of",
+				Required:    true,
+				MaxLength:   1900,
+			},
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "time",
+				Description:  "The time of the reminder (HH:MM)",
+				Required:     true,
+				Autocomplete: true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "date",
+				Description: "[Optionnal] The date of the reminder (dd/mm/yyyy). Default: today",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "here",
+				Description: "[Optionnal] Ping you in this channel instead of a DM",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "repeat",
+				Description: "[Optionnal] Remind you again at every occurrence, manage it with /reminders",
+				Required:    false,
+				Choices:     repeatChoices(),
+			},
+		},
+	}
+}
+
+// handleRemindme schedules the note to be sent to its author in DM, or in
+// the current channel with a ping.
+func handleRemindme(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	config := store.GuildConfig(i.GuildID)
+	user := interactionUser(i)
+
+	note := ""
+	sendTime := ""
+	date := ""
+	here := false
+	repeat := ""
+	for _, option := range options {
+		if option.Name == "note" {
+			note = option.StringValue()
+		} else if option.Name == "time" {
+			sendTime = option.StringValue()
+		} else if option.Name == "date" {
+			date = option.StringValue()
+		} else if option.Name == "here" {
+			here = option.BoolValue()
+		} else if option.Name == "repeat" {
+			repeat = option.StringValue()
+		}
+	}
+
+	channel, err := s.Channel(i.ChannelID)
+	if err != nil {
+		logger.Error("Error scheduling reminder: ", "error", err)
+		respondError(s, i, "Error scheduling reminder", discordError("cannot get this channel", err))
+		return
+	}
+	// the reminders of a DM are sent there anyway
+	dm := !here || i.GuildID == ""
+	content := "⏰ Reminder: " + note
+	if !dm {
+		content = user.Mention() + " " + content
+		err = checkChannel(channel, false)
+		if err == nil {
+			err = checkCanPost(s, user.ID, channel, content, nil)
+		}
+		if err != nil {
+			respondError(s, i, "Error scheduling reminder", err)
+			return
+		}
+	}
+
+	if date == "" {
+		date = time.Now().In(config.location()).Format("02/01/2006")
+	}
+	fixedTime, err := parseSendTime(date, sendTime, config)
+	if err != nil {
+		logger.Error("Error scheduling reminder: ", "error", err)
+		respondError(s, i, "Error scheduling reminder", err)
+		return
+	}
+
+	sch := &Schedule{
+		GuildID:     i.GuildID,
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		AuthorID:    user.ID,
+		Content:     content,
+		// only the author is pinged
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{user.ID}},
+		DM:              dm,
+		Repeat:          repeat,
+		SendAt:          fixedTime,
+	}
+	startSchedule(s, sch)
+	logger.Info("Reminder scheduled", "date", date, "sendTime", sendTime, "dm", dm, "repeat", repeat, "user", user.ID)
+	// the reminders are personal, so they are never shown to the channel
+	respondEphemeralEmbed(s, i, scheduledEmbed("Reminder scheduled", sch))
+}
+
+// handleRemindMessage opens a modal asking when to be reminded of the
+// message the command was used on.
+func handleRemindMessage(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	config := store.GuildConfig(i.GuildID)
+	data := i.ApplicationCommandData()
+	message := data.Resolved.Messages[data.TargetID]
+	if message == nil {
+		respond(s, i, "Cannot find the message to be reminded of")
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: remindMessagePrefix + messageLink(message, i.GuildID),
+			Title:    "Remind me about this message",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "time",
+						Label:       "Time (HH:MM)",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "18:00",
+						Required:    true,
+						MinLength:   5,
+						MaxLength:   5,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "date",
+						Label:       "Date (dd/mm/yyyy), default: today",
+						Style:       discordgo.TextInputShort,
+						Placeholder: time.Now().In(config.location()).Format("02/01/2006"),
+						Required:    false,
+						MaxLength:   10,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "note",
+						Label:     "Note",
+						Style:     discordgo.TextInputParagraph,
+						Required:  false,
+						MaxLength: 1000,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error opening reminder modal", "error", err)
+	}
+}
+
+// handleRemindMessageSubmit schedules a DM to the author of the modal with
+// the link of the message and their note.
+func handleRemindMessageSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	link := strings.TrimPrefix(data.CustomID, remindMessagePrefix)
+	values := modalValues(data)
+
+	config := store.GuildConfig(i.GuildID)
+	date := values["date"]
+	if date == "" {
+		date = time.Now().In(config.location()).Format("02/01/2006")
+	}
+	fixedTime, err := parseSendTime(date, values["time"], config)
+	if err != nil {
+		logger.Error("Error scheduling reminder: ", "error", err)
+		respondError(s, i, "Error scheduling reminder", err)
+		return
+	}
+
+	content := "⏰ Reminder about " + link
+	if values["note"] != "" {
+		content += "\n" + values["note"]
+	}
+	user := interactionUser(i)
+	sch := &Schedule{
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		AuthorID:  user.ID,
+		Content:   content,
+		// only the author is pinged
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{user.ID}},
+		DM:              true,
+		SendAt:          fixedTime,
+	}
+	if channel, err := s.State.Channel(i.ChannelID); err == nil {
+		sch.ChannelName = channel.Name
+	}
+	startSchedule(s, sch)
+	logger.Info("Reminder scheduled", "link", link, "date", date, "sendTime", values["time"], "user", user.ID)
+	respondEphemeralEmbed(s, i, scheduledEmbed("Reminder scheduled", sch))
+}
@@ -0,0 +1,215 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"time"
+)
+
+// repostCommand is the name of the context menu command of the messages
+// scheduling a copy of them.
+const repostCommand = "Schedule repost"
+
+// repostPrefix starts the custom ID of the repost modal, followed by the IDs
+// of the channel and of the message to copy, separated by a colon.
+const repostPrefix = "repost:"
+
+// handleRepost opens a modal asking when and where to repost the message the
+// command was used on, with its content to edit.
+func handleRepost(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	config := store.GuildConfig(i.GuildID)
+	data := i.ApplicationCommandData()
+	message := data.Resolved.Messages[data.TargetID]
+	if message == nil {
+		respond(s, i, "Cannot find the message to repost")
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: repostPrefix + message.ChannelID + ":" + message.ID,
+			Title:    "Schedule a repost",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "message",
+						Label:     "Message, the attachments are copied too",
+						Style:     discordgo.TextInputParagraph,
+						Value:     truncate(message.Content, maxMessageLength),
+						Required:  false,
+						MaxLength: maxMessageLength,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "channel",
+						Label:       "Channel (#name, ID or link)",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "default: this channel",
+						Required:    false,
+						MaxLength:   200,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "time",
+						Label:       "Time (HH:MM)",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "18:00",
+						Required:    true,
+						MinLength:   5,
+						MaxLength:   5,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "date",
+						Label:       "Date (dd/mm/yyyy), default: today",
+						Style:       discordgo.TextInputShort,
+						Placeholder: time.Now().In(config.location()).Format("02/01/2006"),
+						Required:    false,
+						MaxLength:   10,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Error opening repost modal", "error", err)
+	}
+}
+
+// handleRepostSubmit schedules the copy of the message with the content,
+// channel and time of the repost modal.
+func handleRepostSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	sourceChannelID, messageID, _ := strings.Cut(strings.TrimPrefix(data.CustomID, repostPrefix), ":")
+	values := modalValues(data)
+
+	// downloading the attachments can take longer than the time allowed to
+	// respond
+	deferResponse(s, i, replyFlags())
+
+	// we fetch the message again as the URLs of its attachments expire
+	source, err := s.ChannelMessage(sourceChannelID, messageID)
+	if err != nil {
+		logger.Error("Error scheduling repost: ", "error", err)
+		respondError(s, i, "Error scheduling repost", errors.New("Cannot get the message to repost: "+err.Error()))
+		return
+	}
+
+	channel, err := s.Channel(i.ChannelID)
+	if values["channel"] != "" {
+		channel, err = findChannel(s, i.GuildID, values["channel"])
+	}
+	if err != nil {
+		respondError(s, i, "Error scheduling repost", err)
+		return
+	}
+
+	err = checkChannel(channel, false)
+	if err == nil {
+		err = checkCanPost(s, interactionUser(i).ID, channel, values["message"], nil)
+	}
+	if err != nil {
+		respondError(s, i, "Error scheduling repost", err)
+		return
+	}
+
+	config := store.GuildConfig(i.GuildID)
+	date := values["date"]
+	if date == "" {
+		date = time.Now().In(config.location()).Format("02/01/2006")
+	}
+	fixedTime, err := parseSendTime(date, values["time"], config)
+	if err != nil {
+		logger.Error("Error scheduling repost: ", "error", err)
+		respondError(s, i, "Error scheduling repost", err)
+		return
+	}
+
+	files := []ScheduledFile{}
+	for _, attachment := range source.Attachments {
+		err := checkAttachmentSize(attachment.Filename, attachment.Size)
+		if err != nil {
+			respondError(s, i, "Error scheduling repost", err)
+			return
+		}
+		file, err := storeAttachment(interactionContext(i), attachment.URL, attachment.Filename, attachment.ContentType)
+		if err != nil {
+			respondError(s, i, "Error scheduling repost", err)
+			return
+		}
+		files = append(files, file)
+	}
+	// the embeds generated by Discord for the links are generated again
+	embeds := []*discordgo.MessageEmbed{}
+	for _, embed := range source.Embeds {
+		if embed.Type == discordgo.EmbedTypeRich {
+			embeds = append(embeds, embed)
+		}
+	}
+	if values["message"] == "" && len(files) == 0 && len(embeds) == 0 {
+		respond(s, i, "Nothing to repost, the message is empty")
+		return
+	}
+
+	sch := &Schedule{
+		GuildID:     channel.GuildID,
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		AuthorID:    interactionUser(i).ID,
+		Content:     values["message"],
+		Embeds:      embeds,
+		Files:       files,
+		SendAt:      fixedTime,
+	}
+	startSchedule(s, sch)
+	logger.Info("Repost scheduled", "source", source.ID, "files", len(files), "date", date, "sendTime", values["time"], "channel", channel.Name)
+	respondEmbed(s, i, scheduledEmbed("Repost scheduled", sch))
+}
+
+// findChannel returns the channel given by a mention, an ID, a link or the
+// name of a channel of the guild.
+func findChannel(s *discordgo.Session, guildID string, text string) (*discordgo.Channel, error) {
+	ids, err := parseLink(text)
+	if err == nil {
+		// links start with the ID of the guild and may end with the one of a
+		// message
+		if len(ids) > 1 {
+			return s.Channel(ids[1])
+		}
+		return s.Channel(ids[0])
+	}
+
+	name := strings.TrimPrefix(strings.TrimSpace(text), "#")
+	if guildID != "" {
+		channels, err := s.GuildChannels(guildID)
+		if err != nil {
+			return nil, errors.New("Error getting the channels of the server: " + err.Error())
+		}
+		for _, channel := range channels {
+			if channel.Name == name {
+				return channel, nil
+			}
+		}
+	}
+	return nil, errors.New("No channel named #" + name)
+}
@@ -0,0 +1,63 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+// maxRetryDelay caps the delay between two attempts to deliver a message.
+const maxRetryDelay = time.Minute
+
+// transientError reports whether the request may succeed if sent again: the
+// network failed, Discord had an outage or asked to slow down. The other
+// answers of Discord, like a missing permission, won't change by retrying.
+func transientError(err error) bool {
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) {
+		if restErr.Response == nil {
+			return true
+		}
+		status := restErr.Response.StatusCode
+		return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// withRetries calls send until it succeeds, fails with an error that is not
+// transient, or the retries of the settings are made. The delay between the
+// attempts doubles each time, with jitter so that the messages failing
+// together are not all sent again at once.
+func withRetries(sch *Schedule, send func() error) error {
+	current := settings()
+	delay := current.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := send()
+		if err == nil || attempt >= current.deliveryRetries || !transientError(err) {
+			return err
+		}
+		wait := delay/2 + rand.N(delay/2+1)
+		logger.Warn("Delivery failed, retrying", "error", err, "id", sch.ID, "attempt", attempt+1, "wait", wait)
+		time.Sleep(wait)
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
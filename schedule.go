@@ -0,0 +1,1023 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Schedule is a message waiting to be sent.
+type Schedule struct {
+	ID          string `json:"id"`
+	GuildID     string `json:"guild_id"`
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	AuthorID    string `json:"author_id"`
+	Content     string `json:"content,omitempty"`
+	// Files are uploaded along with the content.
+	Files []ScheduledFile `json:"files,omitempty"`
+	// Embeds are sent below the content.
+	Embeds []*discordgo.MessageEmbed `json:"embeds,omitempty"`
+	// AllowedMentions restricts who is pinged, nil pings the users only.
+	AllowedMentions *discordgo.MessageAllowedMentions `json:"allowed_mentions,omitempty"`
+	// TTS reads the message aloud with text-to-speech.
+	TTS bool `json:"tts,omitempty"`
+	// Silent suppresses the push and desktop notifications.
+	Silent bool `json:"silent,omitempty"`
+	// Spoiler hides the content and the files behind a spoiler.
+	Spoiler bool `json:"spoiler,omitempty"`
+	// DM sends the message to the author in DM instead of the channel, the
+	// channel is the one the message was scheduled from.
+	DM bool `json:"dm,omitempty"`
+	// Thread is set when the channel is a thread, which is unarchived if
+	// needed before sending.
+	Thread bool `json:"thread,omitempty"`
+	// ForumTitle is set when the channel is a forum, the message then starts
+	// a new post with this title and the tags ForumTagIDs.
+	ForumTitle  string   `json:"forum_title,omitempty"`
+	ForumTagIDs []string `json:"forum_tag_ids,omitempty"`
+	// ExtraChannelIDs are other channels receiving the same message.
+	ExtraChannelIDs []string `json:"extra_channel_ids,omitempty"`
+	// Crosspost publishes the message of an announcement channel to the
+	// servers following it.
+	Crosspost bool `json:"crosspost,omitempty"`
+	// ReplyToID is the message of the channel the schedule replies to, its
+	// author is pinged if the users are.
+	ReplyToID string `json:"reply_to_id,omitempty"`
+	// Sender is who the message is sent as, senderBot when empty. The name and
+	// avatar of the author are kept for senderMe.
+	Sender          string `json:"sender,omitempty"`
+	SenderName      string `json:"sender_name,omitempty"`
+	SenderAvatarURL string `json:"sender_avatar_url,omitempty"`
+	// Pin pins the message once sent, pinReplace also unpins the previous
+	// messages of the bot.
+	Pin string `json:"pin,omitempty"`
+	// DeleteAfter is how long the sent messages stay before being deleted,
+	// zero keeps them.
+	DeleteAfter time.Duration `json:"delete_after,omitempty"`
+	// Countdown is what a countdown posted in the channel until the message is
+	// sent announces, the message then replaces it.
+	Countdown string `json:"countdown,omitempty"`
+	// Repeat sends the schedule again at every occurrence of the rule, unless
+	// Paused is set, in which case the occurrences are skipped.
+	Repeat string `json:"repeat,omitempty"`
+	Paused bool   `json:"paused,omitempty"`
+	// RepeatDay is the day of the month of a monthly schedule, which falls
+	// on the last day of the months shorter than it, see repeatDay.
+	RepeatDay int `json:"repeat_day,omitempty"`
+	// CampaignID is shared by the steps of a campaign, which are held while
+	// it is paused since PausedAt, and delayed by the pause once resumed.
+	CampaignID string    `json:"campaign_id,omitempty"`
+	PausedAt   time.Time `json:"paused_at,omitempty"`
+	// NotifyBefore is how long before sending the author is told in DM, with
+	// buttons to cancel or send now, zero doesn't tell them.
+	NotifyBefore time.Duration `json:"notify_before,omitempty"`
+	// Action is applied to the existing message MessageID of the channel
+	// instead of sending a new message.
+	Action    string `json:"action,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+	// Reactions are the emojis added by actionReact.
+	Reactions []string `json:"reactions,omitempty"`
+	// Discussion is the name of a thread started on the sent message, archived
+	// after DiscussionArchive minutes of inactivity.
+	Discussion        string `json:"discussion,omitempty"`
+	DiscussionArchive int    `json:"discussion_archive,omitempty"`
+	// Poll is sent instead of the content when set.
+	Poll *SchedulePoll `json:"poll,omitempty"`
+	// AwaitingApproval is set until a moderator approves the schedule, it is
+	// not sent otherwise.
+	AwaitingApproval bool      `json:"awaiting_approval,omitempty"`
+	SendAt           time.Time `json:"send_at"`
+}
+
+// ScheduledFile is a file downloaded when scheduling, as the attachment URLs
+// given by Discord expire before the message is sent.
+type ScheduledFile struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data,omitempty"`
+	// Path is where the file is stored instead of Data, until it is sent.
+	Path string `json:"path,omitempty"`
+}
+
+// targets returns the channels the schedule is sent to, its own first.
+func (sch *Schedule) targets() []string {
+	return append([]string{sch.ChannelID}, sch.ExtraChannelIDs...)
+}
+
+// retarget returns a copy of the schedule sent only to the given channels
+// among its targets. When its own channel is not one of them, the first of the
+// others takes its place, without the steps that only apply to its own.
+func (sch *Schedule) retarget(s *discordgo.Session, channelIDs []string) *Schedule {
+	copied := *sch
+	copied.ExtraChannelIDs = []string{}
+	for _, channelID := range channelIDs {
+		if channelID != sch.ChannelID {
+			copied.ExtraChannelIDs = append(copied.ExtraChannelIDs, channelID)
+		}
+	}
+	if slices.Contains(channelIDs, sch.ChannelID) || len(copied.ExtraChannelIDs) == 0 {
+		return &copied
+	}
+	copied.ChannelID = copied.ExtraChannelIDs[0]
+	copied.ExtraChannelIDs = copied.ExtraChannelIDs[1:]
+	copied.ChannelName = copied.ChannelID
+	copied.Thread = false
+	if channel, err := s.State.Channel(copied.ChannelID); err == nil {
+		copied.ChannelName = channel.Name
+		copied.Thread = channel.IsThread()
+	}
+	copied.DM = false
+	copied.ForumTitle = ""
+	copied.ForumTagIDs = nil
+	copied.Crosspost = false
+	copied.ReplyToID = ""
+	copied.Pin = ""
+	copied.Countdown = ""
+	copied.Discussion = ""
+	return &copied
+}
+
+// target returns a mention of where the schedule is sent.
+func (sch *Schedule) target() string {
+	if sch.DM {
+		return "DM to <@" + sch.AuthorID + ">"
+	}
+	target := "<#" + sch.ChannelID + ">"
+	for _, extraID := range sch.ExtraChannelIDs {
+		target += " <#" + extraID + ">"
+	}
+	return target
+}
+
+// visibleTo reports whether the schedule is shown to the member in the lists
+// of its guild, the messages sent in DM being private to their author.
+func (sch *Schedule) visibleTo(userID string) bool {
+	return !sch.DM || sch.AuthorID == userID
+}
+
+// preview returns the content of the schedule, or the title of its embed, or
+// the names of its files.
+func (sch *Schedule) preview() string {
+	if sch.Action == actionEdit {
+		return "✏️ " + sch.Content
+	}
+	if sch.Action == actionDelete {
+		return "🗑️ " + sch.Content
+	}
+	if sch.Action == actionReact {
+		return "Reactions " + strings.Join(sch.Reactions, " ")
+	}
+	if sch.Poll != nil {
+		return "📊 " + sch.Poll.Question
+	}
+	if sch.Content != "" {
+		return sch.Content
+	}
+	if len(sch.Embeds) > 0 {
+		if sch.Embeds[0].Title != "" {
+			return sch.Embeds[0].Title
+		}
+		return sch.Embeds[0].Description
+	}
+	names := []string{}
+	for _, file := range sch.Files {
+		names = append(names, "📎 "+file.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+// scheduleRegistry keeps track of the pending schedules.
+type scheduleRegistry struct {
+	mu      sync.Mutex
+	pending map[string]*Schedule
+}
+
+var schedules = &scheduleRegistry{pending: map[string]*Schedule{}}
+
+// add registers the schedule under a new random ID.
+func (r *scheduleRegistry) add(sch *Schedule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		sch.ID = newID()
+		if _, exists := r.pending[sch.ID]; !exists {
+			break
+		}
+	}
+	r.pending[sch.ID] = sch
+	pendingChanged()
+}
+
+// readd registers the schedule under its own ID, for the next occurrence of
+// a recurring schedule, or under a new one if it was taken in the meantime.
+func (r *scheduleRegistry) readd(sch *Schedule) {
+	r.mu.Lock()
+	if _, exists := r.pending[sch.ID]; !exists {
+		r.pending[sch.ID] = sch
+		r.mu.Unlock()
+		pendingChanged()
+		return
+	}
+	r.mu.Unlock()
+	r.add(sch)
+}
+
+// take removes the schedule with the given ID and returns it, or nil if it is
+// not pending anymore.
+func (r *scheduleRegistry) take(id string) *Schedule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sch := r.pending[id]
+	if sch != nil {
+		delete(r.pending, id)
+		pendingChanged()
+	}
+	return sch
+}
+
+// remove removes the schedule if it is still pending, and not replaced by an
+// edited version, and reports whether it was.
+func (r *scheduleRegistry) remove(sch *Schedule) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending[sch.ID] != sch {
+		return false
+	}
+	delete(r.pending, sch.ID)
+	pendingChanged()
+	return true
+}
+
+// replace swaps the pending schedule having the ID of sch for sch, and
+// returns the previous version, or nil if it is not pending anymore.
+func (r *scheduleRegistry) replace(sch *Schedule) *Schedule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous := r.pending[sch.ID]
+	if previous != nil {
+		r.pending[sch.ID] = sch
+		pendingChanged()
+	}
+	return previous
+}
+
+// get returns the pending schedule with the given ID, or nil.
+func (r *scheduleRegistry) get(id string) *Schedule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pending[id]
+}
+
+// guild returns the pending schedules of a guild, sorted by send time.
+func (r *scheduleRegistry) guild(guildID string) []*Schedule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := []*Schedule{}
+	for _, sch := range r.pending {
+		if sch.GuildID == guildID {
+			list = append(list, sch)
+		}
+	}
+	sort.Slice(list, func(a, b int) bool {
+		return list[a].SendAt.Before(list[b].SendAt)
+	})
+	return list
+}
+
+// shared returns the pending schedules of a guild that the member may see,
+// sorted by send time.
+func (r *scheduleRegistry) shared(guildID string, userID string) []*Schedule {
+	list := []*Schedule{}
+	for _, sch := range r.guild(guildID) {
+		if sch.visibleTo(userID) {
+			list = append(list, sch)
+		}
+	}
+	return list
+}
+
+// author returns the pending schedules of an author in every guild, sorted by
+// send time.
+func (r *scheduleRegistry) author(authorID string) []*Schedule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := []*Schedule{}
+	for _, sch := range r.pending {
+		if sch.AuthorID == authorID {
+			list = append(list, sch)
+		}
+	}
+	sort.Slice(list, func(a, b int) bool {
+		return list[a].SendAt.Before(list[b].SendAt)
+	})
+	return list
+}
+
+// campaign returns the pending steps of a campaign, sorted by send time.
+func (r *scheduleRegistry) campaign(campaignID string) []*Schedule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := []*Schedule{}
+	for _, sch := range r.pending {
+		if sch.CampaignID == campaignID {
+			list = append(list, sch)
+		}
+	}
+	sort.Slice(list, func(a, b int) bool {
+		return list[a].SendAt.Before(list[b].SendAt)
+	})
+	return list
+}
+
+// next returns how many schedules are pending and the send time of the next
+// one.
+func (r *scheduleRegistry) next() (int, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := time.Time{}
+	for _, sch := range r.pending {
+		if next.IsZero() || sch.SendAt.Before(next) {
+			next = sch.SendAt
+		}
+	}
+	return len(r.pending), next
+}
+
+// pendingCount returns how many schedules the author has pending in the guild.
+func (r *scheduleRegistry) pendingCount(guildID string, authorID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, sch := range r.pending {
+		if sch.GuildID == guildID && sch.AuthorID == authorID {
+			count++
+		}
+	}
+	return count
+}
+
+// snapshot returns the pending schedules by ID, to save them.
+func (r *scheduleRegistry) snapshot() map[string]*Schedule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.pending)
+}
+
+// filePaths returns the stored files of the pending schedules.
+func (r *scheduleRegistry) filePaths() map[string]bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	paths := map[string]bool{}
+	for _, sch := range r.pending {
+		for _, file := range sch.Files {
+			if file.Path != "" {
+				paths[file.Path] = true
+			}
+		}
+	}
+	return paths
+}
+
+// newID returns a short random identifier for a schedule.
+func newID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// parseSendTime returns the time described by date (dd/mm/yyyy) and sendTime
+// (HH:MM) in the time zone of the guild, which must not be in its quiet hours.
+func parseSendTime(date string, sendTime string, config GuildConfig) (time.Time, error) {
+	// the mistakes in the date or the time are explained to the member
+	if err := checkDate(date, time.Now().In(config.location())); err != nil {
+		return time.Time{}, err
+	}
+	if err := checkTime(sendTime); err != nil {
+		return time.Time{}, err
+	}
+	// Define the fixed time when the message should be sent.
+	fixedTime, err := time.ParseInLocation("02/01/2006 15:04", date+" "+sendTime, config.location())
+	if err != nil {
+		return time.Time{}, &userError{Code: codeInvalidTime, Message: "cannot read " + date + " " + sendTime + ": " + err.Error(), Hint: "The date is dd/mm/yyyy and the time HH:MM."}
+	}
+	logger.Info("Time parsed", "time", fixedTime)
+	if config.inQuietHours(fixedTime) {
+		logger.Debug("Send time refused, quiet hours", "time", fixedTime, "quietHours", config.QuietHours)
+		return time.Time{}, &userError{Code: codeQuietHours, Message: "the server doesn't allow messages during its quiet hours (" + config.QuietHours + ")", Hint: "Choose a time outside of them."}
+	}
+	err = checkSendBounds(fixedTime, time.Now())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fixedTime, nil
+}
+
+// parseSendBounds reads the bounds of the send times, empty values leaving
+// them unbounded.
+func parseSendBounds(min string, max string) (minDelay time.Duration, maxHorizon time.Duration, err error) {
+	if min != "" {
+		minDelay, err = parseDelay(min)
+		if err != nil {
+			return 0, 0, errors.New("Error parsing minimum delay: " + err.Error())
+		}
+	}
+	if max != "" {
+		maxHorizon, err = parseDelay(max)
+		if err != nil {
+			return 0, 0, errors.New("Error parsing maximum horizon: " + err.Error())
+		}
+	}
+	if maxHorizon != 0 && maxHorizon < minDelay {
+		return 0, 0, errors.New("the maximum horizon is shorter than the minimum delay")
+	}
+	return minDelay, maxHorizon, nil
+}
+
+// checkSendBounds returns an error if t is not within the bounds of the send
+// times from now.
+func checkSendBounds(t time.Time, now time.Time) error {
+	current := settings()
+	minDelay, maxHorizon := current.minDelay, current.maxHorizon
+	logger.Debug("Checking send bounds", "time", t, "minDelay", minDelay, "maxHorizon", maxHorizon)
+	if minDelay != 0 && t.Before(now.Add(minDelay)) {
+		return &userError{Code: codeTooSoon, Message: "messages must be scheduled at least " + formatDelay(minDelay) + " in advance", Hint: "Choose a later time, or check how it is read with /sendlater check."}
+	}
+	if maxHorizon != 0 && t.After(now.Add(maxHorizon)) {
+		return &userError{Code: codeTooFar, Message: "messages can't be scheduled more than " + formatDelay(maxHorizon) + " in advance", Hint: "Choose an earlier date, or check how it is read with /sendlater check."}
+	}
+	return nil
+}
+
+// formatDelay writes a delay in days when it is a whole number of them.
+func formatDelay(delay time.Duration) string {
+	day := 24 * time.Hour
+	if delay%day == 0 {
+		return strconv.Itoa(int(delay/day)) + "d"
+	}
+	return delay.String()
+}
+
+// parseDelay reads a delay like 30m, 2h or 1d: a Go duration, or a number of
+// days.
+func parseDelay(text string) (time.Duration, error) {
+	text = strings.TrimSpace(text)
+	if days, found := strings.CutSuffix(text, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	delay, err := time.ParseDuration(text)
+	if err != nil || delay <= 0 {
+		return 0, &userError{Code: codeInvalidDelay, Message: "invalid delay " + text, Hint: "Use a number followed by m, h or d, like 30m, 2h or 1d."}
+	}
+	return delay, nil
+}
+
+// stallDelay is how late a delivery can be before the operators are alerted,
+// the deliveries due at the same time wait for each other so a bit of delay
+// is expected.
+const stallDelay = 5 * time.Minute
+
+// startSchedule registers the schedule and sends it once its time is passed.
+func startSchedule(s *discordgo.Session, sch *Schedule) {
+	sch.AwaitingApproval = needsApproval(s, sch)
+	schedules.add(sch)
+	logger.Debug("Schedule registered", "id", sch.ID, "guild", sch.GuildID, "sendAt", sch.SendAt, "awaitingApproval", sch.AwaitingApproval, "pending", schedules.pendingCount(sch.GuildID, sch.AuthorID))
+	audit(s, AuditScheduled, sch.AuthorID, sch)
+	if sch.AwaitingApproval {
+		requestApproval(s, sch)
+	}
+	watchSchedule(s, sch)
+}
+
+// watchSchedule sends the schedule once its time is passed, unless it was
+// cancelled or replaced in the meantime.
+func watchSchedule(s *discordgo.Session, sch *Schedule) {
+	dispatcher.watch(s, sch)
+}
+
+// dispatch sends the schedule, already removed from the pending ones, and
+// tells its author how it went.
+func dispatch(s *discordgo.Session, sch *Schedule) {
+	defer recoverPanics(map[string]string{"schedule.id": sch.ID, "guild.id": sch.GuildID, "channel.id": sch.ChannelID, "author.id": sch.AuthorID})
+	// the stored files are kept until the next occurrence is pending
+	defer holdFiles(sch)()
+	ctx, sp := startSpan(context.Background(), "deliver", spanKindInternal, "schedule.id", sch.ID, "guild.id", sch.GuildID, "channel.id", sch.ChannelID, "late", time.Since(sch.SendAt).String())
+	var err error
+	defer func() { sp.end(err) }()
+	// the recurring schedules are scheduled again whatever happens, so that a
+	// failure doesn't stop them
+	if sch.Repeat != "" {
+		defer repeatSchedule(s, sch)
+	}
+	if sch.Paused {
+		logger.Info("Paused message skipped", "id", sch.ID)
+		return
+	}
+	// Send a message to the specified channel.
+	logger.Info("Sending message", "message", sch.Content, "files", len(sch.Files), "channel", sch.ChannelName, "id", sch.ID)
+	// nothing is sent if the author or the bot lost access to the channel in
+	// the meantime, the author is told why
+	sent := []*discordgo.Message{}
+	err = errNotApproved
+	if !sch.AwaitingApproval {
+		_, check := startSpan(ctx, "revalidate", spanKindInternal)
+		err = revalidate(s, sch)
+		check.end(err)
+	}
+	if err == nil {
+		// the delivery is recorded, so that it is finished after a crash
+		// without being sent twice
+		endDelivery := beginDelivery(sch)
+		defer endDelivery()
+		_, send := startSpan(ctx, "send", spanKindClient)
+		sent, err = deliver(s, sch)
+		send.end(err)
+		watchDiscordError(err)
+	}
+	if err != nil && !errors.Is(err, errNotApproved) {
+		watchDeliveryFailure(sch, err)
+	}
+	// the message isn't lost when its channel is gone, the author gets it in
+	// DM along with the reason
+	var gone *channelGoneError
+	if errors.As(err, &gone) && sch.Action == "" {
+		fallbackErr := deliverToAuthor(s, sch, gone)
+		if fallbackErr == nil {
+			logger.Warn("Message sent to its author instead", "reason", gone, "id", sch.ID, "channel", sch.ChannelName)
+			audit(s, AuditFailed, sch.AuthorID, sch)
+			return
+		}
+		logger.Error("Error sending message to its author", "error", fallbackErr, "id", sch.ID)
+	}
+	if sch.DeleteAfter > 0 && len(sent) > 0 {
+		deleteLater(s, sch, sent)
+	}
+	_, receipt := startSpan(ctx, "receipt", spanKindClient)
+	sendReceipt(s, sch, sent, err)
+	receipt.end(nil)
+	if err != nil {
+		logger.Error("Error sending message,", "error", err)
+		audit(s, AuditFailed, sch.AuthorID, sch)
+		if !errors.Is(err, errNotApproved) {
+			deadLetter(s, sch, err)
+			reportError("Delivery failed", err, map[string]string{"schedule.id": sch.ID, "guild.id": sch.GuildID, "channel.id": sch.ChannelID, "author.id": sch.AuthorID, "late": time.Since(sch.SendAt).String()})
+		}
+		return
+	}
+	audit(s, AuditSent, sch.AuthorID, sch)
+}
+
+// Pinning modes of the sent messages.
+const (
+	pinAdd     = "pin"
+	pinReplace = "replace"
+)
+
+// maxMessageLength is the maximum number of characters of a Discord message.
+const maxMessageLength = 2000
+
+// deliver sends the schedule to its channel, then to its extra channels, and
+// returns the messages sent, starting with the one of its channel.
+func deliver(s *discordgo.Session, sch *Schedule) ([]*discordgo.Message, error) {
+	channelID := sch.ChannelID
+	if sch.Thread && !sch.DM {
+		err := reviveThread(s, channelID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if sch.Action != "" {
+		return nil, applyAction(s, sch)
+	}
+	if sch.DM {
+		channel, err := s.UserChannelCreate(sch.AuthorID)
+		if err != nil {
+			return nil, errors.New("Error opening DM: " + err.Error())
+		}
+		channelID = channel.ID
+	}
+
+	sent := []*discordgo.Message{}
+	var message *discordgo.Message
+	var err error
+	if sch.ForumTitle != "" && !sch.DM {
+		// the first message of a forum post has the ID of the post
+		var post *discordgo.Channel
+		err = withRetries(sch, func() (err error) {
+			settings().sendLimits.wait(channelID)
+			first := messageSend(sch)
+			defer closeFiles(first.Files)
+			post, err = s.ForumThreadStartComplex(channelID, &discordgo.ThreadStart{
+				Name:        sch.ForumTitle,
+				AppliedTags: sch.ForumTagIDs,
+			}, first)
+			return err
+		})
+		if err == nil {
+			message = &discordgo.Message{ID: post.ID, ChannelID: post.ID, GuildID: sch.GuildID}
+		}
+	} else {
+		err = withRetries(sch, func() (err error) {
+			message, err = sendMessage(s, sch, channelID)
+			return err
+		})
+	}
+	failed := []string{}
+	if err == nil {
+		recordSent(sch, sch.ChannelID, message)
+		sent = append(sent, message)
+	} else {
+		failed = append(failed, sch.ChannelID)
+	}
+	if err == nil && sch.Crosspost {
+		_, err = s.ChannelMessageCrosspost(channelID, message.ID)
+		if err != nil {
+			err = errors.New("Error publishing message: " + err.Error())
+		}
+	}
+	if err == nil && sch.Pin != "" {
+		err = pinMessage(s, message, sch.Pin == pinReplace)
+	}
+	if err == nil && sch.Discussion != "" {
+		_, err = s.MessageThreadStartComplex(channelID, message.ID, &discordgo.ThreadStart{
+			Name:                sch.Discussion,
+			AutoArchiveDuration: sch.DiscussionArchive,
+		})
+		if err != nil {
+			err = errors.New("Error starting discussion thread: " + err.Error())
+		}
+	}
+
+	// a failure in one extra channel must not prevent the others
+	errs := []error{err}
+	for _, extraID := range sch.ExtraChannelIDs {
+		var extra *discordgo.Message
+		extraErr := withRetries(sch, func() (err error) {
+			extra, err = sendMessage(s, sch, extraID)
+			return err
+		})
+		if extraErr != nil {
+			errs = append(errs, errors.New("Error sending in <#"+extraID+">: "+extraErr.Error()))
+			failed = append(failed, extraID)
+			continue
+		}
+		recordSent(sch, extraID, extra)
+		sent = append(sent, extra)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return sent, &deliveryError{Failed: failed, err: err}
+	}
+	return sent, nil
+}
+
+// deliveryError is the error of a delivery, with the target channels the
+// message could not be sent to. It is empty when the message was sent
+// everywhere, and a step that follows failed.
+type deliveryError struct {
+	Failed []string
+	err    error
+}
+
+func (e *deliveryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *deliveryError) Unwrap() error {
+	return e.err
+}
+
+// deliverToAuthor sends the message of the schedule to its author in DM,
+// after telling them why it couldn't be sent in its channel.
+func deliverToAuthor(s *discordgo.Session, sch *Schedule, reason error) error {
+	err := notifyAuthor(s, sch, "Your message `"+sch.ID+"` for "+sch.target()+" could not be sent: "+reason.Error()+"\nHere it is, so that it isn't lost:")
+	if err != nil {
+		return err
+	}
+	channel, err := s.UserChannelCreate(sch.AuthorID)
+	if err != nil {
+		return err
+	}
+	// it is sent by the bot, without its countdown and without pinging anyone
+	fallback := *sch
+	fallback.DM = true
+	fallback.Sender = senderBot
+	fallback.Countdown = ""
+	fallback.AllowedMentions = &discordgo.MessageAllowedMentions{}
+	_, err = sendMessage(s, &fallback, channel.ID)
+	return err
+}
+
+// sendMessage sends the message of the schedule in the channel, as the bot or
+// as its author.
+func sendMessage(s *discordgo.Session, sch *Schedule, channelID string) (*discordgo.Message, error) {
+	settings().sendLimits.wait(channelID)
+	if sch.Poll != nil {
+		return sendPoll(s, sch, channelID)
+	}
+	if sch.Sender == senderMe {
+		return sendAsAuthor(s, sch, channelID)
+	}
+	if sch.Countdown != "" && channelID == sch.ChannelID {
+		return replaceCountdown(s, sch)
+	}
+	message := messageSend(sch)
+	defer closeFiles(message.Files)
+	// the DMs can be sent again later by their recipient
+	if sch.DM {
+		message.Components = snoozeButtons()
+	}
+	return s.ChannelMessageSendComplex(channelID, message)
+}
+
+// messageSend builds the message of the schedule. A content too long for a
+// message is uploaded as a text file instead. The files are read when sent, so
+// a new message is needed for every send.
+func messageSend(sch *Schedule) *discordgo.MessageSend {
+	mention := ""
+	if sch.Sender == senderMention {
+		mention = "Scheduled by <@" + sch.AuthorID + ">\n"
+	}
+	message := &discordgo.MessageSend{
+		Content:         mention + sch.Content,
+		Embeds:          sch.Embeds,
+		AllowedMentions: sch.AllowedMentions,
+		TTS:             sch.TTS,
+	}
+	if message.AllowedMentions == nil {
+		message.AllowedMentions = mentionLevel(mentionsUsers)
+	}
+	// the author is pinged even if the other users aren't
+	if sch.Sender == senderMention && !slices.Contains(message.AllowedMentions.Parse, discordgo.AllowedMentionTypeUsers) {
+		mentions := *message.AllowedMentions
+		mentions.Users = append(slices.Clone(mentions.Users), sch.AuthorID)
+		message.AllowedMentions = &mentions
+	}
+	if sch.Silent {
+		message.Flags |= discordgo.MessageFlagsSuppressNotifications
+	}
+	if sch.ReplyToID != "" {
+		message.Reference = &discordgo.MessageReference{
+			MessageID: sch.ReplyToID,
+			ChannelID: sch.ChannelID,
+			GuildID:   sch.GuildID,
+		}
+		// the replied author is pinged along with the users
+		mentions := *message.AllowedMentions
+		mentions.RepliedUser = slices.Contains(mentions.Parse, discordgo.AllowedMentionTypeUsers)
+		message.AllowedMentions = &mentions
+	}
+	if utf8.RuneCountInString(message.Content) > maxMessageLength {
+		message.Content = mention
+		message.Files = append(message.Files, &discordgo.File{
+			Name:        "message.txt",
+			ContentType: "text/plain; charset=utf-8",
+			Reader:      strings.NewReader(sch.Content),
+		})
+	}
+	for _, file := range sch.Files {
+		message.Files = append(message.Files, &discordgo.File{
+			Name:        file.Name,
+			ContentType: file.ContentType,
+			Reader:      file.reader(),
+		})
+	}
+	if sch.Spoiler {
+		if message.Content != "" {
+			message.Content = "||" + message.Content + "||"
+		}
+		for _, file := range message.Files {
+			file.Name = "SPOILER_" + file.Name
+		}
+	}
+	return message
+}
+
+func handleCancel(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	id := ""
+	for _, option := range options {
+		if option.Name == "id" {
+			id = option.StringValue()
+		}
+	}
+
+	// only the author and the members allowed to manage messages can cancel,
+	// the author from any server
+	user := interactionUser(i)
+	sch := schedules.get(id)
+	if sch == nil || (sch.GuildID != i.GuildID && sch.AuthorID != user.ID) {
+		respondError(s, i, "Error cancelling message", &userError{Code: codeNotFound, Message: "no pending message with ID " + id, Hint: "The IDs are shown by /sendlater agenda and /sendlater mine."})
+		return
+	}
+	if sch.AuthorID != user.ID && !hasPermission(i, discordgo.PermissionManageMessages) {
+		respondError(s, i, "Error cancelling message", &userError{Code: codeNotAllowed, Message: "only its author or a moderator can cancel it", Hint: "Ask its author or a member allowed to manage messages."})
+		return
+	}
+	if schedules.take(id) == nil {
+		respondError(s, i, "Error cancelling message", &userError{Code: codeNotFound, Message: "it was already sent"})
+		return
+	}
+	audit(s, AuditCancelled, user.ID, sch)
+	logger.Info("Message cancelled", "id", id, "user", user.ID)
+	respond(s, i, "Message cancelled!")
+}
+
+// messageChannelTypes are the channels a message can be sent in. A forum
+// takes posts instead, which only the schedule command can create.
+var messageChannelTypes = []discordgo.ChannelType{
+	discordgo.ChannelTypeGuildText,
+	discordgo.ChannelTypeGuildNews,
+	discordgo.ChannelTypeGuildPublicThread,
+	discordgo.ChannelTypeGuildPrivateThread,
+	discordgo.ChannelTypeGuildNewsThread,
+}
+
+// checkChannel returns an error when nothing can be scheduled in the channel,
+// so that it is rejected when scheduling rather than when sending. Forums are
+// accepted if forum is set.
+func checkChannel(channel *discordgo.Channel, forum bool) error {
+	if slices.Contains(messageChannelTypes, channel.Type) || (forum && channel.Type == discordgo.ChannelTypeGuildForum) {
+		return nil
+	}
+	if channel.Type == discordgo.ChannelTypeGuildForum {
+		return &userError{Code: codeInvalidChannel, Message: "#" + channel.Name + " is a forum, it only accepts messages from the schedule command"}
+	}
+	if channel.Type == discordgo.ChannelTypeDM || channel.Type == discordgo.ChannelTypeGroupDM {
+		return &userError{Code: codeInvalidChannel, Message: "messages cannot be scheduled in DM channels", Hint: "Use the dm option instead."}
+	}
+	return &userError{Code: codeInvalidChannel, Message: "messages cannot be scheduled in #" + channel.Name, Hint: "Choose a text or announcement channel, a forum or a thread."}
+}
+
+// channelTypes returns the types of the channels accepted by checkChannel,
+// to filter the channel options.
+func channelTypes(forum bool) []discordgo.ChannelType {
+	if forum {
+		return append(slices.Clone(messageChannelTypes), discordgo.ChannelTypeGuildForum)
+	}
+	return messageChannelTypes
+}
+
+// threadChannel returns the thread given by link or ID.
+func threadChannel(s *discordgo.Session, link string) (*discordgo.Channel, error) {
+	ids, err := parseLink(link)
+	if err != nil {
+		return nil, err
+	}
+	channel, err := s.Channel(ids[len(ids)-1])
+	if err != nil {
+		return nil, discordError("cannot get the thread", err)
+	}
+	if !channel.IsThread() {
+		return nil, &userError{Code: codeInvalidChannel, Message: "#" + channel.Name + " is not a thread", Hint: "Use the channel option for a channel."}
+	}
+	return channel, nil
+}
+
+// linkedMessage returns the message given by link or ID and its channel. A
+// plain ID is looked for in channel.
+func linkedMessage(s *discordgo.Session, channel *discordgo.Channel, link string) (*discordgo.Message, *discordgo.Channel, error) {
+	ids, err := parseLink(link)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ids) != 1 && len(ids) != 3 {
+		return nil, nil, &userError{Code: codeInvalidLink, Message: "not a message link: " + link, Hint: "Copy it with Copy Message Link in the menu of the message."}
+	}
+	if len(ids) == 3 && ids[1] != channel.ID {
+		channel, err = s.Channel(ids[1])
+		if err != nil {
+			return nil, nil, discordError("cannot get the channel of the message", err)
+		}
+	}
+	message, err := s.ChannelMessage(channel.ID, ids[len(ids)-1])
+	if err != nil {
+		return nil, nil, discordError("cannot get the message", err)
+	}
+	return message, channel, nil
+}
+
+// pinMessage pins the message. With replace, the messages pinned earlier by
+// the bot or its webhook in the channel are unpinned first, for rotating pins.
+func pinMessage(s *discordgo.Session, message *discordgo.Message, replace bool) error {
+	if replace {
+		pinned, err := s.ChannelMessagesPinned(message.ChannelID)
+		if err != nil {
+			return errors.New("Error getting pinned messages: " + err.Error())
+		}
+		for _, old := range pinned {
+			fromBot := old.Author != nil && old.Author.ID == s.State.User.ID
+			fromWebhook := message.WebhookID != "" && old.WebhookID == message.WebhookID
+			if !fromBot && !fromWebhook {
+				continue
+			}
+			err = s.ChannelMessageUnpin(message.ChannelID, old.ID)
+			if err != nil {
+				return errors.New("Error unpinning message: " + err.Error())
+			}
+		}
+	}
+	err := s.ChannelMessagePin(message.ChannelID, message.ID)
+	if err != nil {
+		return errors.New("Error pinning message: " + err.Error())
+	}
+	return nil
+}
+
+// reviveThread unarchives the thread if it was archived since scheduling.
+func reviveThread(s *discordgo.Session, threadID string) error {
+	thread, err := s.Channel(threadID)
+	if err != nil {
+		return errors.New("Error getting thread: " + err.Error())
+	}
+	if thread.ThreadMetadata == nil || !thread.ThreadMetadata.Archived {
+		return nil
+	}
+	archived := false
+	_, err = s.ChannelEditComplex(threadID, &discordgo.ChannelEdit{Archived: &archived})
+	if err != nil {
+		return errors.New("Error unarchiving thread: " + err.Error())
+	}
+	logger.Info("Thread unarchived", "thread", threadID)
+	return nil
+}
+
+// forumTags returns the IDs of the comma separated tags of the forum.
+func forumTags(forum *discordgo.Channel, names string) ([]string, error) {
+	ids := []string{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, tag := range forum.AvailableTags {
+			if strings.EqualFold(tag.Name, name) {
+				ids = append(ids, tag.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, &userError{Code: codeInvalidOption, Message: "the forum #" + forum.Name + " has no tag " + name, Hint: "The tags are separated by commas."}
+		}
+	}
+	if len(ids) > 5 {
+		return nil, &userError{Code: codeInvalidOption, Message: "a post can have at most 5 tags"}
+	}
+	return ids, nil
+}
+
+// extraChannels returns the channels mentioned or given by ID in text, which
+// must be in the guild and accept the content from the user.
+func extraChannels(s *discordgo.Session, guildID string, text string, userID string, content string, mentions *discordgo.MessageAllowedMentions) ([]string, error) {
+	ids := []string{}
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' }) {
+		link, err := parseLink(field)
+		if err != nil {
+			return nil, err
+		}
+		channel, err := s.Channel(link[len(link)-1])
+		if err != nil {
+			return nil, discordError("cannot get the channel "+field, err)
+		}
+		if channel.GuildID != guildID {
+			return nil, &userError{Code: codeInvalidChannel, Message: "the channel " + field + " is not in this server"}
+		}
+		err = checkChannel(channel, false)
+		if err == nil {
+			err = checkCanPost(s, userID, channel, content, mentions)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, channel.ID)
+	}
+	return ids, nil
+}
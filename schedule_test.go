@@ -0,0 +1,47 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckSendBounds(t *testing.T) {
+	minDelay, maxHorizon, err := parseSendBounds("10m", "1d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := currentSettings.Load()
+	currentSettings.Store(&runtimeSettings{minDelay: minDelay, maxHorizon: maxHorizon})
+	defer currentSettings.Store(previous)
+
+	now := time.Now()
+	var userErr *userError
+	if err := checkSendBounds(now.Add(5*time.Minute), now); !errors.As(err, &userErr) || userErr.Code != codeTooSoon {
+		t.Errorf("message too soon: got %v", err)
+	}
+	if err := checkSendBounds(now.Add(25*time.Hour), now); !errors.As(err, &userErr) || userErr.Code != codeTooFar {
+		t.Errorf("message too far: got %v", err)
+	}
+	if err := checkSendBounds(now.Add(time.Hour), now); err != nil {
+		t.Errorf("message within the bounds refused: %v", err)
+	}
+	if _, _, err := parseSendBounds("2d", "1h"); err == nil {
+		t.Error("horizon shorter than the delay accepted")
+	}
+}
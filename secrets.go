@@ -0,0 +1,112 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// secretsDir is where Docker and Kubernetes mount the secrets by default.
+const secretsDir = "/run/secrets"
+
+// readTokens reads the tokens that are not set in the environment from their
+// file, from Vault, or from the secrets mounted in secretsDir, in this order.
+func readTokens() error {
+	var err error
+	if Token == "" {
+		Token, err = readSecret("DISCORD_TOKEN_FILE", "token", "discord_token")
+		if err != nil {
+			return errors.New("Error reading token: " + err.Error())
+		}
+	}
+	if SecondaryToken == "" {
+		SecondaryToken, err = readSecret("DISCORD_TOKEN_SECONDARY_FILE", "secondary_token", "discord_token_secondary")
+		if err != nil {
+			return errors.New("Error reading secondary token: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// readSecret returns the content of the file set in fileKey, or the field of
+// the Vault secret set in SENDLATER_VAULT_SECRET, or the content of the file
+// mountName in secretsDir. It returns an empty string if none is set.
+func readSecret(fileKey string, field string, mountName string) (string, error) {
+	if path := os.Getenv(fileKey); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+	if path := os.Getenv("SENDLATER_VAULT_SECRET"); path != "" {
+		return readVaultSecret(os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN"), path, field)
+	}
+	content, err := os.ReadFile(secretsDir + "/" + mountName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// vaultClient is the client of the Vault API.
+var vaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// readVaultSecret reads a field of a secret of the Vault server at addr, with
+// the KV secrets engine in version 1 or 2. A missing field is an empty string,
+// so that the secondary token is optional.
+func readVaultSecret(addr string, token string, path string, field string) (string, error) {
+	if addr == "" || token == "" {
+		return "", errors.New("VAULT_ADDR and VAULT_TOKEN must be set to read SENDLATER_VAULT_SECRET")
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", errors.New("Error creating Vault request: " + err.Error())
+	}
+	req.Header.Set("X-Vault-Token", token)
+	resp, err := vaultClient.Do(req)
+	if err != nil {
+		return "", errors.New("Error reading Vault secret: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("Error reading Vault secret: " + resp.Status)
+	}
+
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&secret)
+	if err != nil {
+		return "", errors.New("Error decoding Vault secret: " + err.Error())
+	}
+	// the version 2 of the KV engine nests the fields under data.data
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+	value, _ := data[field].(string)
+	return value, nil
+}
@@ -0,0 +1,157 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// channelSendWindow is the period over which the per channel rate applies,
+// the one of the rate limits of Discord on the messages of a channel.
+const channelSendWindow = 5 * time.Second
+
+// tokenBucket lets burst events through at once, then rate events per second.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// reserve takes a token and returns how long to wait before it is available.
+// The tokens go negative while the events wait, so the events are let
+// through in the order of their reservations.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// sendLimiter spaces the messages sent by the bot, so that the schedules of
+// the same minute don't trip the rate limits of Discord: a global bucket for
+// all the messages, and a bucket for each channel.
+type sendLimiter struct {
+	mu          sync.Mutex
+	global      *tokenBucket
+	channelRate int
+	channels    map[string]*tokenBucket
+	// pruned is when the idle buckets were last dropped
+	pruned time.Time
+}
+
+// newSendLimiter lets globalRate messages per second through, and
+// channelRate messages per channelSendWindow in each channel.
+func newSendLimiter(globalRate int, channelRate int) *sendLimiter {
+	return &sendLimiter{
+		global:      newTokenBucket(float64(globalRate), float64(globalRate), time.Now()),
+		channelRate: channelRate,
+		channels:    map[string]*tokenBucket{},
+		pruned:      time.Now(),
+	}
+}
+
+// wait blocks until a message can be sent in the channel. It is called from
+// the queue of the channel, see sendQueues, so that only the messages of the
+// channel wait behind it.
+func (l *sendLimiter) wait(channelID string) {
+	delay := l.reserve(channelID, time.Now())
+	if delay > 0 {
+		logger.Debug("Message queued by the rate limits", "channel", channelID, "delay", delay)
+		time.Sleep(delay)
+	}
+}
+
+// reserve takes a token of the global bucket and of the bucket of the
+// channel, and returns how long to wait before sending the message.
+func (l *sendLimiter) reserve(channelID string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	channel := l.channels[channelID]
+	if channel == nil {
+		channel = newTokenBucket(float64(l.channelRate)/channelSendWindow.Seconds(), float64(l.channelRate), now)
+		l.channels[channelID] = channel
+	}
+	delay := max(l.global.reserve(now), channel.reserve(now))
+	// the buckets full again are the same as new ones, they are dropped once
+	// per window rather than on every message
+	if now.Sub(l.pruned) >= channelSendWindow {
+		for id, bucket := range l.channels {
+			bucket.refill(now)
+			if bucket.tokens >= bucket.burst {
+				delete(l.channels, id)
+			}
+		}
+		l.pruned = now
+	}
+	return delay
+}
+
+// channelQueues run the deliveries of each channel one after the other, in
+// a goroutine of the channel, so that the deliveries waiting for the rate
+// limit of a busy channel don't hold the workers of the dispatcher.
+type channelQueues struct {
+	mu      sync.Mutex
+	pending map[string][]func()
+}
+
+// sendQueues are the queues of the channels being sent to, a queue being
+// dropped once empty.
+var sendQueues = &channelQueues{pending: map[string][]func(){}}
+
+// run queues the delivery in the channel, and starts the goroutine of its
+// queue if it is idle.
+func (q *channelQueues) run(channelID string, deliver func()) {
+	q.mu.Lock()
+	pending, busy := q.pending[channelID]
+	q.pending[channelID] = append(pending, deliver)
+	q.mu.Unlock()
+	if !busy {
+		go q.drain(channelID)
+	}
+}
+
+// drain runs the deliveries queued in the channel until there are none left.
+func (q *channelQueues) drain(channelID string) {
+	for {
+		q.mu.Lock()
+		pending := q.pending[channelID]
+		if len(pending) == 0 {
+			delete(q.pending, channelID)
+			q.mu.Unlock()
+			return
+		}
+		deliver := pending[0]
+		pending[0] = nil
+		q.pending[channelID] = pending[1:]
+		q.mu.Unlock()
+		deliver()
+	}
+}
@@ -0,0 +1,76 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSendLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := newSendLimiter(2, 2)
+	delays := []time.Duration{}
+	for _, channelID := range []string{"a", "b", "a", "a"} {
+		delays = append(delays, limiter.reserve(channelID, now))
+	}
+	// the global bucket lets 2 messages per second through, and the one of
+	// a channel 2 per 5 seconds
+	if want := []time.Duration{0, 0, 500 * time.Millisecond, 2500 * time.Millisecond}; !slices.Equal(delays, want) {
+		t.Errorf("got delays %v, want %v", delays, want)
+	}
+
+	// the buckets full again are dropped once the window has passed
+	limiter = newSendLimiter(1000, 5)
+	for k := range 100 {
+		limiter.reserve(strconv.Itoa(k), now)
+	}
+	limiter.reserve("busy", now.Add(time.Second))
+	limiter.reserve("busy", now.Add(channelSendWindow+time.Second))
+	if len(limiter.channels) != 1 {
+		t.Errorf("%d buckets kept after the window, want 1", len(limiter.channels))
+	}
+}
+
+func TestChannelQueues(t *testing.T) {
+	queues := &channelQueues{pending: map[string][]func(){}}
+	var done sync.WaitGroup
+	sent := []int{}
+	// a stays busy until b is sent, so b doesn't wait behind it
+	unblock := make(chan struct{})
+	done.Add(12)
+	queues.run("a", func() {
+		defer done.Done()
+		<-unblock
+	})
+	for k := range 10 {
+		queues.run("a", func() {
+			defer done.Done()
+			sent = append(sent, k)
+		})
+	}
+	queues.run("b", func() {
+		defer done.Done()
+		close(unblock)
+	})
+	done.Wait()
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !slices.Equal(sent, want) {
+		t.Errorf("sent %v in a, want %v", sent, want)
+	}
+}
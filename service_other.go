@@ -0,0 +1,39 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+//go:build !windows
+
+package main
+
+import (
+	"os"
+)
+
+// serviceCommand refuses the Windows service subcommands on the other systems,
+// and reports whether command is one of them, and the exit code.
+func serviceCommand(command string, args []string) (bool, int) {
+	switch command {
+	case "install", "uninstall", "start", "stop":
+		logger.Error("The bot only runs as a service on Windows, use systemd or a container elsewhere", "command", command)
+		return true, 1
+	}
+	return false, 0
+}
+
+// runAsService does nothing, the bot only runs as a service on Windows.
+func runAsService(stop chan<- os.Signal) {}
+
+// chdirService does nothing, the bot only runs as a service on Windows.
+func chdirService() {}
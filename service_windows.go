@@ -0,0 +1,158 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// serviceName is the name of the Windows service of the bot.
+const serviceName = "sendlater"
+
+// serviceCommand runs the Windows service subcommands: install, with the flags
+// the service runs with, uninstall, start and stop. It reports whether command
+// is one of them, and the exit code.
+func serviceCommand(command string, args []string) (bool, int) {
+	var err error
+	switch command {
+	case "install":
+		err = installService(args)
+	case "uninstall":
+		err = uninstallService()
+	case "start":
+		err = controlService(func(service *mgr.Service) error { return service.Start() })
+	case "stop":
+		err = controlService(func(service *mgr.Service) error {
+			_, err := service.Control(svc.Stop)
+			return err
+		})
+	default:
+		return false, 0
+	}
+	if err != nil {
+		logger.Error("Error running service command", "error", err, "command", command)
+		return true, 1
+	}
+	logger.Info("Service command done", "command", command, "service", serviceName)
+	return true, 0
+}
+
+// installService registers the bot as a service started with Windows.
+func installService(args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return errors.New("Error finding executable: " + err.Error())
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return errors.New("Error connecting to the service manager: " + err.Error())
+	}
+	defer m.Disconnect()
+	service, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: "Send Later Discord bot",
+		Description: "Sends the messages scheduled with /sendlater",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return errors.New("Error creating service: " + err.Error())
+	}
+	defer service.Close()
+	// the service is restarted if the bot crashes
+	err = service.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 10 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+	}, uint32((24 * time.Hour).Seconds()))
+	if err != nil {
+		return errors.New("Error setting service recovery: " + err.Error())
+	}
+	return nil
+}
+
+// uninstallService removes the service, which stops once it is stopped.
+func uninstallService() error {
+	return controlService(func(service *mgr.Service) error { return service.Delete() })
+}
+
+// controlService calls fn with the service of the bot.
+func controlService(fn func(service *mgr.Service) error) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return errors.New("Error connecting to the service manager: " + err.Error())
+	}
+	defer m.Disconnect()
+	service, err := m.OpenService(serviceName)
+	if err != nil {
+		return errors.New("Error opening service: " + err.Error())
+	}
+	defer service.Close()
+	return fn(service)
+}
+
+// runAsService reports to the service manager when the bot runs as a Windows
+// service, and turns its stop and shutdown requests into a SIGTERM on stop.
+func runAsService(stop chan<- os.Signal) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return
+	}
+	go func() {
+		err := svc.Run(serviceName, &serviceHandler{stop: stop})
+		if err != nil {
+			logger.Error("Error running service", "error", err)
+		}
+	}()
+}
+
+// chdirService moves to the directory of the executable when the bot runs as
+// a Windows service, which starts in the system directory, so that the
+// relative paths of the store and the logs are next to the bot.
+func chdirService() {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	_ = os.Chdir(filepath.Dir(exe))
+}
+
+// serviceHandler answers the requests of the service manager.
+type serviceHandler struct {
+	stop chan<- os.Signal
+}
+
+func (h *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for request := range requests {
+		switch request.Cmd {
+		case svc.Interrogate:
+			status <- request.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending, WaitHint: 10000}
+			h.stop <- syscall.SIGTERM
+			return false, 0
+		}
+	}
+	return false, 0
+}
@@ -0,0 +1,133 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// runtimeSettings are the settings read while the bot runs. They are
+// published as one snapshot, see settings, so that a reload replaces them at
+// once while the commands and the deliveries read them.
+type runtimeSettings struct {
+	// loc is the time zone of the bot, used when a server has none. It is
+	// set with SENDLATER_TIMEZONE.
+	loc *time.Location
+	// owners are the users managing the feature flags of the bot.
+	owners []string
+	// allowedGuilds and deniedGuilds are the servers the bot serves or
+	// refuses to serve, see guildAllowed.
+	allowedGuilds []string
+	deniedGuilds  []string
+	// publicReplies makes the replies to the commands visible to everyone
+	// in the channel, instead of only to the member who used them.
+	publicReplies bool
+	// archiveDir is where the archives of the servers are kept when their
+	// owners cannot be reached.
+	archiveDir string
+	// minDelay and maxHorizon are the bounds of the send times, relative to
+	// when the message is scheduled. Zero means unbounded.
+	minDelay   time.Duration
+	maxHorizon time.Duration
+	// maxPending is the number of pending messages a member can have when
+	// the guild doesn't set it, each one keeps its content in memory until it
+	// is sent. It is set with SENDLATER_MAX_PENDING.
+	maxPending int
+	// rateLimit is how many scheduling commands a member can use per minute
+	// when the guild doesn't set it. It is set with SENDLATER_RATE_LIMIT.
+	rateLimit int
+	// sendLimits is set with SENDLATER_SEND_RATE and
+	// SENDLATER_CHANNEL_SEND_RATE.
+	sendLimits *sendLimiter
+	// deliveryRetries is how many times a delivery is attempted again after
+	// a transient error, waiting retryDelay then twice as long each time.
+	// They are set with SENDLATER_DELIVERY_RETRIES, 0 disabling the retries,
+	// and SENDLATER_DELIVERY_RETRY_DELAY.
+	deliveryRetries int
+	retryDelay      time.Duration
+	// maxAttachmentSize is the size of the largest file that can be
+	// downloaded, set in megabytes with SENDLATER_MAX_ATTACHMENT_SIZE. It is
+	// the upload limit of Discord in the servers without boosts by default.
+	maxAttachmentSize int64
+}
+
+// defaultSettings are used until the configuration is loaded.
+var defaultSettings = runtimeSettings{
+	loc:               time.Local,
+	maxPending:        25,
+	rateLimit:         5,
+	sendLimits:        newSendLimiter(40, 5),
+	deliveryRetries:   3,
+	retryDelay:        2 * time.Second,
+	maxAttachmentSize: 25 << 20,
+}
+
+var currentSettings atomic.Pointer[runtimeSettings]
+
+// settings returns the current settings, which must not be modified. The
+// callers reading several of them keep the returned snapshot rather than
+// calling settings again, so that they don't mix two configurations.
+func settings() *runtimeSettings {
+	if current := currentSettings.Load(); current != nil {
+		return current
+	}
+	return &defaultSettings
+}
+
+// readRuntimeSettings reads the runtime settings from the environment, once
+// checkSettings checked it.
+func readRuntimeSettings() (*runtimeSettings, error) {
+	timezone := envOr("SENDLATER_TIMEZONE", "Local")
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errors.New("unknown time zone " + timezone)
+	}
+	minDelay, maxHorizon, err := parseSendBounds(os.Getenv("SENDLATER_MIN_DELAY"), os.Getenv("SENDLATER_MAX_HORIZON"))
+	if err != nil {
+		return nil, err
+	}
+	// the delay was checked by checkSettings
+	retryDelay, _ := time.ParseDuration(envOr("SENDLATER_DELIVERY_RETRY_DELAY", "2s"))
+	return &runtimeSettings{
+		loc:               location,
+		owners:            envList("SENDLATER_OWNERS"),
+		allowedGuilds:     envList("SENDLATER_ALLOWED_GUILDS"),
+		deniedGuilds:      envList("SENDLATER_DENIED_GUILDS"),
+		publicReplies:     envOr("SENDLATER_PUBLIC_REPLIES", "false") == "true",
+		archiveDir:        envOr("SENDLATER_ARCHIVE_DIR", "archives"),
+		minDelay:          minDelay,
+		maxHorizon:        maxHorizon,
+		maxPending:        envInt("SENDLATER_MAX_PENDING", 25),
+		rateLimit:         envInt("SENDLATER_RATE_LIMIT", 5),
+		sendLimits:        newSendLimiter(envInt("SENDLATER_SEND_RATE", 40), envInt("SENDLATER_CHANNEL_SEND_RATE", 5)),
+		deliveryRetries:   envInt("SENDLATER_DELIVERY_RETRIES", 3),
+		retryDelay:        retryDelay,
+		maxAttachmentSize: int64(envInt("SENDLATER_MAX_ATTACHMENT_SIZE", 25)) << 20,
+	}, nil
+}
+
+// publishSettings reads the runtime settings and makes them the current ones.
+func publishSettings() error {
+	runtime, err := readRuntimeSettings()
+	if err != nil {
+		return err
+	}
+	currentSettings.Store(runtime)
+	return nil
+}
@@ -0,0 +1,422 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Custom IDs of the components of the setup wizard.
+const (
+	setupTimezoneID   = "setup_timezone"
+	setupAuditID      = "setup_audit_channel"
+	setupRolesID      = "setup_roles"
+	setupQuietHoursID = "setup_quiet_hours"
+	setupPermissionID = "setup_permission"
+	setupMaxPendingID = "setup_max_pending"
+	setupRateLimitID  = "setup_rate_limit"
+	setupApprovalID   = "setup_approval_channel"
+	setupDoneID       = "setup_done"
+	setupNextID       = "setup_next"
+	setupBackID       = "setup_back"
+)
+
+// setupPages are the settings of each page of the wizard, as a message holds
+// at most 5 rows of components.
+var setupPages = [][]string{
+	{setupTimezoneID, setupAuditID, setupRolesID, setupQuietHoursID},
+	{setupPermissionID, setupMaxPendingID, setupRateLimitID, setupApprovalID},
+}
+
+var setupMaxPending = []int{5, 10, 50, 100}
+
+var setupRateLimits = []int{1, 3, 10, 30}
+
+// setupDefault is the select value that resets a setting to its default.
+const setupDefault = "default"
+
+// setupTimezones are the time zones offered by the wizard, a select menu is
+// limited to 25 options.
+var setupTimezones = []string{
+	"UTC",
+	"Europe/London", "Europe/Paris", "Europe/Berlin", "Europe/Madrid", "Europe/Rome",
+	"Europe/Helsinki", "Europe/Moscow", "Europe/Istanbul",
+	"America/New_York", "America/Chicago", "America/Denver", "America/Los_Angeles",
+	"America/Sao_Paulo", "America/Mexico_City",
+	"Asia/Dubai", "Asia/Kolkata", "Asia/Shanghai", "Asia/Tokyo", "Asia/Singapore",
+	"Australia/Sydney", "Pacific/Auckland", "Africa/Johannesburg",
+}
+
+var setupQuietHours = []string{"21:00-09:00", "22:00-07:00", "23:00-08:00", "00:00-06:00"}
+
+// setupJoinDelay is how recent the join of a guild must be for the wizard to
+// be posted, so that restarting the bot does not post it in every guild.
+const setupJoinDelay = 5 * time.Minute
+
+// guildCreate posts the setup wizard when the bot joins a new guild, and keeps
+// track of the owner of the known guilds.
+func guildCreate(s *discordgo.Session, g *discordgo.GuildCreate) {
+	if !guildAllowed(g.ID) {
+		logger.Warn("Bot added to an unlisted guild, ignoring it", "guild", g.ID, "name", g.Name, "owner", g.OwnerID)
+		return
+	}
+	known := store.HasGuildConfig(g.ID)
+	if store.GuildConfig(g.ID).OwnerID != g.OwnerID {
+		err := store.UpdateGuildConfig(g.ID, func(c *GuildConfig) {
+			c.OwnerID = g.OwnerID
+		})
+		if err != nil {
+			logger.Error("Error saving guild owner", "error", err, "guild", g.ID)
+		}
+	}
+	if known || time.Since(g.JoinedAt) > setupJoinDelay {
+		return
+	}
+	if g.SystemChannelID == "" {
+		logger.Info("No system channel to post the setup wizard", "guild", g.ID)
+		return
+	}
+
+	config := store.GuildConfig(g.ID)
+	_, err := s.ChannelMessageSendComplex(g.SystemChannelID, &discordgo.MessageSend{
+		Content:    "Thanks for adding me! A server admin can set me up below, or later with `/sendlater setup`.\n\n" + setupSummary(config),
+		Components: setupComponents(config, 0),
+	})
+	if err != nil {
+		logger.Error("Error posting setup wizard", "error", err, "guild", g.ID)
+		return
+	}
+	logger.Info("Setup wizard posted", "guild", g.ID)
+}
+
+func handleSetup(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" {
+		respond(s, i, "The setup is only available in a server")
+		return
+	}
+	if !hasPermission(i, discordgo.PermissionManageServer) {
+		respond(s, i, "Only the server admins can set up the bot")
+		return
+	}
+	config := store.GuildConfig(i.GuildID)
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    setupSummary(config),
+			Components: setupComponents(config, 0),
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Error("Error responding to interaction", "error", err)
+	}
+}
+
+// handleSetupComponent saves the setting changed in the wizard and refreshes it.
+func handleSetupComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !hasPermission(i, discordgo.PermissionManageServer) {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Only the server admins can set up the bot",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			logger.Error("Error responding to interaction", "error", err)
+		}
+		return
+	}
+
+	data := i.MessageComponentData()
+	config := GuildConfig{}
+	err := store.UpdateGuildConfig(i.GuildID, func(c *GuildConfig) {
+		switch data.CustomID {
+		case setupTimezoneID:
+			c.Timezone = ""
+			if len(data.Values) == 1 && data.Values[0] != setupDefault {
+				c.Timezone = data.Values[0]
+			}
+		case setupAuditID:
+			c.AuditChannelID = ""
+			if len(data.Values) == 1 {
+				c.AuditChannelID = data.Values[0]
+			}
+		case setupApprovalID:
+			c.ApprovalChannelID = ""
+			if len(data.Values) == 1 {
+				c.ApprovalChannelID = data.Values[0]
+			}
+		case setupRolesID:
+			c.AllowedRoleIDs = data.Values
+		case setupQuietHoursID:
+			c.QuietHours = ""
+			if len(data.Values) == 1 && data.Values[0] != setupDefault {
+				c.QuietHours = data.Values[0]
+			}
+		case setupPermissionID:
+			c.RequiredPermission = ""
+			if len(data.Values) == 1 && data.Values[0] != setupDefault {
+				c.RequiredPermission = data.Values[0]
+			}
+		case setupMaxPendingID:
+			c.MaxPending = 0
+			if len(data.Values) == 1 && data.Values[0] != setupDefault {
+				c.MaxPending, _ = strconv.Atoi(data.Values[0])
+			}
+		case setupRateLimitID:
+			c.RateLimit = 0
+			if len(data.Values) == 1 && data.Values[0] != setupDefault {
+				c.RateLimit, _ = strconv.Atoi(data.Values[0])
+			}
+		}
+		config = *c
+	})
+	if err != nil {
+		logger.Error("Error saving guild configuration", "error", err, "guild", i.GuildID)
+	}
+	logger.Info("Guild configuration changed", "guild", i.GuildID, "setting", data.CustomID, "user", interactionUser(i).ID)
+
+	// the wizard stays on the page of the changed setting
+	page := 0
+	for n, settings := range setupPages {
+		if slices.Contains(settings, data.CustomID) {
+			page = n
+		}
+	}
+	if data.CustomID == setupNextID {
+		page = 1
+	}
+	response := &discordgo.InteractionResponseData{
+		Content:    setupSummary(config),
+		Components: setupComponents(config, page),
+	}
+	if data.CustomID == setupDoneID {
+		response.Content = "Setup complete!\n\n" + setupSummary(config)
+		response.Components = []discordgo.MessageComponent{}
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: response,
+	})
+	if err != nil {
+		logger.Error("Error responding to interaction", "error", err)
+	}
+}
+
+// setupSummary describes the current configuration of the guild.
+func setupSummary(config GuildConfig) string {
+	defaults := settings()
+	lines := []string{"**Current configuration**"}
+	if config.Timezone == "" {
+		lines = append(lines, "Time zone: "+defaults.loc.String()+" (default)")
+	} else {
+		lines = append(lines, "Time zone: "+config.Timezone)
+	}
+	if config.AuditChannelID == "" {
+		lines = append(lines, "Audit channel: none")
+	} else {
+		lines = append(lines, "Audit channel: <#"+config.AuditChannelID+">")
+	}
+	if len(config.AllowedRoleIDs) == 0 {
+		lines = append(lines, "Allowed roles: everyone")
+	} else {
+		lines = append(lines, "Allowed roles: <@&"+strings.Join(config.AllowedRoleIDs, "> <@&")+">")
+	}
+	if config.QuietHours == "" {
+		lines = append(lines, "Quiet hours: none")
+	} else {
+		lines = append(lines, "Quiet hours: "+config.QuietHours)
+	}
+	if config.MaxPending == 0 {
+		lines = append(lines, "Pending messages per member: "+strconv.Itoa(defaults.maxPending)+" (default)")
+	} else {
+		lines = append(lines, "Pending messages per member: "+strconv.Itoa(config.MaxPending))
+	}
+	lines = append(lines, "Scheduling commands per member and minute: "+strconv.Itoa(config.rateLimit()))
+	if permission := findGatePermission(config.RequiredPermission); permission == nil {
+		lines = append(lines, "Required permission: none")
+	} else {
+		lines = append(lines, "Required permission: "+permission.Label)
+	}
+	if config.ApprovalChannelID == "" {
+		lines = append(lines, "Approval channel: none")
+	} else {
+		lines = append(lines, "Approval channel: <#"+config.ApprovalChannelID+">")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// setupComponents returns the page of the wizard, preselecting the current
+// configuration.
+func setupComponents(config GuildConfig, page int) []discordgo.MessageComponent {
+	if page == 1 {
+		return setupSecondPage(config)
+	}
+	return setupFirstPage(config)
+}
+
+// setupFirstPage returns the time zone, audit channel, roles and quiet hours
+// settings.
+func setupFirstPage(config GuildConfig) []discordgo.MessageComponent {
+	zero := 0
+
+	timezones := []discordgo.SelectMenuOption{{Label: "Default (" + settings().loc.String() + ")", Value: setupDefault, Default: config.Timezone == ""}}
+	for _, timezone := range setupTimezones {
+		timezones = append(timezones, discordgo.SelectMenuOption{Label: timezone, Value: timezone, Default: config.Timezone == timezone})
+	}
+
+	quietHours := []discordgo.SelectMenuOption{{Label: "No quiet hours", Value: setupDefault, Default: config.QuietHours == ""}}
+	for _, hours := range setupQuietHours {
+		quietHours = append(quietHours, discordgo.SelectMenuOption{Label: "No messages from " + strings.Replace(hours, "-", " to ", 1), Value: hours, Default: config.QuietHours == hours})
+	}
+
+	auditChannel := []discordgo.SelectMenuDefaultValue{}
+	if config.AuditChannelID != "" {
+		auditChannel = append(auditChannel, discordgo.SelectMenuDefaultValue{ID: config.AuditChannelID, Type: discordgo.SelectMenuDefaultValueChannel})
+	}
+	roles := []discordgo.SelectMenuDefaultValue{}
+	for _, role := range config.AllowedRoleIDs {
+		roles = append(roles, discordgo.SelectMenuDefaultValue{ID: role, Type: discordgo.SelectMenuDefaultValueRole})
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    setupTimezoneID,
+				Placeholder: "Time zone",
+				Options:     timezones,
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				MenuType:      discordgo.ChannelSelectMenu,
+				CustomID:      setupAuditID,
+				Placeholder:   "Audit channel (none)",
+				MinValues:     &zero,
+				MaxValues:     1,
+				DefaultValues: auditChannel,
+				ChannelTypes:  []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				MenuType:      discordgo.RoleSelectMenu,
+				CustomID:      setupRolesID,
+				Placeholder:   "Roles allowed to schedule (everyone)",
+				MinValues:     &zero,
+				MaxValues:     25,
+				DefaultValues: roles,
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    setupQuietHoursID,
+				Placeholder: "Quiet hours",
+				Options:     quietHours,
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Done",
+				Style:    discordgo.SuccessButton,
+				CustomID: setupDoneID,
+			},
+			discordgo.Button{
+				Label:    "More settings",
+				Style:    discordgo.SecondaryButton,
+				CustomID: setupNextID,
+			},
+		}},
+	}
+}
+
+// setupSecondPage returns the access settings.
+func setupSecondPage(config GuildConfig) []discordgo.MessageComponent {
+	defaults := settings()
+	zero := 0
+
+	permissions := []discordgo.SelectMenuOption{{Label: "No permission required", Value: setupDefault, Default: config.RequiredPermission == ""}}
+	for _, permission := range gatePermissions {
+		permissions = append(permissions, discordgo.SelectMenuOption{Label: "Members with " + permission.Label + " can schedule", Value: permission.Name, Default: config.RequiredPermission == permission.Name})
+	}
+
+	maxPending := []discordgo.SelectMenuOption{{Label: "Default (" + strconv.Itoa(defaults.maxPending) + " pending messages per member)", Value: setupDefault, Default: config.MaxPending == 0}}
+	for _, quota := range setupMaxPending {
+		maxPending = append(maxPending, discordgo.SelectMenuOption{Label: strconv.Itoa(quota) + " pending messages per member", Value: strconv.Itoa(quota), Default: config.MaxPending == quota})
+	}
+
+	rateLimits := []discordgo.SelectMenuOption{{Label: "Default (" + strconv.Itoa(defaults.rateLimit) + " scheduling commands per minute)", Value: setupDefault, Default: config.RateLimit == 0}}
+	for _, limit := range setupRateLimits {
+		rateLimits = append(rateLimits, discordgo.SelectMenuOption{Label: strconv.Itoa(limit) + " scheduling commands per minute", Value: strconv.Itoa(limit), Default: config.RateLimit == limit})
+	}
+
+	approvalChannel := []discordgo.SelectMenuDefaultValue{}
+	if config.ApprovalChannelID != "" {
+		approvalChannel = append(approvalChannel, discordgo.SelectMenuDefaultValue{ID: config.ApprovalChannelID, Type: discordgo.SelectMenuDefaultValueChannel})
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    setupPermissionID,
+				Placeholder: "Permission allowed to schedule",
+				Options:     permissions,
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    setupMaxPendingID,
+				Placeholder: "Pending messages per member",
+				Options:     maxPending,
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    setupRateLimitID,
+				Placeholder: "Scheduling commands per member and minute",
+				Options:     rateLimits,
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				MenuType:      discordgo.ChannelSelectMenu,
+				CustomID:      setupApprovalID,
+				Placeholder:   "Approval channel (none)",
+				MinValues:     &zero,
+				MaxValues:     1,
+				DefaultValues: approvalChannel,
+				ChannelTypes:  []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+			},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Done",
+				Style:    discordgo.SuccessButton,
+				CustomID: setupDoneID,
+			},
+			discordgo.Button{
+				Label:    "Back",
+				Style:    discordgo.SecondaryButton,
+				CustomID: setupBackID,
+			},
+		}},
+	}
+}
@@ -0,0 +1,75 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package signature signs the payloads that send-later-discord-bot posts to
+// external URLs, and lets their receivers verify them.
+//
+// The signature is the hex encoded HMAC-SHA256, keyed with the secret of the
+// target, of the timestamp header, a dot and the body. Receivers should
+// reject the payloads whose timestamp is too old, and may use the delivery ID
+// to drop duplicates.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of the signed payloads.
+const (
+	SignatureHeader = "X-SendLater-Signature"
+	TimestampHeader = "X-SendLater-Timestamp"
+	DeliveryHeader  = "X-SendLater-Delivery"
+)
+
+// Tolerance is how old a signed payload can be before it is considered a
+// replay.
+const Tolerance = 5 * time.Minute
+
+// Sign returns the signature of body sent at timestamp (unix seconds).
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and the freshness of a payload received at now.
+func Verify(secret string, timestamp string, signature string, body []byte, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid timestamp")
+	}
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > Tolerance || age < -Tolerance {
+		return errors.New("timestamp out of tolerance")
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature)) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// VerifyRequest checks the signature headers of a request whose body was
+// read, for the receivers written in Go.
+func VerifyRequest(secret string, header http.Header, body []byte, now time.Time) error {
+	return Verify(secret, header.Get(TimestampHeader), header.Get(SignatureHeader), body, now)
+}
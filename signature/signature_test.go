@@ -0,0 +1,70 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package signature
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1735689600, 0)
+	body := []byte(`{"action":"sent"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	valid := Sign("secret", timestamp, body)
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      string
+		at        time.Time
+		ok        bool
+	}{
+		{"valid", "secret", timestamp, valid, string(body), now, true},
+		{"received a bit later", "secret", timestamp, valid, string(body), now.Add(Tolerance), true},
+		{"replayed", "secret", timestamp, valid, string(body), now.Add(Tolerance + time.Second), false},
+		{"from the future", "secret", timestamp, valid, string(body), now.Add(-Tolerance - time.Second), false},
+		{"other secret", "other", timestamp, valid, string(body), now, false},
+		{"changed body", "secret", timestamp, valid, `{"action":"failed"}`, now, false},
+		{"changed timestamp", "secret", strconv.FormatInt(now.Unix()+1, 10), valid, string(body), now, false},
+		{"invalid timestamp", "secret", "yesterday", valid, string(body), now, false},
+		{"no signature", "secret", timestamp, "", string(body), now, false},
+	}
+	for _, test := range tests {
+		err := Verify(test.secret, test.timestamp, test.signature, []byte(test.body), test.at)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: got error %v", test.name, err)
+		}
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	now := time.Now()
+	body := []byte("{}")
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	header := http.Header{}
+	header.Set(TimestampHeader, timestamp)
+	header.Set(SignatureHeader, Sign("secret", timestamp, body))
+	if err := VerifyRequest("secret", header, body, now); err != nil {
+		t.Errorf("valid request refused: %v", err)
+	}
+	if err := VerifyRequest("secret", http.Header{}, body, now); err == nil {
+		t.Error("request without headers accepted")
+	}
+}
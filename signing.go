@@ -0,0 +1,51 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"send-later-discord-bot/signature"
+	"strconv"
+	"time"
+)
+
+var signingClient = &http.Client{Timeout: 10 * time.Second}
+
+// postSigned posts the JSON body to url with the signature headers, described
+// in the signature package.
+func postSigned(url string, secret string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("Error creating request: " + err.Error())
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(signature.TimestampHeader, timestamp)
+	req.Header.Set(signature.SignatureHeader, signature.Sign(secret, timestamp, body))
+	req.Header.Set(signature.DeliveryHeader, newID()+newID())
+
+	resp, err := signingClient.Do(req)
+	if err != nil {
+		return errors.New("Error posting payload: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New("Error posting payload: " + resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"strings"
+	"time"
+)
+
+// snoozePrefix starts the custom ID of the snooze buttons of the delivered
+// DMs, followed by one of the snoozes.
+const snoozePrefix = "snooze:"
+
+// snoozes are the delays offered to send a DM again.
+var snoozes = []struct {
+	id    string
+	label string
+}{
+	{"10m", "+10 min"},
+	{"1h", "+1 hour"},
+	{"tomorrow", "Tomorrow"},
+}
+
+// snoozeButtons returns the buttons sending a delivered DM again later.
+func snoozeButtons() []discordgo.MessageComponent {
+	buttons := []discordgo.MessageComponent{}
+	for _, snooze := range snoozes {
+		buttons = append(buttons, discordgo.Button{
+			Label:    snooze.label,
+			Style:    discordgo.SecondaryButton,
+			CustomID: snoozePrefix + snooze.id,
+		})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// snoozeTime returns when a DM snoozed at now is sent again.
+func snoozeTime(id string, now time.Time) time.Time {
+	switch id {
+	case "10m":
+		return now.Add(10 * time.Minute)
+	case "1h":
+		return now.Add(time.Hour)
+	default:
+		return now.AddDate(0, 0, 1)
+	}
+}
+
+// handleSnoozeComponent schedules the DM of the clicked button again, with
+// its content, rich embeds and attachments, and replaces the buttons with
+// the chosen snooze.
+func handleSnoozeComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	id := strings.TrimPrefix(i.MessageComponentData().CustomID, snoozePrefix)
+	if !allowScheduling(s, i, true, true) {
+		return
+	}
+
+	// downloading the attachments can take longer than the time allowed to
+	// respond
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		logger.Error("Error responding to interaction", "error", err)
+		return
+	}
+
+	message := i.Message
+	files := []ScheduledFile{}
+	for _, attachment := range message.Attachments {
+		err := checkAttachmentSize(attachment.Filename, attachment.Size)
+		if err != nil {
+			logger.Error("Error snoozing message", "error", err, "message", message.ID)
+			continue
+		}
+		file, err := storeAttachment(interactionContext(i), attachment.URL, attachment.Filename, attachment.ContentType)
+		if err != nil {
+			logger.Error("Error snoozing message", "error", err, "message", message.ID)
+			continue
+		}
+		files = append(files, file)
+	}
+	embeds := []*discordgo.MessageEmbed{}
+	for _, embed := range message.Embeds {
+		if embed.Type == discordgo.EmbedTypeRich {
+			embeds = append(embeds, embed)
+		}
+	}
+
+	user := interactionUser(i)
+	sch := &Schedule{
+		ChannelID: message.ChannelID,
+		AuthorID:  user.ID,
+		Content:   message.Content,
+		Embeds:    embeds,
+		Files:     files,
+		// only the author is pinged
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: []string{user.ID}},
+		DM:              true,
+		SendAt:          snoozeTime(id, time.Now()),
+	}
+	startSchedule(s, sch)
+	logger.Info("Message snoozed", "id", sch.ID, "snooze", id, "user", user.ID)
+
+	// the buttons are replaced so that the message is only snoozed once
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Snoozed until " + sch.SendAt.In(settings().loc).Format("02/01/2006 15:04"),
+				Style:    discordgo.SecondaryButton,
+				CustomID: snoozePrefix + "done",
+				Disabled: true,
+			},
+		}},
+	}
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Components: &components,
+	})
+	if err != nil {
+		logger.Error("Error responding to interaction", "error", err)
+	}
+}
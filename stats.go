@@ -0,0 +1,107 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsDays is the period of the delivery statistics.
+const statsDays = 7
+
+// statsChannels is the number of busiest channels shown.
+const statsChannels = 5
+
+func handleStats(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" {
+		respond(s, i, "The statistics are only available in a server")
+		return
+	}
+	if !hasPermission(i, discordgo.PermissionManageServer) {
+		respond(s, i, "Only the server admins can see the statistics")
+		return
+	}
+	now := time.Now()
+	entries := store.AuditEntries(i.GuildID, now.AddDate(0, 0, -statsDays), now)
+	logger.Info("Showing statistics", "guild", i.GuildID, "user", interactionUser(i).ID)
+	respondEphemeralEmbed(s, i, statsEmbed(schedules.shared(i.GuildID, interactionUser(i).ID), entries))
+}
+
+// statsEmbed sums up the pending schedules of the guild and the audit entries
+// of the last statsDays days.
+func statsEmbed(pending []*Schedule, entries []AuditEntry) *discordgo.MessageEmbed {
+	sent := 0
+	failed := 0
+	// the busiest channels are the ones with the most messages sent recently
+	// or to come
+	activity := map[string]int{}
+	for _, entry := range entries {
+		switch entry.Action {
+		case AuditSent:
+			sent++
+			activity[entry.ChannelID]++
+		case AuditFailed:
+			failed++
+		}
+	}
+	var next *Schedule
+	for _, sch := range pending {
+		if !sch.DM {
+			activity[sch.ChannelID]++
+		}
+		if next == nil || sch.SendAt.Before(next.SendAt) {
+			next = sch
+		}
+	}
+
+	channels := make([]string, 0, len(activity))
+	for channelID := range activity {
+		channels = append(channels, channelID)
+	}
+	sort.Slice(channels, func(a, b int) bool {
+		if activity[channels[a]] != activity[channels[b]] {
+			return activity[channels[a]] > activity[channels[b]]
+		}
+		return channels[a] < channels[b]
+	})
+	busiest := []string{}
+	for _, channelID := range channels[:min(len(channels), statsChannels)] {
+		busiest = append(busiest, "<#"+channelID+">: "+strconv.Itoa(activity[channelID]))
+	}
+	if len(busiest) == 0 {
+		busiest = append(busiest, "None")
+	}
+
+	upcoming := "Nothing scheduled"
+	if next != nil {
+		upcoming = "<t:" + strconv.FormatInt(next.SendAt.Unix(), 10) + ":R> " + next.target() + " " + truncate(oneLine(next.preview()), 60) + " (`" + next.ID + "`)"
+	}
+
+	return &discordgo.MessageEmbed{
+		Title: "Statistics of this server",
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Pending", Value: strconv.Itoa(len(pending)), Inline: true},
+			{Name: "Delivered in " + strconv.Itoa(statsDays) + " days", Value: strconv.Itoa(sent), Inline: true},
+			{Name: "Failed in " + strconv.Itoa(statsDays) + " days", Value: strconv.Itoa(failed), Inline: true},
+			{Name: "Busiest channels", Value: strings.Join(busiest, "\n")},
+			{Name: "Next delivery", Value: truncate(upcoming, 1024)},
+		},
+	}
+}
@@ -0,0 +1,205 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// Store keeps the state of the bot that must survive a restart in a JSON file.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data storeData
+	// err is the error of the last write, nil once a write succeeds again.
+	err error
+	// dirty is set when changes of updateLater are not written yet.
+	dirty bool
+	// later are the changes of updateLater waiting for the next write. They
+	// have their own lock, as they are added under other locks.
+	laterMu sync.Mutex
+	later   []func(data *storeData)
+}
+
+// storeFlushDelay is how long the changes of updateLater wait to be written
+// together, as every write saves the whole store.
+const storeFlushDelay = time.Second
+
+// storeData is what is written to disk.
+type storeData struct {
+	// Flags are the feature flags enabled for each guild, by guild ID.
+	Flags map[string]map[string]bool `json:"flags,omitempty"`
+	// Guilds are the configurations of the guilds, by guild ID.
+	Guilds map[string]GuildConfig `json:"guilds,omitempty"`
+	// Feeds are the RSS and Atom feeds posted in each guild, by guild ID.
+	Feeds map[string][]Feed `json:"feeds,omitempty"`
+	// Pending are the schedules waiting for their time, by ID.
+	Pending map[string]*Schedule `json:"pending,omitempty"`
+	// Outbox are the deliveries in progress, by schedule ID.
+	Outbox map[string]OutboxEntry `json:"outbox,omitempty"`
+	// Deletions are the sent messages waiting to be deleted.
+	Deletions []Deletion `json:"deletions,omitempty"`
+	// DeadLetters are the deliveries that failed, until they are requeued
+	// or discarded.
+	DeadLetters []DeadLetter `json:"dead_letters,omitempty"`
+	// Audit is the trail of everything that happened to the schedules.
+	Audit []AuditEntry `json:"audit,omitempty"`
+	// AuditDropped is the time of the last entry dropped from the audit
+	// trail of each guild, by guild ID.
+	AuditDropped map[string]time.Time `json:"audit_dropped,omitempty"`
+}
+
+// openStore loads the store from path. A missing file is an empty store.
+func openStore(path string) (*Store, error) {
+	st := &Store{path: path}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, errors.New("Error reading store: " + err.Error())
+	}
+	err = json.Unmarshal(content, &st.data)
+	if err != nil {
+		return nil, errors.New("Error decoding store: " + err.Error())
+	}
+	return st, nil
+}
+
+// reloadGuilds reads the configurations of the guilds from the file again,
+// for the operators who edit them by hand. The rest of the state is the one
+// of the bot.
+func (st *Store) reloadGuilds() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	content, err := os.ReadFile(st.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.New("Error reading store: " + err.Error())
+	}
+	var data storeData
+	err = json.Unmarshal(content, &data)
+	if err != nil {
+		return errors.New("Error decoding store: " + err.Error())
+	}
+	st.data.Guilds = data.Guilds
+	return nil
+}
+
+// view calls fn with the store data locked. fn must not modify the data.
+func (st *Store) view(fn func(data *storeData)) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.applyLater()
+	fn(&st.data)
+}
+
+// update calls fn with the store data locked and writes the result to disk.
+func (st *Store) update(fn func(data *storeData)) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	fn(&st.data)
+	st.applyLater()
+	return st.write()
+}
+
+// updateLater calls fn with the store data locked before the next write,
+// which happens at most storeFlushDelay from now. It is for the changes that
+// are not worth a write of the whole store each, a crash loses them unless
+// another write came in the meantime. fn must not lock the store.
+func (st *Store) updateLater(fn func(data *storeData)) {
+	st.laterMu.Lock()
+	defer st.laterMu.Unlock()
+	st.later = append(st.later, fn)
+	if len(st.later) == 1 {
+		time.AfterFunc(storeFlushDelay, st.flush)
+	}
+}
+
+// applyLater calls the changes of updateLater waiting for the next write. The
+// store must be locked.
+func (st *Store) applyLater() {
+	st.laterMu.Lock()
+	later := st.later
+	st.later = nil
+	st.laterMu.Unlock()
+	for _, fn := range later {
+		fn(&st.data)
+		st.dirty = true
+	}
+}
+
+// flush writes the changes of updateLater, unless another write did.
+func (st *Store) flush() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.applyLater()
+	if !st.dirty {
+		return
+	}
+	err := st.write()
+	if err != nil {
+		logger.Error("Error writing store", "error", err)
+	}
+}
+
+// write saves the data, the store being locked, and records the error.
+func (st *Store) write() error {
+	_, sp := startSpan(context.Background(), "store update", spanKindInternal, "path", st.path)
+	err := st.save()
+	sp.end(err)
+	st.err = err
+	if err != nil {
+		alert("Storage failure", "The store "+st.path+" cannot be written: "+err.Error())
+		return err
+	}
+	st.dirty = false
+	return nil
+}
+
+// lastError returns the error of the last write, nil if it succeeded.
+func (st *Store) lastError() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.err
+}
+
+// save writes the data to a temporary file and renames it over the store so
+// that a crash never leaves a truncated store behind.
+func (st *Store) save() error {
+	content, err := json.MarshalIndent(st.data, "", "  ")
+	if err != nil {
+		return errors.New("Error encoding store: " + err.Error())
+	}
+	tmp := st.path + ".tmp"
+	err = os.WriteFile(tmp, content, 0o600)
+	if err != nil {
+		return errors.New("Error writing store: " + err.Error())
+	}
+	err = os.Rename(tmp, st.path)
+	if err != nil {
+		return errors.New("Error writing store: " + err.Error())
+	}
+	return nil
+}
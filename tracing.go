@@ -0,0 +1,269 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Batching of the exported spans, the spans are dropped when the queue is
+// full rather than slowing the bot down.
+const (
+	traceQueueSize  = 2048
+	traceBatchSize  = 256
+	traceBatchDelay = 5 * time.Second
+)
+
+// Kinds of the spans in OTLP.
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+)
+
+// tracer exports the spans with OTLP over HTTP, using the JSON encoding. It is
+// nil when tracing is disabled.
+var tracer *otlpExporter
+
+// otlpExporter sends the ended spans to the OTLP endpoint in batches.
+type otlpExporter struct {
+	endpoint string
+	headers  map[string]string
+	service  string
+	queue    chan otlpSpan
+}
+
+// setupTracing enables the tracing when an OTLP endpoint is configured with
+// the standard OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT
+// environment variables.
+func setupTracing() {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	headers := map[string]string{}
+	for _, header := range envList("OTEL_EXPORTER_OTLP_HEADERS") {
+		key, value, found := strings.Cut(header, "=")
+		if found {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	tracer = &otlpExporter{
+		endpoint: endpoint,
+		headers:  headers,
+		service:  envOr("OTEL_SERVICE_NAME", "send-later-discord-bot"),
+		queue:    make(chan otlpSpan, traceQueueSize),
+	}
+	go tracer.run()
+	logger.Info("Tracing enabled", "endpoint", endpoint)
+}
+
+// spanContextKey is the key of the current span in a context.
+type spanContextKey struct{}
+
+// span is an operation being traced.
+type span struct {
+	name       string
+	kind       int
+	traceID    string
+	spanID     string
+	parentID   string
+	start      time.Time
+	attributes []otlpAttribute
+}
+
+// startSpan starts a span, child of the span of ctx if any, with attributes
+// given as key and value pairs. The returned context carries the new span.
+func startSpan(ctx context.Context, name string, kind int, attributes ...string) (context.Context, *span) {
+	sp := &span{name: name, kind: kind, spanID: randomHex(8), start: time.Now()}
+	if parent, ok := ctx.Value(spanContextKey{}).(*span); ok {
+		sp.traceID = parent.traceID
+		sp.parentID = parent.spanID
+	} else {
+		sp.traceID = randomHex(16)
+	}
+	for n := 0; n+1 < len(attributes); n += 2 {
+		sp.setAttribute(attributes[n], attributes[n+1])
+	}
+	return context.WithValue(ctx, spanContextKey{}, sp), sp
+}
+
+// setAttribute adds an attribute to the span.
+func (sp *span) setAttribute(key string, value string) {
+	sp.attributes = append(sp.attributes, otlpAttribute{Key: key, Value: otlpValue{StringValue: value}})
+}
+
+// end ends the span, which failed if err is not nil, and queues it for the
+// export.
+func (sp *span) end(err error) {
+	if tracer == nil {
+		return
+	}
+	exported := otlpSpan{
+		TraceID:           sp.traceID,
+		SpanID:            sp.spanID,
+		ParentSpanID:      sp.parentID,
+		Name:              sp.name,
+		Kind:              sp.kind,
+		StartTimeUnixNano: strconv.FormatInt(sp.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		Attributes:        sp.attributes,
+	}
+	if err != nil {
+		exported.Status = &otlpStatus{Code: 2, Message: err.Error()}
+	}
+	select {
+	case tracer.queue <- exported:
+	default:
+	}
+}
+
+// interactionTraces holds the contexts of the interactions being handled, by
+// interaction ID, so that the handlers can add spans to their trace.
+var interactionTraces sync.Map
+
+// interactionContext returns the context of the trace of the interaction.
+func interactionContext(i *discordgo.InteractionCreate) context.Context {
+	if ctx, found := interactionTraces.Load(i.ID); found {
+		return ctx.(context.Context)
+	}
+	return context.Background()
+}
+
+// randomHex returns n random bytes in hexadecimal, for the IDs of the spans.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// run sends the queued spans in batches, at least every traceBatchDelay.
+func (e *otlpExporter) run() {
+	ticker := time.NewTicker(traceBatchDelay)
+	defer ticker.Stop()
+	batch := []otlpSpan{}
+	for {
+		select {
+		case sp := <-e.queue:
+			batch = append(batch, sp)
+			if len(batch) < traceBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		err := e.export(batch)
+		if err != nil {
+			logger.Error("Error exporting spans", "error", err, "spans", len(batch), "endpoint", e.endpoint)
+		}
+		batch = []otlpSpan{}
+	}
+}
+
+// export posts the spans to the OTLP endpoint.
+func (e *otlpExporter) export(spans []otlpSpan) error {
+	payload := otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: e.service}}}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "send-later-discord-bot"},
+			Spans: spans,
+		}},
+	}}}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New("the endpoint answered " + resp.Status)
+	}
+	return nil
+}
+
+// The OTLP/JSON encoding of the spans, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
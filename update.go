@@ -0,0 +1,135 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"unicode/utf8"
+)
+
+// handleUpdate changes the content or the time of a pending schedule. The
+// schedule is replaced by an edited copy, as a dispatcher worker may be reading
+// it.
+func handleUpdate(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	id := ""
+	content := ""
+	sendTime := ""
+	date := ""
+	for _, option := range options {
+		if option.Name == "id" {
+			id = option.StringValue()
+		} else if option.Name == "message" {
+			content = option.StringValue()
+		} else if option.Name == "time" {
+			sendTime = option.StringValue()
+		} else if option.Name == "date" {
+			date = option.StringValue()
+		}
+	}
+
+	// only the author and the members allowed to manage messages can edit
+	user := interactionUser(i)
+	sch := schedules.get(id)
+	if sch == nil || sch.GuildID != i.GuildID {
+		respondError(s, i, "Error editing message", errors.New("no pending message with ID "+id))
+		return
+	}
+	if sch.AuthorID != user.ID && !hasPermission(i, discordgo.PermissionManageMessages) {
+		respondError(s, i, "Error editing message", errors.New("only its author or a moderator can edit it"))
+		return
+	}
+	if content == "" && sendTime == "" && date == "" {
+		respondError(s, i, "Error editing message", errors.New("give a new message, time or date"))
+		return
+	}
+
+	edited := *sch
+	if content != "" {
+		err := checkEditContent(s, sch, user.ID, content)
+		if err != nil {
+			respondError(s, i, "Error editing message", err)
+			return
+		}
+		edited.Content = content
+	}
+	if sendTime != "" || date != "" {
+		// what is not given is kept from the current time
+		config := store.GuildConfig(i.GuildID)
+		current := sch.SendAt.In(config.location())
+		if date == "" {
+			date = current.Format("02/01/2006")
+		}
+		if sendTime == "" {
+			sendTime = current.Format("15:04")
+		}
+		fixedTime, err := parseSendTime(date, sendTime, config)
+		if err != nil {
+			respondError(s, i, "Error editing message", err)
+			return
+		}
+		edited.SendAt = fixedTime
+	}
+
+	err := commitEdit(s, &edited, user.ID, hasPermission(i, discordgo.PermissionManageMessages))
+	if err != nil {
+		respondError(s, i, "Error editing message", err)
+		return
+	}
+	logger.Info("Message edited", "id", id, "user", user.ID)
+	respondEmbed(s, i, scheduledEmbed("Message edited", &edited))
+}
+
+// checkEditContent returns an error when the content of the schedule cannot
+// be replaced by content, or when the member editing it could not post it in
+// the channel themselves.
+func checkEditContent(s *discordgo.Session, sch *Schedule, userID string, content string) error {
+	switch {
+	case sch.Poll != nil:
+		return errors.New("the question of a poll cannot be edited")
+	case sch.Action != "" && sch.Action != actionEdit:
+		return errors.New("a " + sch.Action + " has no content")
+	case sch.Action == actionEdit && utf8.RuneCountInString(content) > maxMessageLength:
+		return errors.New("the message is longer than 2000 characters")
+	}
+	if sch.DM {
+		return nil
+	}
+	channel, err := s.Channel(sch.ChannelID)
+	if err != nil {
+		return errors.New("Error getting channel: " + err.Error())
+	}
+	return checkCanPost(s, userID, channel, content, sch.AllowedMentions)
+}
+
+// commitEdit replaces the pending schedule having the ID of edited by it, and
+// returns an error if it was already sent. The edits of the members who can't
+// manage the messages are reviewed again, as the approval was for the
+// previous version.
+func commitEdit(s *discordgo.Session, edited *Schedule, userID string, moderator bool) error {
+	if !moderator {
+		edited.AwaitingApproval = needsApproval(s, edited)
+	}
+	if schedules.replace(edited) == nil {
+		return errors.New("it was already sent")
+	}
+	watchSchedule(s, edited)
+	audit(s, AuditEdited, userID, edited)
+	if edited.AwaitingApproval {
+		requestApproval(s, edited)
+	}
+	return nil
+}
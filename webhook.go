@@ -0,0 +1,106 @@
+//    Copyright (C) 2025 Martin Spiering
+//
+//    This program is free software: you can redistribute it and/or modify
+//    it under the terms of the GNU General Public License as published by
+//    the Free Software Foundation, either version 3 of the License, or
+//    (at your option) any later version.
+//
+//    This program is distributed in the hope that it will be useful,
+//    but WITHOUT ANY WARRANTY; without even the implied warranty of
+//    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//    GNU General Public License for more details.
+//
+//    You should have received a copy of the GNU General Public License
+//    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Senders of a scheduled message.
+const (
+	// senderBot sends the message as the bot.
+	senderBot = "bot"
+	// senderMe sends the message through a webhook of the channel, with the
+	// name and avatar of its author.
+	senderMe = "me"
+	// senderAnonymous sends the message as the bot without revealing its
+	// author to the members, only the audit trail records them.
+	senderAnonymous = "anonymous"
+	// senderMention sends the message as the bot, starting with a mention of
+	// its author, who is pinged when it is sent.
+	senderMention = "mention"
+)
+
+// webhookName is the name of the webhooks created by the bot.
+const webhookName = "Send Later"
+
+// authorIdentity returns the name and avatar the member has in the guild.
+func authorIdentity(member *discordgo.Member, guildID string) (string, string) {
+	identity := *member
+	identity.GuildID = guildID
+	name := identity.DisplayName()
+	if name == "" {
+		name = identity.User.Username
+	}
+	return name, identity.AvatarURL("")
+}
+
+// channelWebhook returns the webhook of the bot in the channel, creating it
+// if needed.
+func channelWebhook(s *discordgo.Session, channelID string) (*discordgo.Webhook, error) {
+	webhooks, err := s.ChannelWebhooks(channelID)
+	if err != nil {
+		return nil, errors.New("Error getting webhooks: " + err.Error())
+	}
+	for _, webhook := range webhooks {
+		if webhook.User != nil && webhook.User.ID == s.State.User.ID && webhook.Token != "" {
+			return webhook, nil
+		}
+	}
+	webhook, err := s.WebhookCreate(channelID, webhookName, "")
+	if err != nil {
+		return nil, errors.New("Error creating webhook: " + err.Error())
+	}
+	logger.Info("Webhook created", "channel", channelID, "webhook", webhook.ID)
+	return webhook, nil
+}
+
+// sendAsAuthor sends the message of the schedule in the channel through a
+// webhook, under the name and avatar of its author. The webhooks of a thread
+// belong to its parent channel.
+func sendAsAuthor(s *discordgo.Session, sch *Schedule, channelID string) (*discordgo.Message, error) {
+	channel, err := s.Channel(channelID)
+	if err != nil {
+		return nil, errors.New("Error getting channel: " + err.Error())
+	}
+	threadID := ""
+	if channel.IsThread() {
+		threadID = channel.ID
+		channelID = channel.ParentID
+	}
+	webhook, err := channelWebhook(s, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	message := messageSend(sch)
+	defer closeFiles(message.Files)
+	params := &discordgo.WebhookParams{
+		Content:         message.Content,
+		Username:        sch.SenderName,
+		AvatarURL:       sch.SenderAvatarURL,
+		TTS:             message.TTS,
+		Files:           message.Files,
+		Embeds:          message.Embeds,
+		AllowedMentions: message.AllowedMentions,
+		Flags:           message.Flags,
+	}
+	if threadID != "" {
+		return s.WebhookThreadExecute(webhook.ID, webhook.Token, true, threadID, params)
+	}
+	return s.WebhookExecute(webhook.ID, webhook.Token, true, params)
+}